@@ -1,5 +1,10 @@
 --config:debug(true)
 
+-- Skip anything in front of the message, e.g. GTS bulletin envelope
+if not factory.seekStartSignature() then
+    return nil
+end
+
 local editionNumber = factory.peekEditionNumber()
 
 if editionNumber == 4 then
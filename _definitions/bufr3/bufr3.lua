@@ -1,10 +1,10 @@
-local function deserialise()
+-- Deserialise sections 0 to 3, i.e. everything before the data section
+local function deserialiseHeader()
     local section0 = require 'common.section0'
     local section1 = require 'bufr3.section1'
     local section2 = require 'common.section2'
     local section3 = require 'common.section3'
-    local section4 = require 'common.section4'
-    local section5 = require 'common.section5'
+    local localDefinitions = require 'common.local'
 
     local message = factory.newMessage()
 
@@ -12,11 +12,27 @@ local function deserialise()
     section1.deserialise()
 
     if message:getProxyField('isSection2Presents'):value() then
-        section2.deserialise()
+        -- Centres may provide their own definitions for the local data
+        local centre = message:getProxyField('originatingCentre'):value()
+        local localSection2 = localDefinitions.find(centre, 'section2')
+        if localSection2 then
+            localSection2.deserialise()
+        else
+            section2.deserialise()
+        end
     end
 
     section3.deserialise()
 
+    return message
+end
+
+local function deserialise()
+    local section4 = require 'common.section4'
+    local section5 = require 'common.section5'
+
+    local message = deserialiseHeader()
+
     -- Config tables for lookup
     factory.initTableGroup(
         message:getProxyField('masterTableNumber'):value(),
@@ -32,6 +48,14 @@ local function deserialise()
     return message
 end
 
+-- Deserialise only the header sections and skip the rest of the message
+local function scan()
+    local message = deserialiseHeader()
+    factory.skipMessage(message:getProxyField('totalLengthInBytes'):value())
+    return message
+end
+
 return {
-    deserialise = deserialise
+    deserialise = deserialise,
+    scan = scan
 }
\ No newline at end of file
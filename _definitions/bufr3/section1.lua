@@ -1,4 +1,6 @@
 local function deserialise()
+    local virtual = require 'common.virtual'
+
     local section = factory.newSection(1, 'Identification Section')
 
     local lengthInBytes = factory.newField {
@@ -31,7 +33,7 @@ local function deserialise()
     }
 
     factory.newField {
-        'flagBits', BIN, 7;
+        'flagBits', BINARY, 7;
     }
 
     factory.newField {
@@ -84,6 +86,8 @@ local function deserialise()
         proxy = true,
     }
 
+    virtual.section1()
+
     factory.padding(lengthInBytes:value())
 
     return section
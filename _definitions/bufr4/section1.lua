@@ -1,4 +1,6 @@
 local function deserialise()
+    local virtual = require 'common.virtual'
+
     local section = factory.newSection(1, 'Identification Section')
 
     local lengthInBytes = factory.newField {
@@ -89,6 +91,8 @@ local function deserialise()
         proxy = true,
     }
 
+    virtual.section1()
+
     factory.padding(lengthInBytes:value())
 
     return section
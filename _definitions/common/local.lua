@@ -0,0 +1,16 @@
+-- Helpers for locating local definitions provided by individual centres.
+-- Local definitions live under local/<centre>/, e.g. local/98/section2.lua
+
+-- Return the module of the local definition of the given name for the given centre,
+-- or nil if the centre does not provide one.
+local function find(centre, name)
+    local moduleName = 'local.' .. centre .. '.' .. name
+    if package.searchpath(moduleName, package.path) then
+        return require(moduleName)
+    end
+    return nil
+end
+
+return {
+    find = find
+}
@@ -0,0 +1,83 @@
+-- Names of code values used in the header sections
+
+-- BUFR Table A, data category
+local dataCategories = {
+    [0] = 'Surface data - land',
+    [1] = 'Surface data - sea',
+    [2] = 'Vertical soundings (other than satellite)',
+    [3] = 'Vertical soundings (satellite)',
+    [4] = 'Single level upper-air data (other than satellite)',
+    [5] = 'Single level upper-air data (satellite)',
+    [6] = 'Radar data',
+    [7] = 'Synoptic features',
+    [8] = 'Physical/chemical constituents',
+    [9] = 'Dispersal and transport',
+    [10] = 'Radiological data',
+    [11] = 'BUFR tables, complete replacement or update',
+    [12] = 'Surface data (satellite)',
+    [13] = 'Forecasts',
+    [14] = 'Warnings',
+    [20] = 'Status information',
+    [21] = 'Radiances (satellite measured)',
+    [22] = 'Radar (satellite) but not altimeter and scatterometer',
+    [23] = 'Lidar (satellite)',
+    [24] = 'Scatterometry (satellite)',
+    [25] = 'Altimetry (satellite)',
+    [26] = 'Spectrometry (satellite)',
+    [27] = 'Gravity measurement (satellite)',
+    [28] = 'Precision orbit (satellite)',
+    [29] = 'Space environment (satellite)',
+    [30] = 'Calibration datasets (satellite)',
+    [31] = 'Oceanographic data',
+    [32] = 'Lidar (ground-based)',
+    [33] = 'Microwave radiometer (ground-based)',
+    [101] = 'Image data (satellite)',
+    [255] = 'Indicator for local use',
+}
+
+-- Common Code Table C-11, originating centres. Only the most common centres are listed.
+local centres = {
+    [1] = 'Melbourne',
+    [7] = 'US National Weather Service, National Centres for Environmental Prediction (NCEP)',
+    [8] = 'US National Weather Service Telecommunications Gateway (NWSTG)',
+    [34] = 'Tokyo (RSMC), Japan Meteorological Agency',
+    [38] = 'Beijing (RSMC)',
+    [40] = 'Seoul',
+    [54] = 'Montreal (RSMC)',
+    [58] = 'Fleet Numerical Meteorology and Oceanography Center, Monterey',
+    [74] = 'UK Meteorological Office Exeter (RSMC)',
+    [78] = 'Offenbach (RSMC)',
+    [80] = 'Rome (RSMC)',
+    [82] = 'Norrkoping',
+    [84] = 'Toulouse (RSMC)',
+    [85] = 'Toulouse (RSMC)',
+    [86] = 'Helsinki',
+    [88] = 'Oslo',
+    [94] = 'Copenhagen',
+    [96] = 'Athens',
+    [97] = 'European Space Agency (ESA)',
+    [98] = 'European Centre for Medium-Range Weather Forecasts (ECMWF)',
+    [99] = 'De Bilt',
+    [160] = 'US NOAA/NESDIS',
+    [173] = 'US National Aeronautics and Space Administration (NASA)',
+    [254] = 'EUMETSAT Operation Centre',
+}
+
+local function dataCategoryName(category)
+    local name = dataCategories[category]
+    if name then
+        return name
+    elseif category >= 240 and category <= 254 then
+        return 'For experimental use'
+    end
+    return 'Reserved'
+end
+
+local function centreName(centre)
+    return centres[centre] or 'Unknown'
+end
+
+return {
+    dataCategoryName = dataCategoryName,
+    centreName = centreName
+}
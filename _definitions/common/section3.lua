@@ -6,7 +6,7 @@ local function deserialise()
     }
 
     factory.newField {
-        'reservedBits', BIN, 8;
+        'reservedBits', BINARY, 8;
     }
 
     factory.newField {
@@ -25,7 +25,7 @@ local function deserialise()
     }
 
     factory.newField {
-        'flagBits', BIN, 6;
+        'flagBits', BINARY, 6;
     }
 
     factory.newTemplateField {
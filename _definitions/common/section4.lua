@@ -6,7 +6,7 @@ local function deserialise()
     }
 
     factory.newField {
-        'reservedBits', BIN, 8;
+        'reservedBits', BINARY, 8;
     }
 
     factory.newPayloadField {
@@ -0,0 +1,30 @@
+-- Virtual fields computed from other fields. They take no bits in the message.
+local names = require 'common.names'
+
+-- Add the virtual fields of section 1. They must be added after all of its
+-- fields are deserialised.
+local function section1()
+    local message = factory.getMessage()
+    local function value(name)
+        return message:getProxyField(name):value()
+    end
+
+    factory.newVirtualField {
+        'typicalDateTime', message:typicalDateTime();
+        proxy = true,
+    }
+
+    factory.newVirtualField {
+        'dataCategoryName', names.dataCategoryName(value('dataCategory'));
+        proxy = true,
+    }
+
+    factory.newVirtualField {
+        'centreName', names.centreName(value('originatingCentre'));
+        proxy = true,
+    }
+end
+
+return {
+    section1 = section1
+}
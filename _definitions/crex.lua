@@ -0,0 +1,16 @@
+-- Entry script for deserializing CREX messages
+
+-- Skip anything in front of the message, e.g. GTS bulletin envelope
+if not factory.seekStartSignature() then
+    return nil
+end
+
+local editionNumber = factory.peekEditionNumber()
+
+if editionNumber == 2 then
+    local crex2 = require 'crex2.crex2'
+    return crex2.deserialise()
+
+else
+    error("Invalid CREX edition number: " .. editionNumber)
+end
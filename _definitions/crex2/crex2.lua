@@ -0,0 +1,31 @@
+-- CREX edition 2 messages are made of characters. Widths of fields are given in
+-- bits of the characters, e.g. 16 for two characters.
+local function deserialise()
+    local section0 = require 'crex2.section0'
+    local section1 = require 'crex2.section1'
+    local section2 = require 'crex2.section2'
+    local section4 = require 'crex2.section4'
+
+    local message = factory.newMessage()
+
+    section0.deserialise()
+    section1.deserialise()
+
+    -- Config tables for lookup
+    factory.initTableGroup(
+        message:getProxyField('masterTableNumber'):value(),
+        message:getProxyField('originatingCentre'):value(),
+        message:getProxyField('originatingSubCentre'):value(),
+        message:getProxyField('masterTableVersion'):value(),
+        message:getProxyField('localTableVersion'):value()
+    )
+
+    section2.deserialise()
+    section4.deserialise()
+
+    return message
+end
+
+return {
+    deserialise = deserialise
+}
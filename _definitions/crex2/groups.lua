@@ -0,0 +1,18 @@
+-- Helpers for the groups of characters of CREX messages
+
+-- Deserialise a field of the given text, e.g. the letter starting a group, and
+-- raise an error if the text is not matched
+local function expect(name, text, description)
+    local field = factory.newField {
+        name, BYTES, #text * BITS_PER_BYTE;
+    }
+    local x = field:value()
+    if x ~= text then
+        error((description or name) .. ' not matched: ' .. x)
+    end
+    return field
+end
+
+return {
+    expect = expect
+}
@@ -0,0 +1,13 @@
+local groups = require 'crex2.groups'
+
+local function deserialise()
+    local section = factory.newSection(0, 'Indicator Section')
+
+    groups.expect('startSignature', 'CREX++', 'Start signature')
+
+    return section
+end
+
+return {
+    deserialise = deserialise
+}
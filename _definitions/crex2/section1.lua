@@ -0,0 +1,118 @@
+-- Data description section, i.e.
+--   T ttee vvww A mmmnnn bbbbbccccc U uu S nnn Y yyyymmdd H hhmm descriptors ++
+-- Check digits, i.e. E after the descriptors, are not supported.
+local groups = require 'crex2.groups'
+
+local function deserialise()
+    local virtual = require 'common.virtual'
+
+    local section = factory.newSection(1, 'Data Description Section')
+
+    groups.expect('tableIndicator', 'T')
+
+    factory.newField {
+        'masterTableNumber', UINT, 16;
+        proxy = true,
+    }
+
+    factory.newField {
+        'crexEditionNumber', UINT, 16;
+        proxy = true,
+    }
+
+    factory.newField {
+        'masterTableVersion', UINT, 16;
+        proxy = true,
+    }
+
+    factory.newField {
+        'localTableVersion', UINT, 16;
+        proxy = true,
+    }
+
+    groups.expect('categoryIndicator', 'A')
+
+    factory.newField {
+        'dataCategory', UINT, 24;
+        proxy = true,
+    }
+
+    factory.newField {
+        'dataI18nSubCategory', UINT, 24;
+        proxy = true,
+    }
+
+    factory.newField {
+        'originatingCentre', UINT, 40;
+        proxy = true,
+    }
+
+    factory.newField {
+        'originatingSubCentre', UINT, 40;
+        proxy = true,
+    }
+
+    groups.expect('updateIndicator', 'U')
+
+    factory.newField {
+        'updateSequenceNumber', UINT, 16;
+        proxy = true,
+    }
+
+    groups.expect('subsetsIndicator', 'S')
+
+    factory.newField {
+        'nSubsets', UINT, 24;
+        proxy = true,
+    }
+
+    groups.expect('dateIndicator', 'Y')
+
+    factory.newField {
+        'year', UINT, 32;
+        proxy = true,
+    }
+
+    factory.newField {
+        'month', UINT, 16;
+        proxy = true,
+    }
+
+    factory.newField {
+        'day', UINT, 16;
+        proxy = true,
+    }
+
+    groups.expect('timeIndicator', 'H')
+
+    factory.newField {
+        'hour', UINT, 16;
+        proxy = true,
+    }
+
+    factory.newField {
+        'minute', UINT, 16;
+        proxy = true,
+    }
+
+    factory.newCrexTemplateField {
+        'unexpandedTemplate';
+    }
+
+    groups.expect('endOfSection', '++',
+        'End of section 1 (check digits are not supported)')
+
+    -- Subsets of CREX are never compressed
+    factory.newVirtualField {
+        'isCompressed', false;
+        proxy = true,
+    }
+
+    virtual.section1()
+
+    return section
+end
+
+return {
+    deserialise = deserialise
+}
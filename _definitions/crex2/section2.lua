@@ -0,0 +1,20 @@
+-- Data section. Subsets end with + and the section ends with ++.
+local groups = require 'crex2.groups'
+
+local function deserialise()
+    local section = factory.newSection(2, 'Data Section')
+
+    factory.newPayloadField {
+        'payload',
+        factory.getMessage():getProxyField("nSubsets"):value(),
+        false,
+    }
+
+    groups.expect('endOfSection', '++', 'End of section 2')
+
+    return section
+end
+
+return {
+    deserialise = deserialise
+}
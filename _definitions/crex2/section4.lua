@@ -0,0 +1,14 @@
+-- End section. The optional section 3 is not supported.
+local groups = require 'crex2.groups'
+
+local function deserialise()
+    local section = factory.newSection(4, 'End Section')
+
+    groups.expect('stopSignature', '7777', 'Stop signature')
+
+    return section
+end
+
+return {
+    deserialise = deserialise
+}
@@ -0,0 +1,124 @@
+-- ECMWF (centre 98) local data in section 2, i.e. the RDB key.
+--
+-- Latitudes and longitudes are stored as unsigned integers in units of 1e-5 degree
+-- and offset by 90 and 180 degrees respectively, i.e.
+--     latitude = (localLatitude - 9000000) / 100000
+--     longitude = (localLongitude - 18000000) / 100000
+
+local RDB_KEY_LENGTH_IN_BYTES = 52
+
+-- RDB types of satellite data, which have a bounding box instead of a single location
+local SATELLITE_RDB_TYPES = { [2] = true, [3] = true, [8] = true, [12] = true }
+
+local function deserialiseTime(prefix)
+    factory.newField { prefix .. 'Day', UINT, 6; }
+    factory.newField { prefix .. 'Hour', UINT, 5; }
+    factory.newField { prefix .. 'Minute', UINT, 6; }
+    factory.newField { prefix .. 'Second', UINT, 6; }
+    factory.newField { 'spareBits', BINARY, 1; }
+end
+
+local function deserialiseLocation(suffix)
+    factory.newField { 'localLongitude' .. suffix, UINT, 26; }
+    factory.newField { 'spareBits', BINARY, 6; }
+    factory.newField { 'localLatitude' .. suffix, UINT, 25; }
+    factory.newField { 'spareBits', BINARY, 7; }
+end
+
+local function deserialise()
+    local section = factory.newSection(2, 'Optional Section')
+
+    local lengthInBytes = factory.newField {
+        'lengthInBytes', UINT, 24;
+    }
+
+    factory.newField {
+        'reservedBits', BINARY, 8;
+    }
+
+    -- Not an RDB key, keep the local bits as is
+    if lengthInBytes:value() ~= RDB_KEY_LENGTH_IN_BYTES then
+        factory.newField {
+            'localBits', BINARY, (lengthInBytes:value() - 4) * BITS_PER_BYTE;
+        }
+        return section
+    end
+
+    local rdbType = factory.newField {
+        'rdbType', UINT, 8;
+    }
+
+    factory.newField {
+        'oldSubtype', UINT, 8;
+    }
+
+    -- Observation time
+    factory.newField { 'localYear', UINT, 12; }
+    factory.newField { 'localMonth', UINT, 4; }
+    factory.newField { 'localDay', UINT, 6; }
+    factory.newField { 'localHour', UINT, 5; }
+    factory.newField { 'localMinute', UINT, 6; }
+    factory.newField { 'localSecond', UINT, 6; }
+    factory.newField { 'spareBits', BINARY, 1; }
+
+    if SATELLITE_RDB_TYPES[rdbType:value()] then
+        deserialiseLocation('1')
+        deserialiseLocation('2')
+
+        factory.newField {
+            'localNumberOfObservations', UINT, 8;
+        }
+
+        factory.newField {
+            'satelliteID', UINT, 16;
+        }
+
+        factory.newField {
+            'spareBits', BINARY, 48;
+        }
+    else
+        deserialiseLocation('')
+
+        factory.newField {
+            'ident', BYTES, 9 * BITS_PER_BYTE;
+        }
+
+        factory.newField {
+            'spareBits', BINARY, 64;
+        }
+    end
+
+    factory.newField {
+        'messageLength', UINT, 16;
+    }
+
+    -- Time of insertion into RDB and time of receipt
+    deserialiseTime('rdbtime')
+    deserialiseTime('rectime')
+
+    for i = 1, 4 do
+        factory.newField { 'correction' .. i, UINT, 6; }
+        factory.newField { 'correction' .. i .. 'Part', UINT, 1; }
+        factory.newField { 'spareBits', BINARY, 1; }
+    end
+
+    factory.newField {
+        'qualityControl', UINT, 8;
+    }
+
+    factory.newField {
+        'newSubtype', UINT, 16;
+    }
+
+    factory.newField {
+        'daLoop', UINT, 8;
+    }
+
+    factory.padding(lengthInBytes:value())
+
+    return section
+end
+
+return {
+    deserialise = deserialise
+}
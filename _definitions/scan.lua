@@ -0,0 +1,21 @@
+-- Entry script for scanning, i.e. only the header sections are deserialised
+-- and the rest of each message is skipped.
+
+-- Skip anything in front of the message, e.g. GTS bulletin envelope
+if not factory.seekStartSignature() then
+    return nil
+end
+
+local editionNumber = factory.peekEditionNumber()
+
+if editionNumber == 4 then
+    local bufr4 = require 'bufr4.bufr4'
+    return bufr4.scan()
+
+elseif editionNumber == 3 then
+    local bufr3 = require 'bufr3.bufr3'
+    return bufr3.scan()
+
+else
+    error("Invalid BUFR edition number: " .. editionNumber)
+end
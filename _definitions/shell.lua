@@ -0,0 +1,81 @@
+-- Helper functions of the interactive shell.
+-- The shell preloads the list of decoded messages as messages and the first
+-- message as message. Functions taking an optional message m default to message.
+
+-- Make the n-th message the current message
+function use(n)
+    message = assert(messages[n], 'no message ' .. tostring(n))
+    return message
+end
+
+-- Return the header field of the given name, e.g. field('nSubsets')
+function field(name, m)
+    return (m or message):getProxyField(name)
+end
+
+-- Return the payload
+function payload(m)
+    return field('payload', m):value()
+end
+
+-- Return the i-th subset
+function subset(i, m)
+    return payload(m):getSubset(i)
+end
+
+-- Print all cells of the i-th subset, default to the first one
+function cells(i, m)
+    for _, cell in subset(i or 1, m):cells() do
+        print(cell)
+    end
+end
+
+-- Print the node tree of the i-th subset, default to the first one
+function tree(i, m)
+    local function walk(node, indent)
+        if node:kind() == 'valued' then
+            print(indent .. tostring(node:cell()))
+        else
+            print(indent .. tostring(node))
+        end
+        for _, member in node:members() do
+            walk(member, indent .. '  ')
+        end
+    end
+    walk(subset(i or 1, m):root(), '')
+end
+
+-- Pretty print a value. Tables are printed recursively.
+function dump(v, indent)
+    indent = indent or ''
+    if type(v) ~= 'table' then
+        print(indent .. tostring(v))
+        return
+    end
+    for key, value in pairs(v) do
+        if type(value) == 'table' then
+            print(indent .. tostring(key) .. ':')
+            dump(value, indent .. '  ')
+        else
+            print(indent .. tostring(key) .. ' = ' .. tostring(value))
+        end
+    end
+end
+
+function help()
+    print([[
+Globals:
+  messages            list of decoded messages
+  message             the current message
+  factory             the deserializer library of the definitions scripts
+Functions:
+  use(n)              make the n-th message the current message
+  field(name, [m])    header field of the given name, e.g. field('nSubsets')
+  payload([m])        the payload, e.g. #payload() is the number of subsets
+  subset(i, [m])      the i-th subset, e.g. subset(1):find(12101):value()
+  cells([i], [m])     print all cells of the i-th subset
+  tree([i], [m])      print the node tree of the i-th subset
+  dump(v)             pretty print a value, e.g. dump(subset(1):findAll(12101))
+Methods of messages, sections, fields, payloads, subsets, cells and nodes are
+listed by their metatables, e.g. dump(getmetatable(message)).]])
+end
@@ -5,6 +5,7 @@ import (
     "fmt"
     "github.com/ywangd/gobufrkit/bufr"
     "github.com/ywangd/gobufrkit/tdcfio"
+    "github.com/ywangd/gobufrkit/table"
 )
 
 // Register a new metatable and set itself to be its index lookup
@@ -32,11 +33,117 @@ func pushField(state *lua.State, field *bufr.Field) {
     lua.SetMetaTableNamed(state, FIELD_META_TABLE)
 }
 
+// subsetCell is a cell together with the subset it belongs to. The subset is
+// needed for looking up values of the cell's attributes.
+type subsetCell struct {
+    subset *bufr.Subset
+    cell   *bufr.Cell
+}
+
+// subsetNode is a node together with the subset it belongs to. The subset is
+// needed for looking up the cell of a valued node.
+type subsetNode struct {
+    subset *bufr.Subset
+    node   bufr.Node
+}
+
+// Push a payload onto the stack and set its corresponding metatable
+func pushPayload(state *lua.State, payload *bufr.Payload) {
+    state.PushUserData(payload)
+    lua.SetMetaTableNamed(state, PAYLOAD_META_TABLE)
+}
+
+// Push a subset onto the stack and set its corresponding metatable
+func pushSubset(state *lua.State, subset *bufr.Subset) {
+    state.PushUserData(subset)
+    lua.SetMetaTableNamed(state, SUBSET_META_TABLE)
+}
+
+// Push a cell of the given subset onto the stack and set its corresponding metatable
+func pushCell(state *lua.State, subset *bufr.Subset, cell *bufr.Cell) {
+    state.PushUserData(&subsetCell{subset: subset, cell: cell})
+    lua.SetMetaTableNamed(state, CELL_META_TABLE)
+}
+
+// Push a node of the given subset onto the stack and set its corresponding metatable
+func pushNode(state *lua.State, subset *bufr.Subset, node bufr.Node) {
+    state.PushUserData(&subsetNode{subset: subset, node: node})
+    lua.SetMetaTableNamed(state, NODE_META_TABLE)
+}
+
+// Push a Go function for the generic for loop. Each call of the function returns
+// the one based index and the element pushed by push for each of the n elements.
+func pushIterator(state *lua.State, n int, push func(state *lua.State, i int)) {
+    i := 0
+    state.PushGoFunction(func(state *lua.State) int {
+        if i >= n {
+            state.PushNil()
+            return 1
+        }
+        i++
+        state.PushInteger(i)
+        push(state, i-1)
+        return 2
+    })
+}
+
+// Get a one based index argument and convert it to zero based. It raises an
+// error if the index is out of range of n elements.
+func checkIndex(state *lua.State, arg int, n int) int {
+    i := lua.CheckInteger(state, arg)
+    if i < 1 || i > n {
+        lua.Errorf(state, "index out of range [1, %d]: %d", n, i)
+    }
+    return i - 1
+}
+
+// Get a descriptor ID argument, e.g. 12101 or "012101"
+func checkId(state *lua.State, arg int) table.ID {
+    return table.ID(lua.CheckInteger(state, arg))
+}
+
+// Push the ID of a descriptor, or nil if there is no descriptor
+func pushDescriptorId(state *lua.State, descriptor table.Descriptor) {
+    if descriptor == nil {
+        state.PushNil()
+        return
+    }
+    state.PushInteger(int(descriptor.Id()))
+}
+
+// Push the name of a descriptor from its table entry, or nil if there is no entry
+func pushDescriptorName(state *lua.State, descriptor table.Descriptor) {
+    if descriptor == nil || descriptor.Entry() == nil {
+        state.PushNil()
+        return
+    }
+    state.PushString(descriptor.Entry().Name())
+}
+
+// Push the value of a cell. Missing values are pushed as nil.
+func pushCellValue(state *lua.State, cell *bufr.Cell) error {
+    switch v := cell.Value().(type) {
+    case nil:
+        state.PushNil()
+    case []byte:
+        state.PushString(string(v))
+    case *tdcfio.Binary:
+        state.PushUserData(v)
+    default:
+        return pushSimpleValue(state, v)
+    }
+    return nil
+}
+
 // Push the value of a field to stack
 func pushFieldValue(state *lua.State, field *bufr.Field) error {
     switch field.Value.(type) {
+    case *bufr.Payload:
+        pushPayload(state, field.Value.(*bufr.Payload))
     case *tdcfio.Binary:
         state.PushUserData(field.Value.(*tdcfio.Binary))
+    case []byte:
+        state.PushString(string(field.Value.([]byte)))
     default:
         return pushSimpleValue(state, field.Value)
     }
@@ -1,6 +1,8 @@
 package api
 
 import (
+    "context"
+    "io"
     "github.com/Shopify/go-lua"
     "github.com/ywangd/gobufrkit/deserialize"
 )
@@ -9,6 +11,11 @@ const DESERIALIZER_META_TABLE = "gobufrkit.deserializer"
 
 type LibDeserializer struct {
     factory deserialize.Factory
+    // Context of the current run of the deserializer
+    ctx context.Context
+    // Set when deserializing the payload is cancelled. Lua errors can only carry
+    // strings, so the typed error is kept here for the runtime to return.
+    cancelled *deserialize.CancelledError
 }
 
 func (lib *LibDeserializer) getMessage(state *lua.State) int {
@@ -74,6 +81,36 @@ func (lib *LibDeserializer) newField(state *lua.State) int {
     return 1
 }
 
+func (lib *LibDeserializer) newVirtualField(state *lua.State) int {
+    state.RawGetInt(1, 1)
+    name, _ := state.ToString(-1)
+
+    state.RawGetInt(1, 2)
+    var value interface{}
+    switch state.TypeOf(-1) {
+    case lua.TypeString:
+        value, _ = state.ToString(-1)
+    case lua.TypeBoolean:
+        value = state.ToBoolean(-1)
+    case lua.TypeNumber:
+        n, _ := state.ToNumber(-1)
+        if i, ok := state.ToInteger(-1); ok && float64(i) == n {
+            value = i
+        } else {
+            value = n
+        }
+    default:
+        lua.Errorf(state, "invalid value of virtual field %s: %s", name, lua.TypeNameOf(state, -1))
+    }
+
+    state.Field(1, "proxy")
+    proxy := state.ToBoolean(-1)
+
+    field := lib.factory.NewVirtualField(name, value, proxy)
+    pushField(state, field)
+    return 1
+}
+
 func (lib *LibDeserializer) newTemplateField(state *lua.State) int {
     state.RawGetInt(1, 1)
     name, _ := state.ToString(-1)
@@ -100,6 +137,20 @@ func (lib *LibDeserializer) newTemplateField(state *lua.State) int {
     return 1
 }
 
+func (lib *LibDeserializer) newCrexTemplateField(state *lua.State) int {
+    state.RawGetInt(1, 1)
+    name, _ := state.ToString(-1)
+
+    field, err := lib.factory.NewCrexTemplateField(name)
+    if err != nil {
+        state.PushString(err.Error())
+        state.Error()
+        return 0
+    }
+    pushField(state, field)
+    return 1
+}
+
 func (lib *LibDeserializer) newPayloadField(state *lua.State) int {
     state.RawGetInt(1, 1)
     name, _ := state.ToString(-1)
@@ -110,8 +161,11 @@ func (lib *LibDeserializer) newPayloadField(state *lua.State) int {
     state.RawGetInt(1, 3)
     compressed := state.ToBoolean(-1)
 
-    field, err := lib.factory.NewPayloadField(name, nsubsets, compressed)
+    field, err := lib.factory.NewPayloadField(lib.ctx, name, nsubsets, compressed)
     if err != nil {
+        if cancelled, ok := err.(*deserialize.CancelledError); ok {
+            lib.cancelled = cancelled
+        }
         state.PushString(err.Error())
         state.Error()
         return 0
@@ -131,6 +185,16 @@ func (lib *LibDeserializer) padding(state *lua.State) int {
     return 0
 }
 
+func (lib *LibDeserializer) skipMessage(state *lua.State) int {
+    totalLengthInBytes, _ := state.ToUnsigned(1)
+    if err := lib.factory.SkipMessage(totalLengthInBytes); err != nil {
+        state.PushString(err.Error())
+        state.Error()
+        return 0
+    }
+    return 0
+}
+
 func (lib *LibDeserializer) peekEditionNumber(state *lua.State) int {
     v, err := lib.factory.PeekEditionNumber()
     if err != nil {
@@ -142,16 +206,36 @@ func (lib *LibDeserializer) peekEditionNumber(state *lua.State) int {
     return 1
 }
 
+// seekStartSignature pushes true if a start signature is found or false if EOF is reached
+func (lib *LibDeserializer) seekStartSignature(state *lua.State) int {
+    err := lib.factory.SeekStartSignature()
+    if err == io.EOF {
+        state.PushBoolean(false)
+        return 1
+    }
+    if err != nil {
+        state.PushString(err.Error())
+        state.Error()
+        return 0
+    }
+    state.PushBoolean(true)
+    return 1
+}
+
 func (lib *LibDeserializer) Register(state *lua.State) int {
     lua.NewLibrary(state, []lua.RegistryFunction{
         {Name: "getMessage", Function: lib.getMessage},
         {Name: "newMessage", Function: lib.newMessage},
         {Name: "newSection", Function: lib.newSection},
         {Name: "newField", Function: lib.newField},
+        {Name: "newVirtualField", Function: lib.newVirtualField},
         {Name: "newTemplateField", Function: lib.newTemplateField},
+        {Name: "newCrexTemplateField", Function: lib.newCrexTemplateField},
         {Name: "newPayloadField", Function: lib.newPayloadField},
         {Name: "padding", Function: lib.padding},
+        {Name: "skipMessage", Function: lib.skipMessage},
         {Name: "peekEditionNumber", Function: lib.peekEditionNumber},
+        {Name: "seekStartSignature", Function: lib.seekStartSignature},
         {Name: "initTableGroup", Function: lib.initTableGroup},
     })
     return 1
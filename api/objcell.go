@@ -0,0 +1,113 @@
+package api
+
+import (
+    "github.com/Shopify/go-lua"
+    "github.com/ywangd/gobufrkit/bufr"
+)
+
+const CELL_META_TABLE = "gobufrkit.cell"
+
+type ObjCell struct {
+}
+
+func (obj *ObjCell) cellToString(state *lua.State) int {
+    sc := state.ToUserData(1).(*subsetCell)
+    state.PushString(sc.cell.String())
+    return 1
+}
+
+// value returns the decoded value with scale and refval applied, or nil if it is missing
+func (obj *ObjCell) value(state *lua.State) int {
+    sc := state.ToUserData(1).(*subsetCell)
+    if err := pushCellValue(state, sc.cell); err != nil {
+        state.PushString(err.Error())
+        state.Error()
+        return 0
+    }
+    return 1
+}
+
+func (obj *ObjCell) isMissing(state *lua.State) int {
+    sc := state.ToUserData(1).(*subsetCell)
+    state.PushBoolean(sc.cell.Value() == nil)
+    return 1
+}
+
+func (obj *ObjCell) unit(state *lua.State) int {
+    sc := state.ToUserData(1).(*subsetCell)
+    state.PushString(sc.cell.Unit())
+    return 1
+}
+
+// descriptor returns the descriptor ID as an integer, e.g. 12101
+func (obj *ObjCell) descriptor(state *lua.State) int {
+    sc := state.ToUserData(1).(*subsetCell)
+    pushDescriptorId(state, sc.cell.Node().Descriptor)
+    return 1
+}
+
+func (obj *ObjCell) name(state *lua.State) int {
+    sc := state.ToUserData(1).(*subsetCell)
+    pushDescriptorName(state, sc.cell.Node().Descriptor)
+    return 1
+}
+
+// index returns the one based index of the cell in its subset
+func (obj *ObjCell) index(state *lua.State) int {
+    sc := state.ToUserData(1).(*subsetCell)
+    state.PushInteger(sc.cell.Node().Index + 1)
+    return 1
+}
+
+func (obj *ObjCell) node(state *lua.State) int {
+    sc := state.ToUserData(1).(*subsetCell)
+    pushNode(state, sc.subset, sc.cell.Node())
+    return 1
+}
+
+// attributes returns a list of the attribute cells, e.g. associated fields
+func (obj *ObjCell) attributes(state *lua.State) int {
+    sc := state.ToUserData(1).(*subsetCell)
+    state.NewTable()
+    n := 0
+    for _, member := range sc.cell.Node().Members() {
+        if vn, ok := member.(*bufr.ValuedNode); ok {
+            n++
+            pushCell(state, sc.subset, sc.subset.Cell(vn.Index))
+            state.RawSetInt(-2, n)
+        }
+    }
+    return 1
+}
+
+// getAttribute returns the first attribute cell of the given descriptor ID,
+// or nil if none is found
+func (obj *ObjCell) getAttribute(state *lua.State) int {
+    sc := state.ToUserData(1).(*subsetCell)
+    id := checkId(state, 2)
+    for _, member := range sc.cell.Node().Members() {
+        if vn, ok := member.(*bufr.ValuedNode); ok && vn.Descriptor.Id() == id {
+            pushCell(state, sc.subset, sc.subset.Cell(vn.Index))
+            return 1
+        }
+    }
+    state.PushNil()
+    return 1
+}
+
+func (obj *ObjCell) registerCellType(state *lua.State) {
+    registerMetaTable(state, CELL_META_TABLE)
+
+    lua.SetFunctions(state, []lua.RegistryFunction{
+        {Name: "__tostring", Function: obj.cellToString},
+        {Name: "value", Function: obj.value},
+        {Name: "isMissing", Function: obj.isMissing},
+        {Name: "unit", Function: obj.unit},
+        {Name: "descriptor", Function: obj.descriptor},
+        {Name: "name", Function: obj.name},
+        {Name: "index", Function: obj.index},
+        {Name: "node", Function: obj.node},
+        {Name: "attributes", Function: obj.attributes},
+        {Name: "getAttribute", Function: obj.getAttribute},
+    }, 0)
+}
@@ -4,6 +4,7 @@ import (
     "github.com/Shopify/go-lua"
     "fmt"
     "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/tdcfio"
 )
 
 const FIELD_META_TABLE = "gobufrkit.field"
@@ -11,11 +12,37 @@ const FIELD_META_TABLE = "gobufrkit.field"
 type ObjField struct {
 }
 
-// Provide nice to read string representation of the field object for lua
-// TODO: Improve the string format
+// Provide nice to read string representation of the field object for lua, e.g.
+//   nSubsets = 2 (16 bits)
+//   flagBits = 0000000 (7 bits)
+//   payload = 2 subsets, uncompressed (1024 bits)
 func (obj *ObjField) fieldToString(state *lua.State) int {
     field := state.ToUserData(1).(*bufr.Field)
-    state.PushString(fmt.Sprintf("%+v", field))
+
+    var value string
+    switch v := field.Value.(type) {
+    case *bufr.Payload:
+        value = fmt.Sprintf("%d subsets, uncompressed", len(v.Subsets()))
+        if v.Compressed {
+            value = fmt.Sprintf("%d subsets, compressed", len(v.Subsets()))
+        }
+    case *tdcfio.Binary:
+        value = v.String()
+    case []byte:
+        value = fmt.Sprintf("%q", v)
+    case string:
+        value = fmt.Sprintf("%q", v)
+    default:
+        value = fmt.Sprint(v)
+    }
+    if field.IsMissing() {
+        value += " (missing)"
+    }
+    if field.Virtual {
+        state.PushString(fmt.Sprintf("%v = %v (virtual)", field.Name, value))
+        return 1
+    }
+    state.PushString(fmt.Sprintf("%v = %v (%d bits)", field.Name, value, field.Nbits))
     return 1
 }
 
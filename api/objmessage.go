@@ -3,6 +3,7 @@ package api
 import (
     "github.com/Shopify/go-lua"
     "fmt"
+    "strings"
     "github.com/ywangd/gobufrkit/bufr"
 )
 
@@ -11,10 +12,35 @@ const MESSAGE_META_TABLE = "gobufrkit.message"
 type ObjMessage struct {
 }
 
-// TODO: Improve the string format
+// Provide a one line summary of the message, e.g.
+//   message 1 of input.bufr: edition 4, 6 sections, 2 subsets, uncompressed
 func (obj *ObjMessage) messageToString(state *lua.State) int {
     message := state.ToUserData(1).(*bufr.Message)
-    state.PushString(fmt.Sprintf("%+v", message))
+
+    s := "message"
+    if number := message.Metadata("number"); number != nil {
+        s += fmt.Sprintf(" %v", number)
+    }
+    if inputPath, _ := message.Metadata("inputPath").(string); inputPath != "" {
+        s += " of " + inputPath
+    }
+
+    var details []string
+    if field, err := message.ProxyField("bufrEditionNumber"); err == nil {
+        details = append(details, fmt.Sprintf("edition %v", field.Value))
+    }
+    details = append(details, fmt.Sprintf("%d sections", len(message.Sections())))
+    if field, err := message.ProxyField("nSubsets"); err == nil {
+        details = append(details, fmt.Sprintf("%v subsets", field.Value))
+    }
+    if field, err := message.ProxyField("isCompressed"); err == nil {
+        if field.Value == true {
+            details = append(details, "compressed")
+        } else {
+            details = append(details, "uncompressed")
+        }
+    }
+    state.PushString(s + ": " + strings.Join(details, ", "))
     return 1
 }
 
@@ -39,6 +65,27 @@ func (obj *ObjMessage) getProxyField(state *lua.State) int {
     return 1
 }
 
+// getMetadata returns the metadata value of the given name, e.g. inputPath and number,
+// or nil if it is not set
+func (obj *ObjMessage) getMetadata(state *lua.State) int {
+    message := state.ToUserData(1).(*bufr.Message)
+    name, _ := state.ToString(2)
+    value := message.Metadata(name)
+    if value == nil {
+        state.PushNil()
+    } else if err := pushSimpleValue(state, value); err != nil {
+        state.PushString(fmt.Sprint(value))
+    }
+    return 1
+}
+
+// typicalDateTime returns the typical date and time of section 1 in ISO 8601
+func (obj *ObjMessage) typicalDateTime(state *lua.State) int {
+    message := state.ToUserData(1).(*bufr.Message)
+    state.PushString(message.TypicalDateTime())
+    return 1
+}
+
 func (obj *ObjMessage) registerGribMessageType(state *lua.State) {
 
     lua.NewMetaTable(state, MESSAGE_META_TABLE)
@@ -51,6 +98,8 @@ func (obj *ObjMessage) registerGribMessageType(state *lua.State) {
         {Name: "__tostring", Function: obj.messageToString},
         {Name: "setProxyField", Function: obj.setProxyField},
         {Name: "getProxyField", Function: obj.getProxyField},
+        {Name: "getMetadata", Function: obj.getMetadata},
+        {Name: "typicalDateTime", Function: obj.typicalDateTime},
     }, 0)
 
 }
@@ -0,0 +1,119 @@
+package api
+
+import (
+    "github.com/Shopify/go-lua"
+    "fmt"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/table"
+)
+
+const NODE_META_TABLE = "gobufrkit.node"
+
+// Kinds of nodes as seen by Lua scripts
+const (
+    NODE_KIND_BLOCK     = "block"
+    NODE_KIND_VALUELESS = "valueless"
+    NODE_KIND_VALUED    = "valued"
+)
+
+type ObjNode struct {
+}
+
+func nodeDescriptor(node bufr.Node) table.Descriptor {
+    switch n := node.(type) {
+    case *bufr.ValuedNode:
+        return n.Descriptor
+    case *bufr.ValuelessNode:
+        return n.Descriptor
+    }
+    return nil
+}
+
+func (obj *ObjNode) nodeToString(state *lua.State) int {
+    sn := state.ToUserData(1).(*subsetNode)
+    if descriptor := nodeDescriptor(sn.node); descriptor != nil {
+        state.PushString(fmt.Sprintf("%v", descriptor))
+    } else {
+        state.PushString(fmt.Sprintf("block of %d members", len(sn.node.Members())))
+    }
+    return 1
+}
+
+// kind returns one of "block", "valueless" and "valued"
+func (obj *ObjNode) kind(state *lua.State) int {
+    sn := state.ToUserData(1).(*subsetNode)
+    switch sn.node.(type) {
+    case *bufr.ValuedNode:
+        state.PushString(NODE_KIND_VALUED)
+    case *bufr.ValuelessNode:
+        state.PushString(NODE_KIND_VALUELESS)
+    default:
+        state.PushString(NODE_KIND_BLOCK)
+    }
+    return 1
+}
+
+// descriptor returns the descriptor ID as an integer, or nil for a block
+func (obj *ObjNode) descriptor(state *lua.State) int {
+    sn := state.ToUserData(1).(*subsetNode)
+    pushDescriptorId(state, nodeDescriptor(sn.node))
+    return 1
+}
+
+func (obj *ObjNode) name(state *lua.State) int {
+    sn := state.ToUserData(1).(*subsetNode)
+    pushDescriptorName(state, nodeDescriptor(sn.node))
+    return 1
+}
+
+func (obj *ObjNode) getNumberOfMembers(state *lua.State) int {
+    sn := state.ToUserData(1).(*subsetNode)
+    state.PushInteger(len(sn.node.Members()))
+    return 1
+}
+
+// getMember returns the member node of the given one based index
+func (obj *ObjNode) getMember(state *lua.State) int {
+    sn := state.ToUserData(1).(*subsetNode)
+    members := sn.node.Members()
+    i := checkIndex(state, 2, len(members))
+    pushNode(state, sn.subset, members[i])
+    return 1
+}
+
+// members returns an iterator over the index and member node pairs
+func (obj *ObjNode) members(state *lua.State) int {
+    sn := state.ToUserData(1).(*subsetNode)
+    members := sn.node.Members()
+    pushIterator(state, len(members), func(state *lua.State, i int) {
+        pushNode(state, sn.subset, members[i])
+    })
+    return 1
+}
+
+// cell returns the cell of a valued node, or nil for other nodes
+func (obj *ObjNode) cell(state *lua.State) int {
+    sn := state.ToUserData(1).(*subsetNode)
+    if vn, ok := sn.node.(*bufr.ValuedNode); ok {
+        pushCell(state, sn.subset, sn.subset.Cell(vn.Index))
+    } else {
+        state.PushNil()
+    }
+    return 1
+}
+
+func (obj *ObjNode) registerNodeType(state *lua.State) {
+    registerMetaTable(state, NODE_META_TABLE)
+
+    lua.SetFunctions(state, []lua.RegistryFunction{
+        {Name: "__tostring", Function: obj.nodeToString},
+        {Name: "__len", Function: obj.getNumberOfMembers},
+        {Name: "kind", Function: obj.kind},
+        {Name: "descriptor", Function: obj.descriptor},
+        {Name: "name", Function: obj.name},
+        {Name: "getNumberOfMembers", Function: obj.getNumberOfMembers},
+        {Name: "getMember", Function: obj.getMember},
+        {Name: "members", Function: obj.members},
+        {Name: "cell", Function: obj.cell},
+    }, 0)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+    "github.com/Shopify/go-lua"
+    "fmt"
+    "github.com/ywangd/gobufrkit/bufr"
+)
+
+const PAYLOAD_META_TABLE = "gobufrkit.payload"
+
+type ObjPayload struct {
+}
+
+func (obj *ObjPayload) payloadToString(state *lua.State) int {
+    payload := state.ToUserData(1).(*bufr.Payload)
+    state.PushString(fmt.Sprintf("payload of %d subsets (compressed: %v)",
+        len(payload.Subsets()), payload.Compressed))
+    return 1
+}
+
+func (obj *ObjPayload) isCompressed(state *lua.State) int {
+    payload := state.ToUserData(1).(*bufr.Payload)
+    state.PushBoolean(payload.Compressed)
+    return 1
+}
+
+func (obj *ObjPayload) getNumberOfSubsets(state *lua.State) int {
+    payload := state.ToUserData(1).(*bufr.Payload)
+    state.PushInteger(len(payload.Subsets()))
+    return 1
+}
+
+// getSubset returns the subset of the given one based index
+func (obj *ObjPayload) getSubset(state *lua.State) int {
+    payload := state.ToUserData(1).(*bufr.Payload)
+    i := checkIndex(state, 2, len(payload.Subsets()))
+    pushSubset(state, payload.Subset(i))
+    return 1
+}
+
+// subsets returns an iterator over the index and subset pairs, e.g.
+//   for i, subset in payload:subsets() do ... end
+func (obj *ObjPayload) subsets(state *lua.State) int {
+    payload := state.ToUserData(1).(*bufr.Payload)
+    subsets := payload.Subsets()
+    pushIterator(state, len(subsets), func(state *lua.State, i int) {
+        pushSubset(state, subsets[i])
+    })
+    return 1
+}
+
+func (obj *ObjPayload) registerPayloadType(state *lua.State) {
+    registerMetaTable(state, PAYLOAD_META_TABLE)
+
+    lua.SetFunctions(state, []lua.RegistryFunction{
+        {Name: "__tostring", Function: obj.payloadToString},
+        {Name: "__len", Function: obj.getNumberOfSubsets},
+        {Name: "isCompressed", Function: obj.isCompressed},
+        {Name: "getNumberOfSubsets", Function: obj.getNumberOfSubsets},
+        {Name: "getSubset", Function: obj.getSubset},
+        {Name: "subsets", Function: obj.subsets},
+    }, 0)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+    "testing"
+    "context"
+    "os"
+    "github.com/Shopify/go-lua"
+    assert2 "github.com/seanpont/assert"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+func TestPayloadBindings(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    ins, err := os.Open("../_testdata/contrived.bufr")
+    assert.Nil(err)
+    defer ins.Close()
+    message, err := DecodeMessageAt(context.Background(), &Config{
+        DefinitionsPath: "../_definitions",
+        TablesPath:      "../_definitions/tables",
+        InputType:       tdcfio.BinaryInput,
+    }, ins, 0)
+    assert.Nil(err)
+
+    r := NewScriptRt("../_definitions", BOOT_SCRIPT, nil)
+    lua.OpenLibraries(r.state)
+    r.initTypes()
+    r.NewGlobalUserData("message", message, MESSAGE_META_TABLE)
+
+    assert.Nil(lua.DoString(r.state, `
+        local payload = message:getProxyField("payload"):value()
+        assert(#payload == 2 and payload:getNumberOfSubsets() == 2)
+        assert(not payload:isCompressed())
+
+        local n = 0
+        for i, subset in payload:subsets() do
+            assert(subset:index() == i)
+            n = n + 1
+        end
+        assert(n == 2)
+
+        local subset = payload:getSubset(2)
+        assert(#subset == subset:getNumberOfCells())
+        local cell = subset:getCell(2)
+        assert(cell:descriptor() == 1002 and cell:value() == 888)
+        assert(cell:name() == "WMO STATION NUMBER")
+        assert(cell:index() == 2 and not cell:isMissing())
+        assert(#cell:attributes() == 0 and cell:getAttribute(33007) == nil)
+
+        assert(subset:find("001001"):value() == 95)
+        assert(subset:find(1001):unit() == "Numeric")
+        assert(subset:find(999999) == nil)
+        local factors = subset:findAll(31001)
+        assert(#factors == 2 and factors[2]:index() > factors[1]:index())
+
+        local ncells = 0
+        for i, c in subset:cells() do
+            assert(c:index() == i)
+            ncells = ncells + 1
+        end
+        assert(ncells == #subset)
+
+        -- Walk the node tree and count the valued nodes
+        local function count(node)
+            local n = 0
+            if node:kind() == "valued" then
+                assert(node:cell():descriptor() == node:descriptor())
+                n = 1
+            end
+            for _, member in node:members() do
+                n = n + count(member)
+            end
+            return n
+        end
+        local root = subset:root()
+        assert(root:kind() == "block" and root:descriptor() == nil)
+        assert(count(root) == #subset)
+        assert(root:getMember(1):descriptor() == 301001)
+        assert(#root:getMember(1) == 2)
+    `))
+
+    err = lua.DoString(r.state, `message:getProxyField("payload"):value():getSubset(3)`)
+    assert.NotNil(err)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
     "github.com/Shopify/go-lua"
+    "fmt"
     "github.com/ywangd/gobufrkit/bufr"
 )
 
@@ -10,6 +11,15 @@ const SECTION_META_TABLE = "gobufrkit.section"
 type ObjSection struct {
 }
 
+// Provide a one line summary of the section, e.g.
+//   section 3 (Data Description Section): 7 fields
+func (obj *ObjSection) sectionToString(state *lua.State) int {
+    section := state.ToUserData(1).(*bufr.Section)
+    state.PushString(fmt.Sprintf("section %d (%v): %d fields",
+        section.Number(), section.Metadata("description"), len(section.Fields())))
+    return 1
+}
+
 func (obj *ObjSection) getField(state *lua.State) int {
     section := state.ToUserData(1).(*bufr.Section)
     name, _ := state.ToString(2)
@@ -31,6 +41,7 @@ func (obj *ObjSection) registerGribSectionType(state *lua.State) {
     state.SetField(-2, "__index")
 
     lua.SetFunctions(state, []lua.RegistryFunction{
+        {Name: "__tostring", Function: obj.sectionToString},
         {Name: "getField", Function: obj.getField},
         {Name: "getNumberOfFields", Function: obj.getNumberOfFields},
     }, 0)
@@ -0,0 +1,102 @@
+package api
+
+import (
+    "github.com/Shopify/go-lua"
+    "fmt"
+    "github.com/ywangd/gobufrkit/bufr"
+)
+
+const SUBSET_META_TABLE = "gobufrkit.subset"
+
+type ObjSubset struct {
+}
+
+func (obj *ObjSubset) subsetToString(state *lua.State) int {
+    subset := state.ToUserData(1).(*bufr.Subset)
+    state.PushString(fmt.Sprintf("subset %d of %d cells", subset.Index()+1, len(subset.Cells())))
+    return 1
+}
+
+// index returns the one based index of the subset in the payload
+func (obj *ObjSubset) index(state *lua.State) int {
+    subset := state.ToUserData(1).(*bufr.Subset)
+    state.PushInteger(subset.Index() + 1)
+    return 1
+}
+
+func (obj *ObjSubset) getNumberOfCells(state *lua.State) int {
+    subset := state.ToUserData(1).(*bufr.Subset)
+    state.PushInteger(len(subset.Cells()))
+    return 1
+}
+
+// getCell returns the cell of the given one based index
+func (obj *ObjSubset) getCell(state *lua.State) int {
+    subset := state.ToUserData(1).(*bufr.Subset)
+    i := checkIndex(state, 2, len(subset.Cells()))
+    pushCell(state, subset, subset.Cell(i))
+    return 1
+}
+
+// cells returns an iterator over the index and cell pairs in the order of decoding
+func (obj *ObjSubset) cells(state *lua.State) int {
+    subset := state.ToUserData(1).(*bufr.Subset)
+    cells := subset.Cells()
+    pushIterator(state, len(cells), func(state *lua.State, i int) {
+        pushCell(state, subset, cells[i])
+    })
+    return 1
+}
+
+// root returns the root node of the hierarchical structure of the subset
+func (obj *ObjSubset) root(state *lua.State) int {
+    subset := state.ToUserData(1).(*bufr.Subset)
+    pushNode(state, subset, subset.Root())
+    return 1
+}
+
+// find returns the first cell of the given descriptor ID, or nil if none is found
+func (obj *ObjSubset) find(state *lua.State) int {
+    subset := state.ToUserData(1).(*bufr.Subset)
+    id := checkId(state, 2)
+    for _, cell := range subset.Cells() {
+        if cell.Node().Descriptor.Id() == id {
+            pushCell(state, subset, cell)
+            return 1
+        }
+    }
+    state.PushNil()
+    return 1
+}
+
+// findAll returns a list of all cells of the given descriptor ID
+func (obj *ObjSubset) findAll(state *lua.State) int {
+    subset := state.ToUserData(1).(*bufr.Subset)
+    id := checkId(state, 2)
+    state.NewTable()
+    n := 0
+    for _, cell := range subset.Cells() {
+        if cell.Node().Descriptor.Id() == id {
+            n++
+            pushCell(state, subset, cell)
+            state.RawSetInt(-2, n)
+        }
+    }
+    return 1
+}
+
+func (obj *ObjSubset) registerSubsetType(state *lua.State) {
+    registerMetaTable(state, SUBSET_META_TABLE)
+
+    lua.SetFunctions(state, []lua.RegistryFunction{
+        {Name: "__tostring", Function: obj.subsetToString},
+        {Name: "__len", Function: obj.getNumberOfCells},
+        {Name: "index", Function: obj.index},
+        {Name: "getNumberOfCells", Function: obj.getNumberOfCells},
+        {Name: "getCell", Function: obj.getCell},
+        {Name: "cells", Function: obj.cells},
+        {Name: "root", Function: obj.root},
+        {Name: "find", Function: obj.find},
+        {Name: "findAll", Function: obj.findAll},
+    }, 0)
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+    "context"
+    "io"
+    "fmt"
+    "bytes"
+    "github.com/pkg/errors"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+// DecodeMessageAt decodes only the message at the given byte offset of a random
+// access input. The start and stop signatures of the message are validated before
+// decoding. The abbreviated heading of any GTS bulletin envelope in front of the
+// message is kept as metadata. Start byte indices of the sections are relative to
+// the input.
+func DecodeMessageAt(ctx context.Context, config *Config, r io.ReaderAt, offset int64) (*bufr.Message, error) {
+    b, err := tdcfio.ReadMessageAt(r, offset)
+    if err != nil {
+        return nil, err
+    }
+
+    // The envelope is decoded along with the message so that its heading is kept
+    envelope, err := tdcfio.ReadEnvelopeAt(r, offset)
+    if err != nil {
+        return nil, err
+    }
+    ins := io.MultiReader(bytes.NewReader(envelope), bytes.NewReader(b))
+    rt, err := NewRuntime(config, tdcfio.NewPeekableBitReader(ins))
+    if err != nil {
+        return nil, err
+    }
+    message, err := rt.Run(ctx)
+    if err != nil {
+        if err == io.EOF {
+            return nil, fmt.Errorf("no message at offset %v", offset)
+        }
+        return nil, errors.Wrapf(err, "cannot decode message at offset %v", offset)
+    }
+
+    for _, section := range message.Sections() {
+        section.StartByteIndex += int(offset) - len(envelope)
+    }
+    return message, nil
+}
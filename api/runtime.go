@@ -1,29 +1,55 @@
 package api
 
 import (
+    "context"
     "github.com/ywangd/gobufrkit/tdcfio"
     "github.com/ywangd/gobufrkit/deserialize"
     "github.com/pkg/errors"
     "github.com/ywangd/gobufrkit/bufr"
 )
 
+// Default entry script for deserializing messages
+const BOOT_SCRIPT = "boot.lua"
+
+// Entry script for scanning only the header sections of messages
+const SCAN_SCRIPT = "scan.lua"
+
+// Entry script for deserializing CREX messages
+const CREX_SCRIPT = "crex.lua"
+
+// Script defining the helper functions of the interactive shell
+const SHELL_SCRIPT = "shell.lua"
+
 type Config struct {
     DefinitionsPath string
     TablesPath      string
+    // Entry script relative to the definitions path, default to BOOT_SCRIPT or
+    // CREX_SCRIPT for CREX input
+    Script string
 
     // Only binary stream provides compressed data
     // in the format described by the BUFR Spec.
     InputType  tdcfio.InputType
     Compatible bool
     Verbose    bool
+
+    // Resource limits for deserializing each message, default to deserialize.DefaultLimits
+    Limits *deserialize.Limits
+    // Run definitions scripts in a sandbox with the given limits if not nil
+    Sandbox *Sandbox
 }
 
 func (c *Config) toDeserializeConfig() *deserialize.Config {
+    limits := deserialize.DefaultLimits
+    if c.Limits != nil {
+        limits = *c.Limits
+    }
     return &deserialize.Config{
         TablesPath: c.TablesPath,
         InputType:  c.InputType,
         Compatible: c.Compatible,
         Verbose:    c.Verbose,
+        Limits:     limits,
     }
 }
 
@@ -36,7 +62,15 @@ func NewRuntime(config *Config, pr tdcfio.PeekableReader) (*Runtime, error) {
 
     factory := deserialize.NewDefaultFactory(config.toDeserializeConfig(), pr)
 
-    scriptRt := NewScriptRt(config.DefinitionsPath, factory)
+    script := config.Script
+    if script == "" {
+        script = BOOT_SCRIPT
+        if config.InputType == tdcfio.CrexInput {
+            script = CREX_SCRIPT
+        }
+    }
+    scriptRt := NewScriptRt(config.DefinitionsPath, script, factory)
+    scriptRt.sandbox = config.Sandbox
     if err := scriptRt.Initialize(); err != nil {
         return nil, errors.Wrap(err, "cannot initialise script runtime")
     }
@@ -47,6 +81,14 @@ func NewRuntime(config *Config, pr tdcfio.PeekableReader) (*Runtime, error) {
     }, nil
 }
 
-func (rt *Runtime) Run() (*bufr.Message, error) {
-    return rt.scriptRt.RunDeserializer()
+// Run deserializes the next message from the input. It returns io.EOF when
+// no more message can be found. Cancellation and deadline of the context are
+// checked while deserializing the payload, in which case a *deserialize.CancelledError
+// is returned with the progress made so far. The input is left in the middle of
+// the message and the runtime should not be run again.
+func (rt *Runtime) Run(ctx context.Context) (*bufr.Message, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    return rt.scriptRt.RunDeserializer(ctx)
 }
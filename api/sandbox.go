@@ -0,0 +1,313 @@
+package api
+
+import (
+    "fmt"
+    "os"
+    "runtime"
+    "strings"
+    "path/filepath"
+    "github.com/Shopify/go-lua"
+)
+
+// Number of Lua instructions between two checks of the sandbox limits
+const SANDBOX_HOOK_COUNT = 1000
+
+// Sandbox restricts definitions scripts contributed by third parties. Only the
+// base, package, string, table, math and bit32 libraries are available, without
+// any function that accesses files. Modules can only be required and searched for
+// from within the definitions path. A zero limit means no limit.
+type Sandbox struct {
+    // Maximum number of Lua instructions to deserialize each message
+    MaxInstructions int
+    // Maximum number of bytes the heap can grow by while deserializing each
+    // message. The limit is best-effort: the heap is sampled every SANDBOX_HOOK_COUNT
+    // instructions and once the deserializer returns, and it also counts memory taken
+    // by other goroutines. string.rep, string.format and table.concat check their
+    // results against the remaining budget before building them, but the .. operator
+    // cannot be checked and a few repeated concatenations can exhaust the memory of
+    // the process between two samples.
+    MaxMemory int
+}
+
+// DefaultSandbox has limits generous enough for the standard definitions
+var DefaultSandbox = Sandbox{
+    MaxInstructions: 10000000,
+    MaxMemory:       1 << 30,
+}
+
+// SandboxError is returned when a script running in the sandbox exceeds one of its limits
+type SandboxError struct {
+    Limit string
+    Max   int
+}
+
+func (e *SandboxError) Error() string {
+    return fmt.Sprintf("script exceeds the sandbox limit of %v %v", e.Max, e.Limit)
+}
+
+// sandboxState keeps track of the resources used by the current message
+type sandboxState struct {
+    instructions int
+    heapStart    uint64
+    violation    *SandboxError
+}
+
+// Functions of the base library that are removed in the sandbox
+var unsafeBaseFunctions = []string{"dofile", "loadfile", "load", "collectgarbage"}
+
+// openSafeLibraries opens the standard libraries that are safe for the sandbox
+// and installs the hook enforcing its limits.
+func (r *ScriptRt) openSafeLibraries() {
+    libs := []lua.RegistryFunction{
+        {Name: "_G", Function: lua.BaseOpen},
+        {Name: "package", Function: lua.PackageOpen},
+        {Name: "table", Function: lua.TableOpen},
+        {Name: "string", Function: lua.StringOpen},
+        {Name: "bit32", Function: lua.Bit32Open},
+        {Name: "math", Function: lua.MathOpen},
+    }
+    for _, lib := range libs {
+        lua.Require(r.state, lib.Name, lib.Function, true)
+        r.state.Pop(1)
+    }
+    for _, name := range unsafeBaseFunctions {
+        r.state.PushNil()
+        r.state.SetGlobal(name)
+    }
+
+    // Modules are searched only in package.preload and the definitions path
+    r.state.Global("package")
+    r.state.CreateTable(2, 0)
+    r.state.Field(-2, "searchers")
+    r.state.RawGetInt(-1, 1) // the preload searcher
+    r.state.Remove(-2)
+    r.state.RawSetInt(-2, 1)
+    r.state.PushGoFunction(r.searchDefinitions)
+    r.state.RawSetInt(-2, 2)
+    r.state.SetField(-2, "searchers")
+    r.state.PushNil()
+    r.state.SetField(-2, "loadlib")
+    r.state.PushGoFunction(r.searchPath)
+    r.state.SetField(-2, "searchpath")
+    r.state.Pop(1)
+
+    // string.rep, string.format and table.concat can allocate any amount of memory
+    // in a single instruction
+    r.state.Global("string")
+    r.state.PushGoFunction(r.stringRep)
+    r.state.SetField(-2, "rep")
+    r.state.Field(-1, "format")
+    r.state.PushGoClosure(r.stringFormat, 1)
+    r.state.SetField(-2, "format")
+    r.state.Pop(1)
+    r.state.Global("table")
+    r.state.PushGoFunction(r.tableConcat)
+    r.state.SetField(-2, "concat")
+    r.state.Pop(1)
+
+    // Scripts loaded on initialization are also subject to the limits
+    r.sandboxState = &sandboxState{}
+    r.resetSandbox()
+    lua.SetDebugHook(r.state, r.checkSandbox, lua.MaskCount, SANDBOX_HOOK_COUNT)
+}
+
+// resetSandbox starts counting resources used for a new message
+func (r *ScriptRt) resetSandbox() {
+    if r.sandboxState == nil {
+        return
+    }
+    // Garbage left by the previous message would otherwise hide the growth once collected
+    if r.sandbox.MaxMemory > 0 {
+        runtime.GC()
+    }
+    var stats runtime.MemStats
+    runtime.ReadMemStats(&stats)
+    *r.sandboxState = sandboxState{heapStart: stats.HeapAlloc}
+}
+
+// checkSandbox is the count hook that raises an error once a limit is exceeded
+func (r *ScriptRt) checkSandbox(state *lua.State, _ lua.Debug) {
+    r.sandboxState.instructions += SANDBOX_HOOK_COUNT
+    if max := r.sandbox.MaxInstructions; max > 0 && r.sandboxState.instructions > max {
+        r.violate(state, "instructions", max)
+    }
+    if max := r.sandbox.MaxMemory; max > 0 && r.heapGrowth() > int64(max) {
+        // The growth may well be garbage
+        runtime.GC()
+        if r.heapGrowth() > int64(max) {
+            r.violate(state, "bytes of memory", max)
+        }
+    }
+}
+
+// checkHeap records a violation if the heap has grown beyond the memory limit once the
+// deserializer returns. Strings built by concatenation can grow exponentially between
+// two samples of the hook, so the heap is checked before any garbage is collected.
+func (r *ScriptRt) checkHeap() {
+    if r.sandboxState == nil || r.sandboxState.violation != nil {
+        return
+    }
+    if max := r.sandbox.MaxMemory; max > 0 && r.heapGrowth() > int64(max) {
+        r.sandboxState.violation = &SandboxError{Limit: "bytes of memory", Max: max}
+    }
+}
+
+// checkAlloc raises a violation of the memory limit if allocating the given number of
+// bytes would exceed what remains of it
+func (r *ScriptRt) checkAlloc(state *lua.State, size int64) {
+    max := r.sandbox.MaxMemory
+    if max <= 0 {
+        return
+    }
+    if size > int64(max) {
+        r.violate(state, "bytes of memory", max)
+    }
+    if size > int64(max)-r.heapGrowth() {
+        runtime.GC()
+        if size > int64(max)-r.heapGrowth() {
+            r.violate(state, "bytes of memory", max)
+        }
+    }
+}
+
+func (r *ScriptRt) heapGrowth() int64 {
+    var stats runtime.MemStats
+    runtime.ReadMemStats(&stats)
+    return int64(stats.HeapAlloc) - int64(r.sandboxState.heapStart)
+}
+
+// violate records the violation so that it is not lost to a pcall in the script
+// and raises it as a Lua error.
+func (r *ScriptRt) violate(state *lua.State, limit string, max int) {
+    r.sandboxState.violation = &SandboxError{Limit: limit, Max: max}
+    lua.Errorf(state, "%v", r.sandboxState.violation)
+}
+
+// violation returns the violation of the sandbox limits for the current message if any
+func (r *ScriptRt) violation() error {
+    if r.sandboxState == nil || r.sandboxState.violation == nil {
+        return nil
+    }
+    return r.sandboxState.violation
+}
+
+// searchDefinitions is a package searcher that loads modules strictly from within
+// the definitions path
+func (r *ScriptRt) searchDefinitions(state *lua.State) int {
+    name := lua.CheckString(state, 1)
+    fileName, err := r.resolveModule(name)
+    if err != nil {
+        state.PushString("\n\t" + err.Error())
+        return 1
+    }
+    if err := lua.LoadFile(state, fileName, "t"); err != nil {
+        message, _ := state.ToString(-1)
+        lua.Errorf(state, "error loading module '%s' from file '%s':\n\t%s", name, fileName, message)
+    }
+    state.PushString(fileName)
+    return 2
+}
+
+// searchPath is package.searchpath restricted to the definitions path. The path
+// argument is ignored.
+func (r *ScriptRt) searchPath(state *lua.State) int {
+    name := lua.CheckString(state, 1)
+    fileName, err := r.resolveModule(name)
+    if err != nil {
+        state.PushNil()
+        state.PushString("\n\t" + err.Error())
+        return 2
+    }
+    state.PushString(fileName)
+    return 1
+}
+
+// resolveModule returns the file of the module after following any symbolic links
+func (r *ScriptRt) resolveModule(name string) (string, error) {
+    root, err := filepath.Abs(r.definitionsPath)
+    if err != nil {
+        return "", err
+    }
+    if root, err = filepath.EvalSymlinks(root); err != nil {
+        return "", err
+    }
+    fileName := filepath.Join(root, strings.Replace(name, ".", string(filepath.Separator), -1)+".lua")
+    if fileName, err = filepath.EvalSymlinks(fileName); err != nil {
+        return "", fmt.Errorf("no file '%v' in the definitions path", name)
+    }
+    rel, err := filepath.Rel(root, fileName)
+    if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+        return "", fmt.Errorf("module '%v' is outside of the definitions path", name)
+    }
+    if info, err := os.Stat(fileName); err != nil || info.IsDir() {
+        return "", fmt.Errorf("no file '%v' in the definitions path", name)
+    }
+    return fileName, nil
+}
+
+// stringRep is string.rep that refuses to build strings larger than the remaining memory
+func (r *ScriptRt) stringRep(state *lua.State) int {
+    s, n, sep := lua.CheckString(state, 1), lua.CheckInteger(state, 2), lua.OptString(state, 3, "")
+    if n <= 0 {
+        state.PushString("")
+        return 1
+    }
+    r.checkAlloc(state, int64(len(s)+len(sep))*int64(n))
+    state.PushString(strings.Repeat(s+sep, n-1) + s)
+    return 1
+}
+
+// stringFormat is string.format that refuses to build strings larger than the remaining
+// memory. The size of the result is bounded by the format, the lengths of the string
+// arguments, four times as many with %q escaping them, and the widest conversion of
+// any other argument. The original string.format is its upvalue.
+func (r *ScriptRt) stringFormat(state *lua.State) int {
+    format := lua.CheckString(state, 1)
+    factor := int64(1)
+    if strings.Contains(format, "%q") {
+        factor = 4
+    }
+    size := int64(len(format))
+    for i := 2; i <= state.Top(); i++ {
+        if state.TypeOf(i) == lua.TypeString {
+            str, _ := state.ToString(i)
+            size += factor*int64(len(str)) + 2
+        }
+        size += 512
+    }
+    r.checkAlloc(state, size)
+    state.PushValue(lua.UpValueIndex(1))
+    state.Insert(1)
+    state.Call(state.Top()-1, 1)
+    return 1
+}
+
+// tableConcat is table.concat that refuses to build strings larger than the remaining memory
+func (r *ScriptRt) tableConcat(state *lua.State) int {
+    lua.CheckType(state, 1, lua.TypeTable)
+    sep := lua.OptString(state, 2, "")
+    first := lua.OptInteger(state, 3, 1)
+    var last int
+    if state.IsNoneOrNil(4) {
+        last = lua.LengthEx(state, 1)
+    } else {
+        last = lua.CheckInteger(state, 4)
+    }
+
+    var strs []string
+    size := int64(0)
+    for i := first; i <= last; i++ {
+        state.RawGetInt(1, i)
+        str, ok := state.ToString(-1)
+        if !ok {
+            lua.Errorf(state, "invalid value (%s) at index %d in table for 'concat'",
+                lua.TypeNameOf(state, -1), i)
+        }
+        state.Pop(1)
+        strs = append(strs, str)
+        size += int64(len(str) + len(sep))
+    }
+    r.checkAlloc(state, size)
+    state.PushString(strings.Join(strs, sep))
+    return 1
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+    "testing"
+    "context"
+    "io"
+    "os"
+    "strings"
+    "path/filepath"
+    assert2 "github.com/seanpont/assert"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+func TestSandbox(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    // The standard definitions work in the sandbox
+    ins, err := os.Open("../_testdata/contrived.bufr")
+    assert.Nil(err)
+    defer ins.Close()
+    message, err := DecodeMessageAt(context.Background(), &Config{
+        DefinitionsPath: "../_definitions",
+        TablesPath:      "../_definitions/tables",
+        InputType:       tdcfio.BinaryInput,
+        Sandbox:         &DefaultSandbox,
+    }, ins, 0)
+    assert.Nil(err)
+    assert.NotNil(message)
+
+    dir := t.TempDir()
+    definitionsPath := filepath.Join(dir, "definitions")
+    assert.Nil(os.Mkdir(definitionsPath, 0755))
+    assert.Nil(os.WriteFile(filepath.Join(dir, "outside.lua"), []byte(`return {}`), 0644))
+    assert.Nil(os.Symlink(filepath.Join(dir, "outside.lua"), filepath.Join(definitionsPath, "link.lua")))
+    assert.Nil(os.WriteFile(filepath.Join(definitionsPath, "inside.lua"), []byte(`return 42`), 0644))
+
+    run := func(script string) error {
+        assert.Nil(os.WriteFile(filepath.Join(definitionsPath, "boot.lua"), []byte(script), 0644))
+        rt, err := NewRuntime(&Config{
+            DefinitionsPath: definitionsPath,
+            Sandbox:         &Sandbox{MaxInstructions: 1000000, MaxMemory: 10 << 20},
+        }, tdcfio.NewPeekableBitReader(strings.NewReader("")))
+        assert.Nil(err)
+        _, err = rt.Run(context.Background())
+        return err
+    }
+
+    // Only safe libraries are available
+    // A script returning nil reports no more messages
+    assert.Equal(run(`assert(require('inside') == 42); return nil`), io.EOF)
+    assert.Equal(run(`assert(io == nil and os == nil and debug == nil and dofile == nil and load == nil)`), io.EOF)
+    assert.True(strings.Contains(run(`require 'link'`).Error(), "outside of the definitions path"))
+    assert.True(strings.Contains(run(`require 'outside'`).Error(), "not found"))
+    // Modules can only be searched for within the definitions path
+    assert.Equal(run(`assert(package.searchpath('inside', '/?.lua'):sub(-10) == 'inside.lua')
+        assert(package.searchpath('outside', '../?.lua') == nil)
+        assert(package.searchpath('link', package.path) == nil)
+        return nil`), io.EOF)
+
+    // Limits are reported even if the script catches the error
+    err = run(`while true do end`)
+    assert.Equal(err, &SandboxError{Limit: "instructions", Max: 1000000})
+    err = run(`pcall(function() while true do end end); return nil`)
+    assert.Equal(err, &SandboxError{Limit: "instructions", Max: 1000000})
+    err = run(`local s = string.rep("x", 2^30)`)
+    assert.Equal(err, &SandboxError{Limit: "bytes of memory", Max: 10 << 20})
+    err = run(`local t = {} for i = 1, 1e5 do t[i] = string.rep("x", 1000) .. i end`)
+    assert.Equal(err, &SandboxError{Limit: "bytes of memory", Max: 10 << 20})
+    // Strings are checked against what remains of the limit
+    err = run(`local a = string.rep("x", 6 * 2^20) local b = string.rep("y", 6 * 2^20)`)
+    assert.Equal(err, &SandboxError{Limit: "bytes of memory", Max: 10 << 20})
+    err = run(`local s = string.rep("x", 4 * 2^20) local t = string.format("%s%s", s, s)`)
+    assert.Equal(err, &SandboxError{Limit: "bytes of memory", Max: 10 << 20})
+    err = run(`local s = string.rep("x", 3 * 2^20) local t = string.format("%q", s)`)
+    assert.Equal(err, &SandboxError{Limit: "bytes of memory", Max: 10 << 20})
+    assert.Equal(run(`assert(string.format("%5.1f %s %d", 1.25, "a", 3) == "  1.2 a 3"); return nil`), io.EOF)
+    err = run(`local t = {} for i = 1, 64 do t[i] = string.rep("x", 2^20) end local s = table.concat(t)`)
+    assert.Equal(err, &SandboxError{Limit: "bytes of memory", Max: 10 << 20})
+    assert.Equal(run(`assert(table.concat({1, "a", 2}, ",") == "1,a,2"); assert(table.concat({1, 2, 3}, "", 2) == "23"); return nil`), io.EOF)
+}
@@ -1,8 +1,10 @@
 package api
 
 import (
+    "context"
     "io"
     "os"
+    "fmt"
     "path/filepath"
     "github.com/Shopify/go-lua"
     "github.com/ywangd/gobufrkit/deserialize"
@@ -17,19 +19,28 @@ const (
 
 type ScriptRt struct {
     definitionsPath string
+    script          string
     state           *lua.State
     factory         deserialize.Factory
+    lib             *LibDeserializer
+    // Run scripts in the sandbox if not nil
+    sandbox         *Sandbox
+    sandboxState    *sandboxState
 }
 
-func NewScriptRt(definitionsPath string, factory deserialize.Factory) *ScriptRt {
+func NewScriptRt(definitionsPath, script string, factory deserialize.Factory) *ScriptRt {
     state := lua.NewState()
-    return &ScriptRt{definitionsPath: definitionsPath, state: state, factory: factory}
+    return &ScriptRt{definitionsPath: definitionsPath, script: script, state: state, factory: factory}
 }
 
 // Initialize the runtime. This will setup the bindings and perform other
 // initialization stuff.
 func (r *ScriptRt) Initialize() error {
-    lua.OpenLibraries(r.state)
+    if r.sandbox != nil {
+        r.openSafeLibraries()
+    } else {
+        lua.OpenLibraries(r.state)
+    }
     r.initConstants()
     r.initPkgPath()
     if err := r.initScripts(); err != nil {
@@ -65,17 +76,43 @@ func (r *ScriptRt) Load(reader io.Reader, chunkName, keyName string) error {
     return nil
 }
 
-// Get the decoder func from the runtime metatable and execute it
-func (r *ScriptRt) RunDeserializer() (*bufr.Message, error) {
+// Get the decoder func from the runtime metatable and execute it. Deserializing
+// the payload stops with a *deserialize.CancelledError once the context is done.
+// A *SandboxError is returned if the scripts exceed a limit of the sandbox.
+func (r *ScriptRt) RunDeserializer(ctx context.Context) (*bufr.Message, error) {
+    r.lib.ctx, r.lib.cancelled = ctx, nil
+    defer func() { r.lib.ctx = context.Background() }()
+    r.resetSandbox()
+
     lua.MetaTableNamed(r.state, RUNTIME_METATABLE)
     r.state.Field(-1, DESERIALIZER)
     r.state.Remove(-2)
-    // TODO: check Start signature (probably in lua part)
-    if err := r.state.ProtectedCall(0, 1, 0); err != nil {
+    err := r.state.ProtectedCall(0, 1, 0)
+    r.checkHeap()
+    if err != nil {
+        r.state.Pop(1) // the error object
+        if r.lib.cancelled != nil {
+            return nil, r.lib.cancelled
+        }
+        if err := r.violation(); err != nil {
+            return nil, err
+        }
         return nil, err
     }
-    message := r.state.ToUserData(-1).(*bufr.Message)
+    if err := r.violation(); err != nil {
+        r.state.Pop(1)
+        return nil, err
+    }
+    // The deserializer returns nil when no more message can be found
+    if r.state.IsNil(-1) {
+        r.state.Pop(1)
+        return nil, io.EOF
+    }
+    message, ok := r.state.ToUserData(-1).(*bufr.Message)
     r.state.Pop(1)
+    if !ok {
+        return nil, fmt.Errorf("deserializer returns no message")
+    }
     return message, nil
 }
 
@@ -105,7 +142,7 @@ func (r *ScriptRt) initPkgPath() {
 
 // Load the lua scripts for deserializer
 func (r *ScriptRt) initScripts() error {
-    scriptFileName := filepath.Join(r.definitionsPath, "boot.lua")
+    scriptFileName := filepath.Join(r.definitionsPath, r.script)
     ins, err := os.Open(scriptFileName)
     if err != nil {
         return err
@@ -118,10 +155,14 @@ func (r *ScriptRt) initTypes() {
     (&ObjMessage{}).registerGribMessageType(r.state)
     (&ObjSection{}).registerGribSectionType(r.state)
     (&ObjField{}).registerSectionFieldType(r.state)
+    (&ObjPayload{}).registerPayloadType(r.state)
+    (&ObjSubset{}).registerSubsetType(r.state)
+    (&ObjCell{}).registerCellType(r.state)
+    (&ObjNode{}).registerNodeType(r.state)
 }
 
 // initialise local libraries
 func (r *ScriptRt) initLibs() {
-    lua.Require(r.state, "factory",
-        (&LibDeserializer{factory: r.factory}).Register, true)
+    r.lib = &LibDeserializer{factory: r.factory, ctx: context.Background()}
+    lua.Require(r.state, "factory", r.lib.Register, true)
 }
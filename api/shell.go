@@ -0,0 +1,102 @@
+package api
+
+import (
+    "io"
+    "fmt"
+    "strings"
+    "path/filepath"
+    "github.com/Shopify/go-lua"
+    "github.com/pkg/errors"
+    "github.com/ywangd/gobufrkit/bufr"
+)
+
+// ErrIncomplete is returned by Shell.Eval when the code is not yet a complete
+// chunk, e.g. a function without its end. More lines should be read and the
+// code evaluated again.
+var ErrIncomplete = fmt.Errorf("incomplete chunk")
+
+// Shell is an interactive Lua session on the script runtime of a Runtime. Besides
+// the factory library and types of the definitions scripts, the state has the
+// decoded messages as the list messages, the first message as message, and the
+// helper functions of SHELL_SCRIPT preloaded.
+type Shell struct {
+    rt *ScriptRt
+    // Output of the print function
+    out io.Writer
+}
+
+// NewShell returns a Shell on the script runtime of the given Runtime. The print
+// function of the shell writes to the given writer.
+func NewShell(rt *Runtime, messages []*bufr.Message, out io.Writer) (*Shell, error) {
+    r := rt.scriptRt
+    s := &Shell{rt: r, out: out}
+    r.state.Register("print", s.print)
+
+    r.state.CreateTable(len(messages), 0)
+    for i, message := range messages {
+        pushMessage(r.state, message)
+        r.state.RawSetInt(-2, i+1)
+    }
+    r.state.SetGlobal("messages")
+    if len(messages) > 0 {
+        r.NewGlobalUserData("message", messages[0], MESSAGE_META_TABLE)
+    }
+
+    scriptFileName := filepath.Join(r.definitionsPath, SHELL_SCRIPT)
+    if err := lua.DoFile(r.state, scriptFileName); err != nil {
+        return nil, errors.Wrapf(err, "cannot load %v: %v", scriptFileName, popErrorMessage(r.state))
+    }
+    return s, nil
+}
+
+// print writes the string forms of its arguments separated by tabs, the same as
+// the print function of the Lua base library does to the standard output.
+func (s *Shell) print(state *lua.State) int {
+    strs := make([]string, state.Top())
+    for i := range strs {
+        strs[i], _ = lua.ToStringMeta(state, i+1)
+        state.Pop(1) // the string pushed by ToStringMeta
+    }
+    fmt.Fprintln(s.out, strings.Join(strs, "\t"))
+    return 0
+}
+
+// Eval evaluates a chunk of code typed in the shell. The code is first tried as
+// an expression, in which case the string forms of its values are returned, as
+// converted by their __tostring metamethods. It returns ErrIncomplete if the code
+// is not a complete chunk.
+func (s *Shell) Eval(code string) ([]string, error) {
+    state := s.rt.state
+    top := state.Top()
+    s.rt.resetSandbox()
+    if err := lua.LoadBuffer(state, "return "+code, "=stdin", ""); err != nil {
+        state.Pop(1)
+        if err := lua.LoadBuffer(state, code, "=stdin", ""); err != nil {
+            message := popErrorMessage(state)
+            if strings.HasSuffix(message, "<eof>") {
+                return nil, ErrIncomplete
+            }
+            return nil, fmt.Errorf("%v", message)
+        }
+    }
+
+    if err := state.ProtectedCall(0, lua.MultipleReturns, 0); err != nil {
+        state.SetTop(top)
+        return nil, err
+    }
+    var results []string
+    for i := top + 1; i <= state.Top(); i++ {
+        str, _ := lua.ToStringMeta(state, i)
+        state.Pop(1) // the string pushed by ToStringMeta
+        results = append(results, str)
+    }
+    state.SetTop(top)
+    return results, nil
+}
+
+// popErrorMessage pops the error message left on the stack by a failed load
+func popErrorMessage(state *lua.State) string {
+    message, _ := state.ToString(-1)
+    state.Pop(1)
+    return message
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+    "testing"
+    "context"
+    "os"
+    "bytes"
+    "strings"
+    assert2 "github.com/seanpont/assert"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+func TestShell(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    config := &Config{
+        DefinitionsPath: "../_definitions",
+        TablesPath:      "../_definitions/tables",
+        InputType:       tdcfio.BinaryInput,
+    }
+    ins, err := os.Open("../_testdata/contrived.bufr")
+    assert.Nil(err)
+    defer ins.Close()
+    message, err := DecodeMessageAt(context.Background(), config, ins, 0)
+    assert.Nil(err)
+
+    rt, err := NewRuntime(config, tdcfio.NewPeekableBitReader(ins))
+    assert.Nil(err)
+    out := new(bytes.Buffer)
+    shell, err := NewShell(rt, []*bufr.Message{message}, out)
+    assert.Nil(err)
+
+    // print writes to the output of the shell
+    results, err := shell.Eval("print(1, 'a', message ~= nil)")
+    assert.Nil(err)
+    assert.Equal(len(results), 0)
+    assert.Equal(out.String(), "1\ta\ttrue\n")
+
+    // Expressions return the string forms of their values
+    results, err = shell.Eval("1 + 1, 'a'")
+    assert.Nil(err)
+    assert.Equal(results, []string{"2", "a"})
+
+    results, err = shell.Eval("message")
+    assert.Nil(err)
+    assert.Equal(len(results), 1)
+    assert.True(strings.Contains(results[0], "edition 4"))
+
+    results, err = shell.Eval("field('nSubsets')")
+    assert.Nil(err)
+    assert.Equal(len(results), 1)
+    assert.True(strings.HasPrefix(results[0], "nSubsets = "))
+
+    // Statements return nothing and leave their effects in the state
+    results, err = shell.Eval("n = #subset(1)")
+    assert.Nil(err)
+    assert.Equal(len(results), 0)
+    results, err = shell.Eval("n > 0")
+    assert.Nil(err)
+    assert.Equal(results, []string{"true"})
+
+    _, err = shell.Eval("function f()")
+    assert.Equal(err, ErrIncomplete)
+    _, err = shell.Eval("function f() return 42 end")
+    assert.Nil(err)
+    results, err = shell.Eval("f()")
+    assert.Nil(err)
+    assert.Equal(results, []string{"42"})
+
+    _, err = shell.Eval("1 +* 2")
+    assert.NotNil(err)
+    _, err = shell.Eval("error('boom')")
+    assert.NotNil(err)
+    assert.True(strings.Contains(err.Error(), "boom"))
+}
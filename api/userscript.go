@@ -0,0 +1,133 @@
+package api
+
+import (
+    "path/filepath"
+    "github.com/Shopify/go-lua"
+    "github.com/pkg/errors"
+    "github.com/ywangd/gobufrkit/bufr"
+)
+
+// Names of the hook functions a user script can define. All hooks are optional.
+const (
+    // on_start(args) is called once before any message with a list of the input files
+    HOOK_ON_START = "on_start"
+    // on_message(message) is called for each decoded message. Returning false skips
+    // the subsets of the message.
+    HOOK_ON_MESSAGE = "on_message"
+    // on_subset(subset, message) is called for each subset of a message
+    HOOK_ON_SUBSET = "on_subset"
+    // on_end() is called once after all messages
+    HOOK_ON_END = "on_end"
+)
+
+// UserScript runs hook functions defined by a user script over decoded messages.
+// The script has access to the same types as the definitions scripts, i.e.
+// messages, sections and fields, as well as the payload, subsets, cells and nodes.
+// It can also call stop() to stop processing any further messages.
+type UserScript struct {
+    rt      *ScriptRt
+    stopped bool
+}
+
+// NewUserScript loads and runs the top level of the script file. Modules can be
+// required from the directory of the script.
+func NewUserScript(path string) (*UserScript, error) {
+    r := NewScriptRt(filepath.Dir(path), filepath.Base(path), nil)
+    s := &UserScript{rt: r}
+
+    lua.OpenLibraries(r.state)
+    r.initConstants()
+    r.initPkgPath()
+    r.initTypes()
+    r.state.Register("stop", func(state *lua.State) int {
+        s.stopped = true
+        return 0
+    })
+    if err := lua.DoFile(r.state, path); err != nil {
+        return nil, errors.Wrapf(err, "cannot load script %v", path)
+    }
+    return s, nil
+}
+
+// Stopped tells whether the script has called stop()
+func (s *UserScript) Stopped() bool {
+    return s.stopped
+}
+
+// Start calls the on_start hook with the list of input files
+func (s *UserScript) Start(inputPaths []string) error {
+    _, err := s.call(HOOK_ON_START, func(state *lua.State) int {
+        state.CreateTable(len(inputPaths), 0)
+        for i, inputPath := range inputPaths {
+            state.PushString(inputPath)
+            state.RawSetInt(-2, i+1)
+        }
+        return 1
+    })
+    return err
+}
+
+// RunMessage calls the on_message hook for the message and then the on_subset
+// hook for each of its subsets unless on_message returns false or stop() is called.
+func (s *UserScript) RunMessage(message *bufr.Message) error {
+    keep, err := s.call(HOOK_ON_MESSAGE, func(state *lua.State) int {
+        pushMessage(state, message)
+        return 1
+    })
+    if err != nil || !keep || s.stopped || !s.defined(HOOK_ON_SUBSET) {
+        return err
+    }
+
+    field, err := message.ProxyField("payload")
+    if err != nil {
+        return err
+    }
+    payload, ok := field.Value.(*bufr.Payload)
+    if !ok {
+        return nil
+    }
+    for _, subset := range payload.Subsets() {
+        _, err := s.call(HOOK_ON_SUBSET, func(state *lua.State) int {
+            pushSubset(state, subset)
+            pushMessage(state, message)
+            return 2
+        })
+        if err != nil || s.stopped {
+            return err
+        }
+    }
+    return nil
+}
+
+// End calls the on_end hook
+func (s *UserScript) End() error {
+    _, err := s.call(HOOK_ON_END, func(state *lua.State) int {
+        return 0
+    })
+    return err
+}
+
+// defined tells whether the script defines a global function of the given name
+func (s *UserScript) defined(name string) bool {
+    s.rt.state.Global(name)
+    defer s.rt.state.Pop(1)
+    return s.rt.state.IsFunction(-1)
+}
+
+// call calls the named hook, if defined, with the arguments pushed by push. It
+// returns false if the hook returns false.
+func (s *UserScript) call(name string, push func(state *lua.State) int) (bool, error) {
+    state := s.rt.state
+    state.Global(name)
+    if !state.IsFunction(-1) {
+        state.Pop(1)
+        return true, nil
+    }
+    if err := state.ProtectedCall(push(state), 1, 0); err != nil {
+        state.Pop(1) // the error object
+        return false, errors.Wrapf(err, "error in %v", name)
+    }
+    keep := !state.IsBoolean(-1) || state.ToBoolean(-1)
+    state.Pop(1)
+    return keep, nil
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+    "testing"
+    "context"
+    "os"
+    "path/filepath"
+    "github.com/Shopify/go-lua"
+    assert2 "github.com/seanpont/assert"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+func TestUserScript(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    ins, err := os.Open("../_testdata/contrived.bufr")
+    assert.Nil(err)
+    defer ins.Close()
+    message, err := DecodeMessageAt(context.Background(), &Config{
+        DefinitionsPath: "../_definitions",
+        TablesPath:      "../_definitions/tables",
+        InputType:       tdcfio.BinaryInput,
+    }, ins, 0)
+    assert.Nil(err)
+
+    path := filepath.Join(t.TempDir(), "script.lua")
+    assert.Nil(os.WriteFile(path, []byte(`
+        files, messages, stations = 0, 0, {}
+        function on_start(f) files = #f end
+        function on_message(m)
+            messages = messages + 1
+            return messages == 1
+        end
+        function on_subset(subset, m)
+            stations[#stations + 1] = subset:find(1002):value()
+            if #stations == 3 then stop() end
+        end
+    `), 0644))
+
+    script, err := NewUserScript(path)
+    assert.Nil(err)
+    assert.Nil(script.Start([]string{"a.bufr", "b.bufr"}))
+    assert.Nil(script.RunMessage(message))
+    // Subsets are skipped when on_message returns false
+    assert.Nil(script.RunMessage(message))
+    assert.False(script.Stopped())
+    assert.Nil(script.End())
+
+    state := script.rt.state
+    assert.Nil(lua.DoString(state, `assert(files == 2 and messages == 2 and #stations == 2)`))
+    assert.Nil(lua.DoString(state, `assert(stations[1] == 461 and stations[2] == 888)`))
+
+    // Subsets are no longer processed once stop() is called
+    assert.Nil(lua.DoString(state, `messages = 0`))
+    assert.Nil(script.RunMessage(message))
+    assert.True(script.Stopped())
+    assert.Nil(lua.DoString(state, `assert(#stations == 3)`))
+}
@@ -3,6 +3,7 @@ package bufr
 import (
     "fmt"
     "encoding/json"
+    "math"
 )
 
 // Cell is a pair of a Node and its value. It is used to build the flat
@@ -39,6 +40,52 @@ func (c *Cell) Value() interface{} {
     return c.v
 }
 
+// Unit returns the unit string of the cell's value, e.g. K, m/s, CODE TABLE.
+// Numeric values are always stored with scale and refval already applied, so
+// the value returned by Value is the physical value in this unit.
+func (c *Cell) Unit() string {
+    if c.n.PackingInfo == nil {
+        return ""
+    }
+    return c.n.PackingInfo.UnitString
+}
+
+// PackedValue returns the unsigned integer that represents the cell's value in
+// binary form, i.e. the value before scale and refval are applied.
+// A missing value is returned as an integer with all bits set. A numeric value which
+// does not fit in the number of bits without being taken as missing is an error.
+func (c *Cell) PackedValue() (uint, error) {
+    info := c.n.PackingInfo
+    switch v := c.v.(type) {
+    case nil:
+        return MissingValue(info.Nbits)
+    case uint:
+        return v, nil
+    case int:
+        // Sign bit followed by the absolute value
+        if v < 0 {
+            return 1<<uint(info.Nbits-1) | uint(-v), nil
+        }
+        return uint(v), nil
+    case float64:
+        x := math.Round(v*math.Pow10(info.Scale) - info.Refval)
+        if x < 0 {
+            return 0, fmt.Errorf("cell value is below reference value: %v", v)
+        }
+        // All bits set is reserved for missing value unless there is a single bit
+        missing, err := MissingValue(info.Nbits)
+        if err != nil {
+            return 0, err
+        }
+        if x > float64(missing) || IsMissing(uint(x), info.Nbits) {
+            return 0, fmt.Errorf("cell value is too large for %v bits: %v", info.Nbits, v)
+        }
+        return uint(x), nil
+    default:
+        return 0, fmt.Errorf("cell value has no packed integer form: %T", c.v)
+    }
+}
+
 func (c *Cell) MarshalJSON() ([]byte, error) {
     return json.Marshal(c.v)
 }
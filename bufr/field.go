@@ -82,3 +82,10 @@ func NewHiddenField(name string, value interface{}, nbits int) *Field {
     field.Hidden = true
     return field
 }
+
+// NewVirtualField creates a field that takes no bits, e.g. computed from other fields
+func NewVirtualField(name string, value interface{}) *Field {
+    field := NewField(name, value, 0)
+    field.Virtual = true
+    return field
+}
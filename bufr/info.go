@@ -2,9 +2,14 @@ package bufr
 
 import "github.com/ywangd/gobufrkit/table"
 
+// PackingInfo describes how a value is packed into its binary form.
 type PackingInfo struct {
     Unit   table.Unit
     Scale  int
     Refval float64
     Nbits  int
-}
\ No newline at end of file
+
+    // UnitString is the unit as described by Table B, e.g. K, m/s, CODE TABLE.
+    // It is empty for values that are not described by a Table B entry.
+    UnitString string
+}
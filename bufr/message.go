@@ -59,6 +59,40 @@ func (m *Message) ProxyField(name string) (*Field, error) {
     return nil, fmt.Errorf("no proxy field named: %s", name)
 }
 
+// TypicalDateTime formats the typical date and time of section 1 in ISO 8601, e.g.
+// 2012-11-02T00:30:00. Fields the message does not have, e.g. second of edition 3
+// and CREX, are taken as zero.
+func (m *Message) TypicalDateTime() string {
+    var parts [6]uint
+    for i, name := range []string{"year", "month", "day", "hour", "minute", "second"} {
+        if field, err := m.ProxyField(name); err == nil {
+            switch v := field.Value.(type) {
+            case uint:
+                parts[i] = v
+            case int:
+                parts[i] = uint(v)
+            case float64:
+                parts[i] = uint(v)
+            }
+        }
+    }
+    return fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:%02d",
+        FullYear(parts[0]), parts[1], parts[2], parts[3], parts[4], parts[5])
+}
+
+// FullYear returns the year of a message with the century, which edition 3 and CREX
+// edition 1 leave out. Years of century above 50 are in the 20th century.
+func FullYear(year uint) uint {
+    switch {
+    case year >= 100:
+        return year
+    case year > 50:
+        return year + 1900
+    default:
+        return year + 2000
+    }
+}
+
 func (m *Message) MarshalJSON() ([]byte, error) {
     return json.Marshal(m.sections)
 }
@@ -75,3 +109,44 @@ func NewMessage(inputPath string) *Message {
     }
     return &message
 }
+
+// Copy returns a copy of the message with its own sections and fields so that
+// field values can be changed without affecting the original message. Field
+// values themselves, e.g. the Payload, are shared until they are replaced.
+func (m *Message) Copy() *Message {
+    message := &Message{
+        metadata:    make(map[string]interface{}, len(m.metadata)),
+        proxyFields: make(map[string]*Field, len(m.proxyFields)),
+    }
+    for name, value := range m.metadata {
+        message.metadata[name] = value
+    }
+
+    copies := make(map[*Field]*Field)
+    for _, s := range m.sections {
+        section := &Section{
+            StartByteIndex: s.StartByteIndex,
+            Padding:        s.Padding,
+            number:         s.number,
+            metadata:       make(map[string]interface{}, len(s.metadata)),
+        }
+        for name, value := range s.metadata {
+            section.metadata[name] = value
+        }
+        for _, f := range s.fields {
+            field := *f
+            copies[f] = &field
+            section.fields = append(section.fields, &field)
+        }
+        message.sections = append(message.sections, section)
+    }
+
+    for name, f := range m.proxyFields {
+        if field, ok := copies[f]; ok {
+            message.proxyFields[name] = field
+        } else {
+            message.proxyFields[name] = f
+        }
+    }
+    return message
+}
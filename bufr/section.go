@@ -1,7 +1,6 @@
 package bufr
 
 import (
-    "log"
     "encoding/json"
 )
 
@@ -36,13 +35,9 @@ func (s *Section) SetMetadata(name string, value interface{}) {
     s.metadata[name] = value
 }
 
+// Metadata returns the metadata of the given name or nil if it does not exist
 func (s *Section) Metadata(name string) interface{} {
-    value, ok := s.metadata[name]
-    if !ok {
-        // TODO: return an error instead
-        log.Fatalf("No metadata of name: %s\n", name)
-    }
-    return value
+    return s.metadata[name]
 }
 
 func (s *Section) Fields() []*Field {
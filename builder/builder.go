@@ -0,0 +1,354 @@
+// Package builder constructs BUFR edition 4 messages in Go, e.g.
+//
+//   b := builder.NewBuilder("_definitions/tables")
+//   b.Set("originatingCentre", 1)
+//   b.Set("masterTableVersion", 25)
+//   b.SetTemplate(301011, 101000, 31001, 12101)
+//   b.AddSubset(2018, 1, 2, []interface{}{273.15, 274.15})
+//   message, err := b.Build()
+//
+// Subset values are given in template order. A delayed replication is given as a
+// []interface{} of its repetitions in place of the delayed replication factor. Each
+// repetition is either a single value or a []interface{} of values if the replicated
+// block has more than one value. A nil value is a missing value.
+//
+// The built message can be encoded with serialize.BinarySerializer.
+package builder
+
+import (
+    "context"
+    "fmt"
+    "math"
+    "github.com/pkg/errors"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/deserialize"
+    "github.com/ywangd/gobufrkit/deserialize/parser"
+    "github.com/ywangd/gobufrkit/deserialize/payload"
+    "github.com/ywangd/gobufrkit/serialize"
+    "github.com/ywangd/gobufrkit/table"
+    "github.com/ywangd/gobufrkit/tdcfio"
+    "github.com/ywangd/gobufrkit/transform"
+)
+
+// fieldSpec describes a header field as defined in the definitions scripts
+type fieldSpec struct {
+    name     string
+    dataType deserialize.DataType
+    nbits    int
+    proxy    bool
+    // Fields managed by the builder cannot be set by name
+    managed bool
+}
+
+type sectionSpec struct {
+    number      int
+    description string
+    fields      []fieldSpec
+}
+
+// Layout of edition 4 messages without the optional section 2. It must agree with
+// the definitions scripts, which TestSectionSpecs checks.
+var sectionSpecs = []sectionSpec{
+    {0, "Indicator Section", []fieldSpec{
+        {"startSignature", deserialize.BYTES, 32, false, true},
+        {"totalLengthInBytes", deserialize.UINT, 24, true, true},
+        {"bufrEditionNumber", deserialize.UINT, 8, true, true},
+    }},
+    {1, "Identification Section", []fieldSpec{
+        {"lengthInBytes", deserialize.UINT, 24, false, true},
+        {"masterTableNumber", deserialize.UINT, 8, true, false},
+        {"originatingCentre", deserialize.UINT, 16, true, false},
+        {"originatingSubCentre", deserialize.UINT, 16, true, false},
+        {"updateSequenceNumber", deserialize.UINT, 8, true, false},
+        {"isSection2Presents", deserialize.BOOL, 1, true, true},
+        {"flagBits", deserialize.BINARY, 7, false, true},
+        {"dataCategory", deserialize.UINT, 8, true, false},
+        {"dataI18nSubCategory", deserialize.UINT, 8, true, false},
+        {"dataLocalSubCategory", deserialize.UINT, 8, true, false},
+        {"masterTableVersion", deserialize.UINT, 8, true, false},
+        {"localTableVersion", deserialize.UINT, 8, true, false},
+        {"year", deserialize.UINT, 16, true, false},
+        {"month", deserialize.UINT, 8, true, false},
+        {"day", deserialize.UINT, 8, true, false},
+        {"hour", deserialize.UINT, 8, true, false},
+        {"minute", deserialize.UINT, 8, true, false},
+        {"second", deserialize.UINT, 8, true, false},
+    }},
+    {3, "Data Description Section", []fieldSpec{
+        {"lengthInBytes", deserialize.UINT, 24, false, true},
+        {"reservedBits", deserialize.BINARY, 8, false, true},
+        {"nSubsets", deserialize.UINT, 16, true, true},
+        {"isObservation", deserialize.BOOL, 1, true, false},
+        {"isCompressed", deserialize.BOOL, 1, true, true},
+        {"flagBits", deserialize.BINARY, 6, false, true},
+        {"unexpandedTemplate", deserialize.BINARY, 0, true, true},
+    }},
+    {4, "Data Section", []fieldSpec{
+        {"lengthInBytes", deserialize.UINT, 24, false, true},
+        {"reservedBits", deserialize.BINARY, 8, false, true},
+        {"payload", deserialize.BINARY, 0, true, true},
+    }},
+    {5, "End Section", []fieldSpec{
+        {"stopSignature", deserialize.BYTES, 32, false, true},
+    }},
+}
+
+// Builder collects header fields, template and subset values for building a message.
+type Builder struct {
+    tablesPath string
+    values     map[string]interface{}
+    template   []table.ID
+    subsets    [][]interface{}
+    compressed bool
+}
+
+// NewBuilder returns a pointer to Builder which looks up descriptors from tables
+// under the given path.
+func NewBuilder(tablesPath string) *Builder {
+    return &Builder{
+        tablesPath: tablesPath,
+        values: map[string]interface{}{
+            "startSignature":     []byte("BUFR"),
+            "bufrEditionNumber":  uint(4),
+            "isObservation":      true,
+            "stopSignature":      []byte("7777"),
+            "isSection2Presents": false,
+        },
+    }
+}
+
+// Set sets the value of a header field by its name, e.g. originatingCentre.
+// Fields derived from the content, e.g. lengths and number of subsets, cannot be set.
+func (b *Builder) Set(name string, value interface{}) error {
+    spec := lookupSpec(name)
+    if spec == nil {
+        return fmt.Errorf("unknown header field: %v", name)
+    }
+    if spec.managed {
+        return fmt.Errorf("header field cannot be set: %v", name)
+    }
+
+    switch spec.dataType {
+    case deserialize.UINT:
+        x, err := tdcfio.ValueToFloat64(value)
+        if err != nil {
+            return errors.Wrapf(err, "invalid value for %v", name)
+        }
+        if x != math.Trunc(x) {
+            return fmt.Errorf("value is not an integer for %v: %v", name, value)
+        }
+        if x < 0 || uint(x) >= 1<<uint(spec.nbits) {
+            return fmt.Errorf("value out of range for %v: %v", name, value)
+        }
+        b.values[name] = uint(x)
+    case deserialize.BOOL:
+        v, ok := value.(bool)
+        if !ok {
+            return fmt.Errorf("invalid value for %v: %v", name, value)
+        }
+        b.values[name] = v
+    default:
+        return fmt.Errorf("unsupported header field: %v", name)
+    }
+    return nil
+}
+
+// SetTemplate sets the unexpanded template as a list of descriptor IDs, e.g. 309052
+func (b *Builder) SetTemplate(ids ...table.ID) {
+    b.template = ids
+}
+
+// SetCompressed sets whether the subsets are compressed
+func (b *Builder) SetCompressed(compressed bool) {
+    b.compressed = compressed
+}
+
+// AddSubset appends a subset of values given in template order
+func (b *Builder) AddSubset(values ...interface{}) {
+    b.subsets = append(b.subsets, values)
+}
+
+// Build creates the message. The packing information of each value is derived
+// from the tables of the master table and local table versions.
+func (b *Builder) Build() (*bufr.Message, error) {
+    if len(b.template) == 0 {
+        return nil, fmt.Errorf("template is not set")
+    }
+    if len(b.subsets) == 0 {
+        return nil, fmt.Errorf("no subset is added")
+    }
+
+    tableGroup := table.NewChainingTableGroup(b.tablesPath)
+    if err := tableGroup.AddLocalAndWmoTableGroups(
+        b.intValue("masterTableNumber"), b.intValue("originatingCentre"),
+        b.intValue("originatingSubCentre"), b.intValue("masterTableVersion"),
+        b.intValue("localTableVersion")); err != nil {
+        return nil, err
+    }
+
+    ut := table.NewUnexpandedTemplate(b.template, 2, 6, 8)
+    tree, err := parser.NewParser(tableGroup).Parse(ut)
+    if err != nil {
+        return nil, errors.Wrap(err, "cannot parse template")
+    }
+
+    pay := bufr.NewPayload(false)
+    config := &payload.DesVisitorConfig{InputType: tdcfio.ValueInput}
+    for i, values := range b.subsets {
+        r := tdcfio.NewValueReader(flatten(values))
+        desvis, err := payload.NewDeserializeVisitor(context.Background(), config, r, 1)
+        if err != nil {
+            return nil, err
+        }
+        if err := tree.Accept(desvis); err != nil {
+            return nil, errors.Wrapf(err, "cannot build subset %v", i+1)
+        }
+        if r.Remaining() > 0 {
+            return nil, fmt.Errorf("too many values for subset %v: %v left", i+1, r.Remaining())
+        }
+        if err := desvis.Produce(pay); err != nil {
+            return nil, err
+        }
+    }
+
+    message := b.assemble(ut, pay)
+    if b.compressed {
+        if message, err = transform.Recompress(message, true); err != nil {
+            return nil, err
+        }
+    }
+    if err := serialize.UpdateLengths(message); err != nil {
+        return nil, err
+    }
+    return message, nil
+}
+
+// Convert creates an edition 4 message of the header fields, template and subsets
+// of the given message, e.g. one decoded from CREX. Header fields that can be set
+// are copied as they are if the message has them. The subsets are uncompressed.
+func Convert(message *bufr.Message) (*bufr.Message, error) {
+    field, err := message.ProxyField("unexpandedTemplate")
+    if err != nil {
+        return nil, err
+    }
+    ut, ok := field.Value.(*table.UnexpandedTemplate)
+    if !ok {
+        return nil, fmt.Errorf("invalid unexpanded template: %T", field.Value)
+    }
+    if field, err = message.ProxyField("payload"); err != nil {
+        return nil, err
+    }
+    payload, ok := field.Value.(*bufr.Payload)
+    if !ok {
+        return nil, fmt.Errorf("invalid payload: %T", field.Value)
+    }
+
+    b := NewBuilder("")
+    for _, ss := range sectionSpecs {
+        for _, spec := range ss.fields {
+            if spec.managed {
+                continue
+            }
+            if field, err := message.ProxyField(spec.name); err == nil {
+                if err := b.Set(spec.name, field.Value); err != nil {
+                    return nil, err
+                }
+            }
+        }
+    }
+
+    pay := bufr.NewPayload(false)
+    for _, subset := range payload.Subsets() {
+        pay.AddSubset(bufr.NewSubset(subset.Cells(), subset.Root()))
+    }
+    m := b.assemble(table.NewUnexpandedTemplate(ut.Ids(), 2, 6, 8), pay)
+    if err := serialize.UpdateLengths(m); err != nil {
+        return nil, err
+    }
+    return m, nil
+}
+
+// assemble creates the message of the header fields, template and payload
+func (b *Builder) assemble(ut *table.UnexpandedTemplate, pay *bufr.Payload) *bufr.Message {
+    values := map[string]interface{}{
+        "nSubsets":           uint(len(pay.Subsets())),
+        "isCompressed":       false,
+        "unexpandedTemplate": ut,
+        "payload":            pay,
+    }
+    for name, value := range b.values {
+        values[name] = value
+    }
+
+    message := bufr.NewMessage("")
+    for _, ss := range sectionSpecs {
+        section := message.NewSection(ss.number, ss.description)
+        for _, spec := range ss.fields {
+            value, ok := values[spec.name]
+            if !ok {
+                value = zeroValue(spec)
+            }
+            nbits := spec.nbits
+            if spec.name == "unexpandedTemplate" {
+                nbits = len(ut.Ids()) * (ut.Fbits() + ut.Xbits() + ut.Ybits())
+            }
+            field := bufr.NewField(spec.name, value, nbits)
+            section.AddField(field)
+            if spec.proxy {
+                message.SetProxyField(field)
+            }
+        }
+    }
+    return message
+}
+
+func (b *Builder) intValue(name string) int {
+    v, _ := b.values[name].(uint)
+    return int(v)
+}
+
+func lookupSpec(name string) *fieldSpec {
+    for _, ss := range sectionSpecs {
+        for i := range ss.fields {
+            if ss.fields[i].name == name {
+                return &ss.fields[i]
+            }
+        }
+    }
+    return nil
+}
+
+func zeroValue(spec fieldSpec) interface{} {
+    switch spec.dataType {
+    case deserialize.UINT:
+        return uint(0)
+    case deserialize.BOOL:
+        return false
+    case deserialize.BINARY:
+        binary, _ := tdcfio.NewBinary(make([]byte, (spec.nbits+tdcfio.NBITS_PER_BYTE-1)/tdcfio.NBITS_PER_BYTE), spec.nbits)
+        return binary
+    }
+    return nil
+}
+
+// flatten replaces delayed replications, i.e. nested lists, with the number of
+// repetitions followed by the values of each repetition.
+func flatten(values []interface{}) []interface{} {
+    var flat []interface{}
+    for _, v := range values {
+        repetitions, ok := v.([]interface{})
+        if !ok {
+            flat = append(flat, v)
+            continue
+        }
+        flat = append(flat, uint(len(repetitions)))
+        for _, repetition := range repetitions {
+            if block, ok := repetition.([]interface{}); ok {
+                flat = append(flat, flatten(block)...)
+            } else {
+                flat = append(flat, repetition)
+            }
+        }
+    }
+    return flat
+}
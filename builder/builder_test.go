@@ -0,0 +1,186 @@
+package builder
+
+import (
+    "testing"
+    "context"
+    "bytes"
+    assert2 "github.com/seanpont/assert"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/deserialize"
+    "github.com/ywangd/gobufrkit/serialize"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+func newTestBuilder(t *testing.T) *Builder {
+    assert := assert2.Assert(t)
+    b := NewBuilder("../_definitions/tables")
+    assert.Nil(b.Set("originatingCentre", 1))
+    assert.Nil(b.Set("masterTableVersion", 25))
+    assert.Nil(b.Set("dataCategory", 2))
+    assert.Nil(b.Set("year", 2018))
+    // year, month, day, delayed replication of temperature
+    b.SetTemplate(301011, 101000, 31001, 12101)
+    b.AddSubset(2018, 1, 2, []interface{}{273.15, nil, 274.15})
+    b.AddSubset(2018, 1, 3, []interface{}{280.0, 281.5, 282.25})
+    return b
+}
+
+func TestBuilder_Build(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    for _, compressed := range []bool{false, true} {
+        b := newTestBuilder(t)
+        b.SetCompressed(compressed)
+        message, err := b.Build()
+        assert.Nil(err)
+
+        buf := new(bytes.Buffer)
+        assert.Nil(serialize.NewBinarySerializer(buf).Serialize(message))
+        field, err := message.ProxyField("totalLengthInBytes")
+        assert.Nil(err)
+        assert.Equal(field.Value, uint(buf.Len()))
+
+        decoded, err := api.DecodeMessageAt(context.Background(), &api.Config{
+            DefinitionsPath: "../_definitions",
+            TablesPath:      "../_definitions/tables",
+            InputType:       tdcfio.BinaryInput,
+        }, bytes.NewReader(buf.Bytes()), 0)
+        assert.Nil(err)
+
+        field, err = decoded.ProxyField("isCompressed")
+        assert.Nil(err)
+        assert.Equal(field.Value, compressed)
+        field, err = decoded.ProxyField("payload")
+        assert.Nil(err)
+        subsets := field.Value.(*bufr.Payload).Subsets()
+        assert.Equal(len(subsets), 2)
+        cells := subsets[0].Cells()
+        assert.Equal(len(cells), 7)
+        assert.Equal(cells[2].Value(), float64(2))
+        assert.Equal(cells[3].Value(), float64(3))
+        assert.Equal(cells[4].Value(), 273.15)
+        assert.Nil(cells[5].Value())
+        assert.Equal(subsets[1].Cell(6).Value(), 282.25)
+    }
+}
+
+// TestSectionSpecs checks the section layout of the builder against the one defined
+// by the definitions scripts, by decoding a built message with them.
+func TestSectionSpecs(t *testing.T) {
+    message, err := newTestBuilder(t).Build()
+    if err != nil {
+        t.Fatal(err)
+    }
+    buf := new(bytes.Buffer)
+    if err := serialize.NewBinarySerializer(buf).Serialize(message); err != nil {
+        t.Fatal(err)
+    }
+    decoded, err := api.DecodeMessageAt(context.Background(), &api.Config{
+        DefinitionsPath: "../_definitions",
+        TablesPath:      "../_definitions/tables",
+        InputType:       tdcfio.BinaryInput,
+    }, bytes.NewReader(buf.Bytes()), 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    sections := decoded.Sections()
+    if len(sections) != len(sectionSpecs) {
+        t.Fatalf("definitions have %v sections, specs have %v", len(sections), len(sectionSpecs))
+    }
+    for i, ss := range sectionSpecs {
+        section := sections[i]
+        if section.Number() != ss.number || section.Metadata("description") != ss.description {
+            t.Errorf("section %v %q is defined as %v %q", ss.number, ss.description,
+                section.Number(), section.Metadata("description"))
+        }
+        var fields []*bufr.Field
+        for _, field := range section.Fields() {
+            if !field.Virtual && !field.Hidden {
+                fields = append(fields, field)
+            }
+        }
+        if len(fields) != len(ss.fields) {
+            t.Errorf("section %v has %v fields defined, specs have %v", ss.number, len(fields), len(ss.fields))
+            continue
+        }
+        for j, spec := range ss.fields {
+            field := fields[j]
+            if field.Name != spec.name {
+                t.Errorf("section %v field %v is defined as %v, not %v", ss.number, j, field.Name, spec.name)
+                continue
+            }
+            // Template and payload have no fixed number of bits
+            if spec.nbits > 0 && (field.Nbits != spec.nbits || !hasDataType(field.Value, spec.dataType)) {
+                t.Errorf("section %v field %v is defined as %T of %v bits", ss.number, spec.name, field.Value, field.Nbits)
+            }
+            if _, err := decoded.ProxyField(spec.name); (err == nil) != spec.proxy {
+                t.Errorf("section %v field %v is defined with proxy %v", ss.number, spec.name, err == nil)
+            }
+        }
+    }
+}
+
+// hasDataType checks whether a decoded value is of the given data type
+func hasDataType(value interface{}, dataType deserialize.DataType) bool {
+    switch value.(type) {
+    case uint:
+        return dataType == deserialize.UINT
+    case bool:
+        return dataType == deserialize.BOOL
+    case []byte:
+        return dataType == deserialize.BYTES
+    case *tdcfio.Binary:
+        return dataType == deserialize.BINARY
+    }
+    return false
+}
+
+func TestBuilder_Errors(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    b := NewBuilder("../_definitions/tables")
+    assert.NotNil(b.Set("noSuchField", 1))
+    assert.NotNil(b.Set("nSubsets", 1))
+    assert.NotNil(b.Set("month", 256))
+    assert.NotNil(b.Set("month", 1.5))
+    assert.Nil(b.Set("month", 2.0))
+
+    b = newTestBuilder(t)
+    b.AddSubset(2018, 1, 4, []interface{}{280.0}, 1)
+    _, err := b.Build()
+    assert.NotNil(err)
+
+    // Different delayed replication factors cannot be compressed
+    b = newTestBuilder(t)
+    b.AddSubset(2018, 1, 4, []interface{}{280.0})
+    b.SetCompressed(true)
+    _, err = b.Build()
+    assert.NotNil(err)
+}
+
+func TestBuilder_ValueTooLarge(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    // 012101 has 16 bits of scale 2, i.e. up to 655.34 as 655.35 is missing
+    for _, compressed := range []bool{false, true} {
+        for _, temperature := range []float64{5000.0, 655.35} {
+            b := newTestBuilder(t)
+            b.AddSubset(2018, 1, 4, []interface{}{280.0, 281.5, temperature})
+            b.SetCompressed(compressed)
+            message, err := b.Build()
+            if err == nil {
+                err = serialize.NewBinarySerializer(new(bytes.Buffer)).Serialize(message)
+            }
+            assert.NotNil(err)
+        }
+
+        b := newTestBuilder(t)
+        b.AddSubset(2018, 1, 4, []interface{}{280.0, 281.5, 655.34})
+        b.SetCompressed(compressed)
+        message, err := b.Build()
+        assert.Nil(err)
+        assert.Nil(serialize.NewBinarySerializer(new(bytes.Buffer)).Serialize(message))
+    }
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+    "os"
+    "io"
+    "log"
+    "github.com/spf13/cobra"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/serialize"
+)
+
+// crexCmd represents the crex command
+var crexCmd = &cobra.Command{
+    Use:   "crex [filename]",
+    Short: "Convert messages from a BUFR file or STDIN if no file is given to CREX.",
+    Long: `Convert messages from a BUFR file or STDIN if no file is given to CREX.
+
+Messages are written as CREX edition 2 messages, with values converted to the
+units, scales and widths of the CREX columns of Table B. Messages containing
+values that cannot be represented in CREX, e.g. operator descriptors changing
+widths of character values, may fail to convert. CREX messages can be decoded
+with "decode --crex" and converted back to BUFR with "encode --crex".`,
+    Args: cobra.MaximumNArgs(1),
+    Run:  runCrex,
+}
+
+func init() {
+    RootCmd.AddCommand(crexCmd)
+    crexCmd.Flags().StringP("output", "o", "", "Output file (default is STDOUT)")
+}
+
+func runCrex(cmd *cobra.Command, args []string) {
+    var out io.Writer = os.Stdout
+    if outputPath := cmd.Flag("output").Value.String(); outputPath != "" {
+        f, err := os.Create(outputPath)
+        if err != nil {
+            log.Fatal(err.Error())
+        }
+        defer f.Close()
+        out = f
+    }
+
+    serializer := serialize.NewCrexSerializer(out)
+    err := forEachMessage(newRuntimeConfig(cmd), args, func(message *bufr.Message) error {
+        return serializer.Serialize(message)
+    })
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+}
@@ -3,12 +3,15 @@ package cmd
 import (
     "os"
     "log"
+    "fmt"
+    "github.com/ywangd/gobufrkit/api"
     "github.com/spf13/cobra"
     "github.com/spf13/viper"
-    "github.com/ywangd/gobufrkit/api"
-    "github.com/ywangd/gobufrkit/tdcfio"
+    "github.com/ywangd/gobufrkit/bufr"
     "path/filepath"
     "github.com/ywangd/gobufrkit/serialize"
+    "github.com/ywangd/gobufrkit/units"
+    "github.com/ywangd/gobufrkit/tdcfio"
 )
 
 // decodeCmd represents the decode command
@@ -24,9 +27,16 @@ var decodeCmd = &cobra.Command{
 func init() {
     RootCmd.AddCommand(decodeCmd)
     decodeCmd.Flags().BoolP("first-message", "1", false, "Decode only the first message")
-    decodeCmd.Flags().BoolP("attributed", "a", false, "Output attributed hierarchical structure")
+    decodeCmd.Flags().BoolP("attributed", "a", false, "Output as JSON with attributes, e.g. units, of each value")
     decodeCmd.Flags().BoolP("json", "j", false, "Output as bare JSON format")
     decodeCmd.Flags().BoolP("show-hidden-fields", "x", false, "Show hidden fields, e.g. padding")
+    decodeCmd.Flags().BoolP("show-packing", "p", false, "Show packed integers and packing parameters of values")
+    decodeCmd.Flags().Bool("csv", false, "Output data values as CSV")
+    decodeCmd.Flags().String("units", "", "Convert values to the given unit system, e.g. si, aviation, operational")
+    decodeCmd.Flags().Int64Slice("offset", nil, "Decode only messages at the given byte offsets")
+    decodeCmd.Flags().StringP("message", "m", "", "Decode only the given messages, e.g. 5 or 1,3-7,10-")
+    decodeCmd.Flags().String("index", "", "Index file created by scan for locating messages")
+    decodeCmd.Flags().Bool("crex", false, "Read CREX messages instead of BUFR")
 }
 
 func runDecode(cmd *cobra.Command, args []string) {
@@ -34,52 +44,100 @@ func runDecode(cmd *cobra.Command, args []string) {
     // Command line argument processing
     firstMessage := cmd.Flag("first-message").Changed
 
-    // Open the input BUFR file
-    var (
-        ins *os.File
-        err error
-    )
-    if len(args) > 0 {
-        ins, err = os.Open(args[0])
-        if err != nil {
-            log.Fatal(err.Error())
-        }
-        defer ins.Close()
-    } else {
-        ins = os.Stdin
+    serializer, err := newSerializer(cmd, viper.GetString("definitions_path"))
+    if err != nil {
+        log.Fatal(err.Error())
     }
 
-    pr := tdcfio.NewPeekableBitReader(ins)
-    definitionsPath := viper.GetString("definitions_path")
-    tablesPath := filepath.Join(definitionsPath, "tables")
+    handler := func(message *bufr.Message) error {
+        if err := serializer.Serialize(message); err != nil {
+            return err
+        }
+        if firstMessage {
+            return errStopMessages
+        }
+        return nil
+    }
 
-    config := &api.Config{
-        DefinitionsPath: definitionsPath,
-        TablesPath:      tablesPath,
-        InputType:       tdcfio.BinaryInput,
-        Compatible:      cmd.Flag("compatible").Changed,
-        Verbose:         cmd.Flag("debug").Changed,
+    // The index is only used to locate messages selected by number
+    if cmd.Flag("index").Changed && !cmd.Flag("message").Changed {
+        log.Fatal("--index can only be used with --message")
     }
 
-    rt, err := api.NewRuntime(config, pr)
+    config := newRuntimeConfig(cmd)
+    if cmd.Flag("crex").Changed {
+        config.InputType = tdcfio.CrexInput
+    }
+    if cmd.Flag("offset").Changed || cmd.Flag("message").Changed {
+        err = decodeSelected(cmd, config, args, handler)
+    } else {
+        err = forEachMessage(config, args, handler)
+    }
     if err != nil {
         log.Fatal(err.Error())
     }
+}
 
-    flatText := serialize.NewFlatTextSerializer(os.Stdout)
+// decodeSelected jumps directly to and decodes only the messages selected by
+// byte offsets or message numbers
+func decodeSelected(cmd *cobra.Command, config *api.Config, args []string,
+    handler func(*bufr.Message) error) error {
 
-    for i := 0; ; i++ {
-        message, err := rt.Run()
+    if len(args) == 0 {
+        return fmt.Errorf("selecting messages requires an input file")
+    }
+    ins, err := os.Open(args[0])
+    if err != nil {
+        return err
+    }
+    defer ins.Close()
+    // Offsets of messages are only meaningful in plain files
+    if ok, err := tdcfio.IsRandomAccessible(ins); err != nil {
+        return err
+    } else if !ok {
+        return fmt.Errorf("cannot select messages of compressed or archived input: %v", args[0])
+    }
+
+    var locations []messageLocation
+    if cmd.Flag("offset").Changed {
+        offsets, _ := cmd.Flags().GetInt64Slice("offset")
+        for i, offset := range offsets {
+            locations = append(locations, messageLocation{i + 1, offset})
+        }
+    } else {
+        ranges, err := parseMessageRanges(cmd.Flag("message").Value.String())
         if err != nil {
-            log.Fatal("Lua error:", err.Error())
+            return err
         }
-        message.SetMetadata("number", i+1)
-
-        flatText.Serialize(message)
+        locations, err = locateMessages(ins, args[0], ranges, cmd.Flag("index").Value.String())
+        if err != nil {
+            return err
+        }
+    }
+    return forEachMessageAt(config, args[0], ins, locations, handler)
+}
 
-        break // TODO: debug
-        if firstMessage {
-            break
+// newSerializer creates the serializer for output format selected by command line flags
+func newSerializer(cmd *cobra.Command, definitionsPath string) (serialize.Serializer, error) {
+    config := &serialize.Config{
+        ShowHidden:  cmd.Flag("show-hidden-fields").Changed,
+        ShowPacking: cmd.Flag("show-packing").Changed,
+    }
+    if name := cmd.Flag("units").Value.String(); name != "" {
+        system, err := units.LoadSystem(filepath.Join(definitionsPath, "units.csv"), name)
+        if err != nil {
+            return nil, err
         }
+        config.Units = system
+    }
+    switch {
+    case cmd.Flag("attributed").Changed:
+        return serialize.NewJsonSerializer(os.Stdout, config), nil
+    case cmd.Flag("json").Changed:
+        return serialize.NewFlatJsonSerializer(os.Stdout, config), nil
+    case cmd.Flag("csv").Changed:
+        return serialize.NewCsvSerializer(os.Stdout, config), nil
+    default:
+        return serialize.NewFlatTextSerializer(os.Stdout, config), nil
     }
 }
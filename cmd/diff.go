@@ -0,0 +1,68 @@
+package cmd
+
+import (
+    "os"
+    "log"
+    "fmt"
+    "encoding/json"
+    "github.com/spf13/cobra"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/diff"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+    Use:   "diff file1 file2",
+    Short: "Compare messages of two BUFR files semantically.",
+    Long: `Compare messages of two BUFR files semantically.
+
+Messages are aligned by their order. Header fields are aligned by section and
+name. Subset values are aligned by their descriptor path, so that differences
+in templates, replication counts or attributes are reported as structural
+differences instead of shifting all subsequent values.
+
+The exit status is 0 if the inputs are the same, 1 if they differ and 2 if
+there is any error.`,
+    Args: cobra.ExactArgs(2),
+    Run:  runDiff,
+}
+
+func init() {
+    RootCmd.AddCommand(diffCmd)
+    diffCmd.Flags().BoolP("json", "j", false, "Output as JSON lines")
+    diffCmd.Flags().Float64P("tolerance", "t", 0, "Maximum absolute difference for numbers to be considered equal")
+}
+
+func runDiff(cmd *cobra.Command, args []string) {
+    tolerance, _ := cmd.Flags().GetFloat64("tolerance")
+    asJson := cmd.Flag("json").Changed
+
+    config := newRuntimeConfig(cmd)
+    var inputs [2][]*bufr.Message
+    for i, arg := range args {
+        err := forEachMessage(config, []string{arg}, func(message *bufr.Message) error {
+            inputs[i] = append(inputs[i], message)
+            return nil
+        })
+        if err != nil {
+            log.Println(err.Error())
+            os.Exit(2)
+        }
+    }
+
+    diffs := diff.CompareAll(inputs[0], inputs[1], &diff.Options{Tolerance: tolerance})
+    enc := json.NewEncoder(os.Stdout)
+    for _, d := range diffs {
+        if asJson {
+            if err := enc.Encode(d); err != nil {
+                log.Println(err.Error())
+                os.Exit(2)
+            }
+        } else {
+            fmt.Println(d)
+        }
+    }
+    if len(diffs) > 0 {
+        os.Exit(1)
+    }
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+    "os"
+    "log"
+    "github.com/spf13/cobra"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/serialize"
+)
+
+// dumpCmd represents the dump command
+var dumpCmd = &cobra.Command{
+    Use:   "dump [filename]",
+    Short: "Dump the bit layout of messages from a BUFR file or STDIN if no file is given.",
+    Long: `Dump the bit layout of messages from a BUFR file or STDIN if no file is given.
+
+Every header field and payload value is listed with its absolute bit offset
+in the message, its width, the raw integer of its bits, the decoded value and
+the field name or descriptor. With --bits, the raw bits are shown as well.
+
+Values of compressed subsets are listed descriptor by descriptor as they are
+packed, i.e. the minimum value, the width of increments (nbitsDiff) and the
+increment of each subset.`,
+    Args: cobra.MaximumNArgs(1),
+    Run:  runDump,
+}
+
+func init() {
+    RootCmd.AddCommand(dumpCmd)
+    dumpCmd.Flags().BoolP("bits", "b", false, "Show the raw bits of each field and value")
+    dumpCmd.Flags().BoolP("first-message", "1", false, "Dump only the first message")
+}
+
+func runDump(cmd *cobra.Command, args []string) {
+    firstMessage := cmd.Flag("first-message").Changed
+
+    dumper := serialize.NewBitDumper(os.Stdout)
+    dumper.ShowBits = cmd.Flag("bits").Changed
+    err := forEachRawMessage(newRuntimeConfig(cmd), args, func(message *bufr.Message, b []byte) error {
+        if err := dumper.Dump(message, b); err != nil {
+            return err
+        }
+        if firstMessage {
+            return errStopMessages
+        }
+        return nil
+    })
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+}
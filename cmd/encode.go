@@ -0,0 +1,155 @@
+package cmd
+
+import (
+    "os"
+    "io"
+    "log"
+    "bytes"
+    "fmt"
+    "github.com/spf13/cobra"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/builder"
+    "github.com/ywangd/gobufrkit/serialize"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+// encodeCmd represents the encode command
+var encodeCmd = &cobra.Command{
+    Use:   "encode [filename]",
+    Short: "Encode messages from a BUFR file or STDIN if no file is given.",
+    Long: `Encode messages from a BUFR file or STDIN if no file is given.
+
+The messages can be framed as WMO GTS bulletins, e.g. for dissemination via
+a message switch. The abbreviated heading is taken from the --heading flag
+or, if not given, from the bulletin envelope of the input message.
+
+With --text, messages are read from text dumps written by the decode command,
+e.g. to encode a message after editing its values:
+
+  gobufrkit decode --show-hidden-fields input.bufr > input.txt
+  gobufrkit encode --text input.txt -o output.bufr
+
+With --crex, CREX messages are converted to BUFR edition 4 messages.
+
+Values must not be converted to other units. Lengths of sections are updated
+on encoding, but the unexpanded template and replication factors must agree
+with the cells.`,
+    Aliases: []string{"e"},
+    Args:    cobra.MaximumNArgs(1),
+    Run:     runEncode,
+}
+
+func init() {
+    RootCmd.AddCommand(encodeCmd)
+    encodeCmd.Flags().StringP("output", "o", "", "Output file (default is STDOUT)")
+    encodeCmd.Flags().BoolP("gts", "g", false, "Frame each message as a GTS bulletin")
+    encodeCmd.Flags().String("heading", "", "Abbreviated heading of bulletins, i.e. \"TTAAii CCCC YYGGgg [BBB]\"")
+    encodeCmd.Flags().String("ftp-format", "", "Prefix bulletins for FTP transfer using format 00 or 01")
+    encodeCmd.Flags().Int("csn", 0, "Channel sequence number of the first bulletin")
+    encodeCmd.Flags().BoolP("text", "t", false, "Read messages from text dumps written by the decode command")
+    encodeCmd.Flags().Bool("crex", false, "Read CREX messages and convert them to BUFR")
+}
+
+func runEncode(cmd *cobra.Command, args []string) {
+    var out io.Writer = os.Stdout
+    if outputPath := cmd.Flag("output").Value.String(); outputPath != "" {
+        f, err := os.Create(outputPath)
+        if err != nil {
+            log.Fatal(err.Error())
+        }
+        defer f.Close()
+        out = f
+    }
+
+    var (
+        heading *tdcfio.Heading
+        err     error
+    )
+    if s := cmd.Flag("heading").Value.String(); s != "" {
+        heading, err = tdcfio.ParseHeading(s)
+        if err != nil {
+            log.Fatal(err.Error())
+        }
+    }
+
+    var bw *tdcfio.BulletinWriter
+    if cmd.Flag("gts").Changed || cmd.Flag("ftp-format").Changed {
+        format, err := bulletinFormat(cmd.Flag("ftp-format").Value.String())
+        if err != nil {
+            log.Fatal(err.Error())
+        }
+        csn, _ := cmd.Flags().GetInt("csn")
+        if bw, err = tdcfio.NewBulletinWriter(out, format, csn); err != nil {
+            log.Fatal(err.Error())
+        }
+    }
+
+    config := newRuntimeConfig(cmd)
+    if cmd.Flag("text").Changed {
+        config.InputType = tdcfio.FlatTextInput
+    }
+    crex := cmd.Flag("crex").Changed
+    if crex {
+        config.InputType = tdcfio.CrexInput
+    }
+    err = forEachMessage(config, args, func(message *bufr.Message) error {
+        if crex {
+            m, err := builder.Convert(message)
+            if err != nil {
+                return err
+            }
+            message = m
+        }
+        if bw == nil {
+            return serialize.NewBinarySerializer(out).Serialize(message)
+        }
+
+        buf := new(bytes.Buffer)
+        if err := serialize.NewBinarySerializer(buf).Serialize(message); err != nil {
+            return err
+        }
+        h := heading
+        if h == nil {
+            if h = headingFromMetadata(message); h == nil {
+                return fmt.Errorf("no abbreviated heading for message %v", message.Metadata("number"))
+            }
+        }
+        return bw.WriteBulletin(h, buf.Bytes())
+    })
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+}
+
+// bulletinFormat converts the FTP format identifier to bulletin format
+func bulletinFormat(ftpFormat string) (tdcfio.BulletinFormat, error) {
+    switch ftpFormat {
+    case "":
+        return tdcfio.PlainBulletin, nil
+    case "00":
+        return tdcfio.FtpFormat00, nil
+    case "01":
+        return tdcfio.FtpFormat01, nil
+    default:
+        return 0, fmt.Errorf("unsupported FTP format: %v", ftpFormat)
+    }
+}
+
+// headingFromMetadata returns the abbreviated heading recorded when the message
+// was decoded from a GTS bulletin, or nil if there is none.
+func headingFromMetadata(message *bufr.Message) *tdcfio.Heading {
+    heading := &tdcfio.Heading{}
+    var ok bool
+    if heading.TTAAii, ok = message.Metadata("gtsTTAAii").(string); !ok {
+        return nil
+    }
+    if heading.CCCC, ok = message.Metadata("gtsCCCC").(string); !ok {
+        return nil
+    }
+    if heading.YYGGgg, ok = message.Metadata("gtsYYGGgg").(string); !ok {
+        return nil
+    }
+    // The BBB indicator is optional
+    heading.BBB, _ = message.Metadata("gtsBBB").(string)
+    return heading
+}
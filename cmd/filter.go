@@ -0,0 +1,85 @@
+package cmd
+
+import (
+    "os"
+    "io"
+    "log"
+    "github.com/spf13/cobra"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/filter"
+)
+
+// filterCmd represents the filter command
+var filterCmd = &cobra.Command{
+    Use:   "filter expression [filenames]",
+    Short: "Copy messages matching the expression from BUFR files or STDIN if no file is given.",
+    Long: `Copy messages matching the expression from BUFR files or STDIN if no file is given.
+
+The expression is evaluated against the header fields, i.e. proxy fields of
+sections 0 to 3, and typicalDateTime or typicalDate, e.g.
+
+  filter 'dataCategory==0 && originatingCentre in (98,7)' mixed.bufr
+  filter 'typicalDate >= "2012-11-01" && nSubsets>100' mixed.bufr
+
+Matching messages are copied byte for byte without re-encoding.`,
+    Aliases: []string{"f"},
+    Args:    cobra.MinimumNArgs(1),
+    Run:     runFilter,
+}
+
+func init() {
+    RootCmd.AddCommand(filterCmd)
+    filterCmd.Flags().StringP("output", "o", "", "Output file (default is STDOUT)")
+    filterCmd.Flags().BoolP("invert-match", "v", false, "Copy messages NOT matching the expression")
+}
+
+func runFilter(cmd *cobra.Command, args []string) {
+    expr, err := filter.Parse(args[0])
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+    invert := cmd.Flag("invert-match").Changed
+
+    var out io.Writer = os.Stdout
+    if outputPath := cmd.Flag("output").Value.String(); outputPath != "" {
+        f, err := os.Create(outputPath)
+        if err != nil {
+            log.Fatal(err.Error())
+        }
+        defer f.Close()
+        out = f
+    }
+
+    // Only header sections are needed for evaluating the expression
+    config := newRuntimeConfig(cmd)
+    config.Script = api.SCAN_SCRIPT
+
+    inputs := [][]string{nil}
+    if len(args) > 1 {
+        inputs = inputs[:0]
+        for _, arg := range args[1:] {
+            inputs = append(inputs, []string{arg})
+        }
+    }
+    for _, input := range inputs {
+        if err := filterMessages(config, input, expr, invert, out); err != nil {
+            log.Fatal(err.Error())
+        }
+    }
+}
+
+// filterMessages copies the raw bytes of the messages of the input that match, or
+// do not match if invert is true, the expression to the writer.
+func filterMessages(config *api.Config, args []string, expr filter.Expr, invert bool, out io.Writer) error {
+    return forEachRawMessage(config, args, func(message *bufr.Message, raw []byte) error {
+        matched, err := filter.Match(expr, message)
+        if err != nil {
+            return err
+        }
+        if matched != invert {
+            _, err = out.Write(raw)
+        }
+        return err
+    })
+}
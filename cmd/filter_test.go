@@ -0,0 +1,49 @@
+package cmd
+
+import (
+    "testing"
+    "bytes"
+    "io/ioutil"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/filter"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+// TestFilterMessages filters the four GTS enveloped messages of a file by their
+// typical date and time, and checks they are copied byte for byte.
+func TestFilterMessages(t *testing.T) {
+    path := "../_testdata/ISMD01_OKPR.bufr"
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Offsets and lengths of the messages, listed by scan
+    messages := [][]byte{data[31:723], data[758:1472], data[1507:2207], data[2242:2952]}
+
+    config := &api.Config{
+        DefinitionsPath: "../_definitions",
+        TablesPath:      "../_definitions/tables",
+        InputType:       tdcfio.BinaryInput,
+        Script:          api.SCAN_SCRIPT,
+    }
+    expr, err := filter.Parse("typicalDateTime >= '2007-11-21T12:00:00'")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for _, c := range []struct {
+        invert   bool
+        expected [][]byte
+    }{
+        {false, [][]byte{messages[0], messages[2]}},
+        {true, [][]byte{messages[1], messages[3]}},
+    } {
+        var out bytes.Buffer
+        if err := filterMessages(config, []string{path}, expr, c.invert, &out); err != nil {
+            t.Fatal(err)
+        }
+        if !bytes.Equal(out.Bytes(), bytes.Join(c.expected, nil)) {
+            t.Errorf("invert=%v: copied %v bytes that are not the expected messages", c.invert, out.Len())
+        }
+    }
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+    "os"
+    "io"
+    "log"
+    "github.com/spf13/cobra"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/serialize"
+    "github.com/ywangd/gobufrkit/transform"
+)
+
+// mergeCmd represents the merge command
+var mergeCmd = &cobra.Command{
+    Use:   "merge [filenames]",
+    Short: "Merge messages from BUFR files or STDIN if no file is given into a single message.",
+    Long: `Merge messages from BUFR files or STDIN if no file is given into a single message.
+
+All messages must share an identical unexpanded template and table versions.
+Header fields other than number of subsets and compression are taken from
+the first message.`,
+    Args: cobra.ArbitraryArgs,
+    Run:  runMerge,
+}
+
+func init() {
+    RootCmd.AddCommand(mergeCmd)
+    mergeCmd.Flags().StringP("output", "o", "", "Output file (default is STDOUT)")
+    mergeCmd.Flags().BoolP("compress", "z", false, "Compress the merged message")
+}
+
+func runMerge(cmd *cobra.Command, args []string) {
+    var out io.Writer = os.Stdout
+    if outputPath := cmd.Flag("output").Value.String(); outputPath != "" {
+        f, err := os.Create(outputPath)
+        if err != nil {
+            log.Fatal(err.Error())
+        }
+        defer f.Close()
+        out = f
+    }
+
+    inputs := [][]string{nil}
+    if len(args) > 0 {
+        inputs = inputs[:0]
+        for _, arg := range args {
+            inputs = append(inputs, []string{arg})
+        }
+    }
+
+    var messages []*bufr.Message
+    config := newRuntimeConfig(cmd)
+    for _, input := range inputs {
+        err := forEachMessage(config, input, func(message *bufr.Message) error {
+            messages = append(messages, message)
+            return nil
+        })
+        if err != nil {
+            log.Fatal(err.Error())
+        }
+    }
+
+    message, err := transform.Merge(messages, cmd.Flag("compress").Changed)
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+    if err := serialize.NewBinarySerializer(out).Serialize(message); err != nil {
+        log.Fatal(err.Error())
+    }
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+    "context"
+    "io"
+    "os"
+    "fmt"
+    "path/filepath"
+    "github.com/spf13/cobra"
+    "github.com/spf13/viper"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+// errStopMessages can be returned by a message handler to stop processing any further messages
+var errStopMessages = fmt.Errorf("stop processing messages")
+
+// newRuntimeConfig creates the runtime config from command line flags
+func newRuntimeConfig(cmd *cobra.Command) *api.Config {
+    definitionsPath := viper.GetString("definitions_path")
+    config := &api.Config{
+        DefinitionsPath: definitionsPath,
+        TablesPath:      filepath.Join(definitionsPath, "tables"),
+        InputType:       tdcfio.BinaryInput,
+        Compatible:      cmd.Flag("compatible").Changed,
+        Verbose:         cmd.Flag("debug").Changed,
+    }
+    if cmd.Flag("sandbox").Changed {
+        sandbox := api.DefaultSandbox
+        config.Sandbox = &sandbox
+    }
+    return config
+}
+
+// forEachMessage decodes all messages from the file given in args, or STDIN if no file
+// is given, and passes them one by one to the handler. The input can be compressed and/or
+// a tar archive of multiple files. Each message has its number and inputPath metadata set.
+func forEachMessage(config *api.Config, args []string, handler func(*bufr.Message) error) error {
+    return processMessages(config, args, false, func(message *bufr.Message, _ []byte) error {
+        return handler(message)
+    })
+}
+
+// forEachRawMessage is the same as forEachMessage except that the handler also
+// receives the raw bytes of each message as found in the (decompressed) input.
+func forEachRawMessage(config *api.Config, args []string, handler func(*bufr.Message, []byte) error) error {
+    return processMessages(config, args, true, handler)
+}
+
+func processMessages(config *api.Config, args []string, raw bool,
+    handler func(*bufr.Message, []byte) error) error {
+
+    var (
+        ins       *os.File
+        inputPath string
+        err       error
+    )
+    if len(args) > 0 {
+        inputPath = args[0]
+        ins, err = os.Open(inputPath)
+        if err != nil {
+            return err
+        }
+        defer ins.Close()
+    } else {
+        inputPath = "-"
+        ins = os.Stdin
+    }
+
+    ar, err := tdcfio.NewArchiveReader(ins, inputPath)
+    if err != nil {
+        return err
+    }
+
+    number := 0
+    for {
+        name, r, err := ar.Next()
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+
+        var rec *recorder
+        if raw {
+            rec = &recorder{r: r}
+            r = rec
+        }
+        rt, err := api.NewRuntime(config, newPeekableReader(config, r))
+        if err != nil {
+            return err
+        }
+
+        for {
+            message, err := rt.Run(context.Background())
+            if err == io.EOF {
+                break
+            }
+            number++
+            if err != nil {
+                return fmt.Errorf("Lua error: message %v of %v: %v", number, name, err)
+            }
+            message.SetMetadata("number", number)
+            message.SetMetadata("inputPath", name)
+
+            var b []byte
+            if rec != nil {
+                if b, err = rec.message(message); err != nil {
+                    return err
+                }
+            }
+            if err := handler(message, b); err != nil {
+                if err == errStopMessages {
+                    return nil
+                }
+                return err
+            }
+        }
+    }
+}
+
+// newPeekableReader returns the reader of the configured input type on r
+func newPeekableReader(config *api.Config, r io.Reader) tdcfio.PeekableReader {
+    switch config.InputType {
+    case tdcfio.FlatTextInput:
+        return tdcfio.NewFlatTextReader(r)
+    case tdcfio.CrexInput:
+        return tdcfio.NewCrexReader(r)
+    default:
+        return tdcfio.NewPeekableBitReader(r)
+    }
+}
+
+// recorder keeps the bytes read from the underlying reader so that the raw bytes
+// of a message can be retrieved using the start byte index of its section 0.
+type recorder struct {
+    r io.Reader
+    // recorded bytes starting from offset of the input
+    buf    []byte
+    offset int
+}
+
+func (rec *recorder) Read(p []byte) (int, error) {
+    n, err := rec.r.Read(p)
+    rec.buf = append(rec.buf, p[:n]...)
+    return n, err
+}
+
+// message returns the raw bytes of the message and forgets everything before its end
+func (rec *recorder) message(message *bufr.Message) ([]byte, error) {
+    sections := message.Sections()
+    if len(sections) == 0 {
+        return nil, fmt.Errorf("message has no section")
+    }
+    field, err := message.ProxyField("totalLengthInBytes")
+    if err != nil {
+        return nil, err
+    }
+    length, _ := field.Value.(uint)
+
+    start := sections[0].StartByteIndex - rec.offset
+    end := start + int(length)
+    if start < 0 || end > len(rec.buf) {
+        return nil, fmt.Errorf("raw bytes of message not available: %v-%v", start, end)
+    }
+    b := make([]byte, end-start)
+    copy(b, rec.buf[start:end])
+
+    rec.buf = rec.buf[end:]
+    rec.offset += end
+    return b, nil
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+    "context"
+    "io"
+    "os"
+    "fmt"
+    "strconv"
+    "strings"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/index"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+// messageRange is an inclusive range of one based message numbers.
+// A zero upper bound means the range is open ended.
+type messageRange struct {
+    from, to int
+}
+
+func (mr messageRange) contains(number int) bool {
+    return number >= mr.from && (mr.to == 0 || number <= mr.to)
+}
+
+// parseMessageRanges parses comma separated message numbers and ranges, e.g. 1,5-7,10-
+func parseMessageRanges(spec string) ([]messageRange, error) {
+    var ranges []messageRange
+    for _, part := range strings.Split(spec, ",") {
+        part = strings.TrimSpace(part)
+        bounds := strings.SplitN(part, "-", 2)
+        from, err := strconv.Atoi(bounds[0])
+        if err != nil || from < 1 {
+            return nil, fmt.Errorf("invalid message number: %q", part)
+        }
+        mr := messageRange{from: from, to: from}
+        if len(bounds) == 2 {
+            mr.to = 0
+            if bounds[1] != "" {
+                if mr.to, err = strconv.Atoi(bounds[1]); err != nil || mr.to < from {
+                    return nil, fmt.Errorf("invalid message range: %q", part)
+                }
+            }
+        }
+        ranges = append(ranges, mr)
+    }
+    return ranges, nil
+}
+
+// lastMessageNumber returns the largest message number of the ranges or zero if
+// any of them is open ended.
+func lastMessageNumber(ranges []messageRange) int {
+    last := 0
+    for _, mr := range ranges {
+        if mr.to == 0 {
+            return 0
+        }
+        if mr.to > last {
+            last = mr.to
+        }
+    }
+    return last
+}
+
+func inRanges(ranges []messageRange, number int) bool {
+    for _, mr := range ranges {
+        if mr.contains(number) {
+            return true
+        }
+    }
+    return false
+}
+
+// messageLocation is the number and byte offset of a message
+type messageLocation struct {
+    number int
+    offset int64
+}
+
+// locateMessages finds the offsets of messages of the given numbers in the input.
+// The offsets are looked up from the index file if it is given. Otherwise
+// the input is searched by reading only section 0 of each message.
+func locateMessages(r io.ReaderAt, inputPath string, ranges []messageRange, indexPath string) ([]messageLocation, error) {
+    var locations []messageLocation
+
+    if indexPath != "" {
+        f, err := os.Open(indexPath)
+        if err != nil {
+            return nil, err
+        }
+        defer f.Close()
+        entries, err := index.Read(f)
+        if err != nil {
+            return nil, err
+        }
+        for _, entry := range entries {
+            if entry.InputPath == inputPath && inRanges(ranges, entry.Number) {
+                locations = append(locations, messageLocation{entry.Number, int64(entry.Offset)})
+            }
+        }
+        return locations, checkLocated(ranges, locations)
+    }
+
+    last := lastMessageNumber(ranges)
+    locator := tdcfio.NewMessageLocator(r)
+    for number := 1; last == 0 || number <= last; number++ {
+        offset, _, err := locator.Next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+        if inRanges(ranges, number) {
+            locations = append(locations, messageLocation{number, offset})
+        }
+    }
+    return locations, checkLocated(ranges, locations)
+}
+
+// checkLocated returns an error if any message of the ranges is not found. Open ended
+// ranges only require their first message.
+func checkLocated(ranges []messageRange, locations []messageLocation) error {
+    found := make(map[int]bool)
+    for _, location := range locations {
+        found[location.number] = true
+    }
+    for _, mr := range ranges {
+        to := mr.to
+        if to == 0 {
+            to = mr.from
+        }
+        for number := mr.from; number <= to; number++ {
+            if !found[number] {
+                return fmt.Errorf("message not found: %d", number)
+            }
+        }
+    }
+    return nil
+}
+
+// forEachMessageAt decodes only the messages at the given locations of the input
+// file and passes them one by one to the handler.
+func forEachMessageAt(config *api.Config, inputPath string, ins *os.File,
+    locations []messageLocation, handler func(*bufr.Message) error) error {
+
+    for _, location := range locations {
+        message, err := api.DecodeMessageAt(context.Background(), config, ins, location.offset)
+        if err != nil {
+            return err
+        }
+        message.SetMetadata("number", location.number)
+        message.SetMetadata("inputPath", inputPath)
+        if err := handler(message); err != nil {
+            if err == errStopMessages {
+                return nil
+            }
+            return err
+        }
+    }
+    return nil
+}
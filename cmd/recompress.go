@@ -0,0 +1,60 @@
+package cmd
+
+import (
+    "os"
+    "io"
+    "log"
+    "github.com/spf13/cobra"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/serialize"
+    "github.com/ywangd/gobufrkit/transform"
+)
+
+// recompressCmd represents the recompress command
+var recompressCmd = &cobra.Command{
+    Use:   "recompress (--compress|--decompress) [filename]",
+    Short: "Compress or decompress messages from a BUFR file or STDIN if no file is given.",
+    Long: `Compress or decompress messages from a BUFR file or STDIN if no file is given.
+
+Messages are decoded and packed again with the isCompressed flag of section 3
+set accordingly. Compression requires delayed replication factors to be equal
+across all subsets of a message.`,
+    Args: cobra.MaximumNArgs(1),
+    Run:  runRecompress,
+}
+
+func init() {
+    RootCmd.AddCommand(recompressCmd)
+    recompressCmd.Flags().StringP("output", "o", "", "Output file (default is STDOUT)")
+    recompressCmd.Flags().BoolP("compress", "z", false, "Compress messages")
+    recompressCmd.Flags().Bool("decompress", false, "Decompress messages")
+}
+
+func runRecompress(cmd *cobra.Command, args []string) {
+    compress := cmd.Flag("compress").Changed
+    if compress == cmd.Flag("decompress").Changed {
+        log.Fatal("exactly one of --compress or --decompress is required")
+    }
+
+    var out io.Writer = os.Stdout
+    if outputPath := cmd.Flag("output").Value.String(); outputPath != "" {
+        f, err := os.Create(outputPath)
+        if err != nil {
+            log.Fatal(err.Error())
+        }
+        defer f.Close()
+        out = f
+    }
+
+    serializer := serialize.NewBinarySerializer(out)
+    err := forEachMessage(newRuntimeConfig(cmd), args, func(message *bufr.Message) error {
+        m, err := transform.Recompress(message, compress)
+        if err != nil {
+            return err
+        }
+        return serializer.Serialize(m)
+    })
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+}
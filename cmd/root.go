@@ -45,6 +45,7 @@ func init() {
     RootCmd.PersistentFlags().StringP("definitions-path", "d", "", "path for definitions files")
     RootCmd.PersistentFlags().BoolP("compatible", "C", false, "turn on compatible mode")
     RootCmd.PersistentFlags().BoolP("debug", "D", false, "turn on debug output")
+    RootCmd.PersistentFlags().Bool("sandbox", false, "run definitions scripts in a sandbox")
 
     // Cobra also supports local flags, which will only run
     // when this action is called directly.
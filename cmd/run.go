@@ -0,0 +1,81 @@
+package cmd
+
+import (
+    "log"
+    "github.com/spf13/cobra"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/bufr"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+    Use:   "run script.lua [filename...]",
+    Short: "Run a Lua script over messages from BUFR files or STDIN if no file is given.",
+    Long: `Run a Lua script over messages from BUFR files or STDIN if no file is given.
+
+The script defines any of the following hook functions:
+
+  on_start(files)           called once before any message
+  on_message(message)       called for each message, return false to skip its subsets
+  on_subset(subset, message) called for each subset of a message
+  on_end()                  called once after all messages
+
+Calling stop() ends processing after the current hook. For an example, the
+following script prints the station of each subset with temperature above 300 K:
+
+  function on_subset(subset, message)
+      local t = subset:find(12101)
+      if t and not t:isMissing() and t:value() > 300 then
+          print(message:getMetadata("number"), subset:index(),
+              subset:find(1001):value(), subset:find(1002):value(), t:value())
+      end
+  end`,
+    Args: cobra.MinimumNArgs(1),
+    Run:  runRun,
+}
+
+func init() {
+    RootCmd.AddCommand(runCmd)
+}
+
+func runRun(cmd *cobra.Command, args []string) {
+    script, err := api.NewUserScript(args[0])
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+    inputPaths := args[1:]
+    if err := script.Start(inputPaths); err != nil {
+        log.Fatal(err.Error())
+    }
+
+    inputs := [][]string{nil}
+    if len(inputPaths) > 0 {
+        inputs = inputs[:0]
+        for _, inputPath := range inputPaths {
+            inputs = append(inputs, []string{inputPath})
+        }
+    }
+
+    config := newRuntimeConfig(cmd)
+    for _, input := range inputs {
+        if script.Stopped() {
+            break
+        }
+        err := forEachMessage(config, input, func(message *bufr.Message) error {
+            if err := script.RunMessage(message); err != nil {
+                return err
+            }
+            if script.Stopped() {
+                return errStopMessages
+            }
+            return nil
+        })
+        if err != nil {
+            log.Fatal(err.Error())
+        }
+    }
+
+    if err := script.End(); err != nil {
+        log.Fatal(err.Error())
+    }
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+    "os"
+    "log"
+    "fmt"
+    "strings"
+    "github.com/spf13/cobra"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/index"
+)
+
+// scanCmd represents the scan command
+var scanCmd = &cobra.Command{
+    Use:   "scan [filenames]",
+    Short: "List messages in BUFR files or STDIN if no file is given.",
+    Long: `List messages in BUFR files or STDIN if no file is given.
+
+Only the header sections, i.e. sections 0 to 3, are decoded. The rest of each
+message is skipped. The JSON lines output can be saved as an index file for
+random access decoding.`,
+    Aliases: []string{"s"},
+    Run:     runScan,
+}
+
+func init() {
+    RootCmd.AddCommand(scanCmd)
+    scanCmd.Flags().BoolP("json", "j", false, "Output as JSON lines, i.e. an index file")
+}
+
+func runScan(cmd *cobra.Command, args []string) {
+    asJson := cmd.Flag("json").Changed
+
+    config := newRuntimeConfig(cmd)
+    config.Script = api.SCAN_SCRIPT
+
+    handler := func(message *bufr.Message) error {
+        entry, err := index.NewEntry(message)
+        if err != nil {
+            return err
+        }
+        if asJson {
+            return index.Write(os.Stdout, entry)
+        }
+        _, err = fmt.Printf("%v #%v offset=%v length=%v edition=%v centre=%v subCentre=%v "+
+            "dataCategory=%v tables=%v/%v dateTime=%v nSubsets=%v compressed=%v template=%v\n",
+            entry.InputPath, entry.Number, entry.Offset, entry.Length, entry.Edition,
+            entry.Centre, entry.SubCentre, entry.DataCategory,
+            entry.MasterTableVersion, entry.LocalTableVersion, entry.TypicalDateTime,
+            entry.NSubsets, entry.Compressed, strings.Join(entry.Template, ","))
+        return err
+    }
+
+    if len(args) == 0 {
+        args = []string{""}
+    }
+    for _, arg := range args {
+        var inputArgs []string
+        if arg != "" {
+            inputArgs = []string{arg}
+        }
+        if err := forEachMessage(config, inputArgs, handler); err != nil {
+            log.Fatal(err.Error())
+        }
+    }
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+    "os"
+    "io"
+    "fmt"
+    "log"
+    "strings"
+    "path/filepath"
+    "github.com/chzyer/readline"
+    "github.com/mitchellh/go-homedir"
+    "github.com/spf13/cobra"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+// Name of the file in the home directory to keep the shell history
+const SHELL_HISTORY_FILE = ".gobufrkit_history"
+
+// shellCmd represents the shell command
+var shellCmd = &cobra.Command{
+    Use:   "shell filename",
+    Short: "Start an interactive Lua shell on messages of a BUFR file.",
+    Long: `Start an interactive Lua shell on messages of a BUFR file.
+
+The shell preloads the list of decoded messages as messages, the first message
+as message and the deserializer library of the definitions scripts as factory.
+Expressions are evaluated and their values printed, e.g.
+
+  > message
+  > field('nSubsets')
+  > subset(1):find(12101):value()
+  > cells(2)
+
+Type help() for the list of helper functions. Lines are edited and recalled with
+the usual key bindings and the history is kept in ~/` + SHELL_HISTORY_FILE + `.`,
+    Args: cobra.ExactArgs(1),
+    Run:  runShell,
+}
+
+func init() {
+    RootCmd.AddCommand(shellCmd)
+}
+
+func runShell(cmd *cobra.Command, args []string) {
+    config := newRuntimeConfig(cmd)
+
+    var messages []*bufr.Message
+    err := forEachMessage(config, args, func(message *bufr.Message) error {
+        messages = append(messages, message)
+        return nil
+    })
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "warning: %v, %v messages decoded\n", err, len(messages))
+    }
+
+    rlConfig := &readline.Config{Prompt: "> "}
+    if home, err := homedir.Dir(); err == nil {
+        rlConfig.HistoryFile = filepath.Join(home, SHELL_HISTORY_FILE)
+    }
+    rl, err := readline.NewEx(rlConfig)
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+    defer rl.Close()
+
+    // A fresh runtime on the input so that the factory works on the raw bytes,
+    // read through the same decompression and input type as the messages
+    ins, err := os.Open(args[0])
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+    defer ins.Close()
+    ar, err := tdcfio.NewArchiveReader(ins, args[0])
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+    _, r, err := ar.Next()
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+    rt, err := api.NewRuntime(config, newPeekableReader(config, r))
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+    shell, err := api.NewShell(rt, messages, rl.Stdout())
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+
+    fmt.Fprintf(rl.Stdout(), "%v messages from %v, type help() for help\n", len(messages), args[0])
+    var lines []string
+    for {
+        line, err := rl.Readline()
+        if err == readline.ErrInterrupt {
+            lines = nil
+            rl.SetPrompt("> ")
+            continue
+        }
+        if err == io.EOF {
+            return
+        }
+        if err != nil {
+            log.Fatal(err.Error())
+        }
+
+        lines = append(lines, line)
+        code := strings.Join(lines, "\n")
+        if strings.TrimSpace(code) == "" {
+            lines = nil
+            continue
+        }
+        results, err := shell.Eval(code)
+        if err == api.ErrIncomplete {
+            rl.SetPrompt(">> ")
+            continue
+        }
+        lines = nil
+        rl.SetPrompt("> ")
+        if err != nil {
+            fmt.Fprintln(rl.Stderr(), err)
+            continue
+        }
+        if len(results) > 0 {
+            fmt.Fprintln(rl.Stdout(), strings.Join(results, "\t"))
+        }
+    }
+}
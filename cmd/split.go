@@ -0,0 +1,57 @@
+package cmd
+
+import (
+    "os"
+    "io"
+    "log"
+    "github.com/spf13/cobra"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/serialize"
+    "github.com/ywangd/gobufrkit/transform"
+)
+
+// splitCmd represents the split command
+var splitCmd = &cobra.Command{
+    Use:   "split [filename]",
+    Short: "Split messages from a BUFR file or STDIN if no file is given into single-subset messages.",
+    Long: `Split messages from a BUFR file or STDIN if no file is given into single-subset messages.
+
+Each subset becomes an uncompressed message of its own. All other header
+fields are kept while the section lengths are updated.`,
+    Args: cobra.MaximumNArgs(1),
+    Run:  runSplit,
+}
+
+func init() {
+    RootCmd.AddCommand(splitCmd)
+    splitCmd.Flags().StringP("output", "o", "", "Output file (default is STDOUT)")
+}
+
+func runSplit(cmd *cobra.Command, args []string) {
+    var out io.Writer = os.Stdout
+    if outputPath := cmd.Flag("output").Value.String(); outputPath != "" {
+        f, err := os.Create(outputPath)
+        if err != nil {
+            log.Fatal(err.Error())
+        }
+        defer f.Close()
+        out = f
+    }
+
+    serializer := serialize.NewBinarySerializer(out)
+    err := forEachMessage(newRuntimeConfig(cmd), args, func(message *bufr.Message) error {
+        messages, err := transform.Split(message)
+        if err != nil {
+            return err
+        }
+        for _, m := range messages {
+            if err := serializer.Serialize(m); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        log.Fatal(err.Error())
+    }
+}
@@ -5,18 +5,42 @@ import (
     "github.com/ywangd/gobufrkit/deserialize/payload"
 )
 
+// Limits bounds the resources a message can take to be deserialized so that
+// malformed or hostile input cannot exhaust memory. A zero field means no limit.
+type Limits struct {
+    // Maximum number of subsets of a message
+    MaxSubsets int
+    // Maximum number of repetitions of a delayed replication
+    MaxReplications int
+    // Maximum depth of nested sequences, replications and operators of a template
+    MaxTemplateDepth int
+    // Maximum number of cells, i.e. values, and nodes of all subsets of a message
+    MaxCells int
+}
+
+// DefaultLimits are generous enough for any sensible message.
+var DefaultLimits = Limits{
+    MaxSubsets:       10000,
+    MaxReplications:  10000,
+    MaxTemplateDepth: 64,
+    MaxCells:         10000000,
+}
+
 type Config struct {
     TablesPath string
     InputType  tdcfio.InputType
     Compatible bool
     Verbose    bool
+    Limits     Limits
 }
 
 func (c *Config) toDesVisitorConfig(compressed bool) *payload.DesVisitorConfig {
     return &payload.DesVisitorConfig{
-        Compressed: compressed,
-        InputType:  c.InputType,
-        Compatible: c.Compatible,
-        Verbose:    c.Verbose,
+        Compressed:      compressed,
+        InputType:       c.InputType,
+        Compatible:      c.Compatible,
+        Verbose:         c.Verbose,
+        MaxReplications: c.Limits.MaxReplications,
+        MaxCells:        c.Limits.MaxCells,
     }
 }
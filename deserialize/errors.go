@@ -0,0 +1,31 @@
+package deserialize
+
+import (
+    "fmt"
+)
+
+// CancelledError is returned when deserializing a payload is cancelled or its
+// deadline is exceeded. It records the progress made before the cancellation.
+type CancelledError struct {
+    // Zero based index of the subset being deserialized. For compressed binary
+    // data, all subsets are deserialized together and the index is always 0.
+    Subset int
+    // Bit position of the reader when the cancellation is noticed
+    Pos int
+    // The error of the context, i.e. context.Canceled or context.DeadlineExceeded
+    Err error
+}
+
+func (e *CancelledError) Error() string {
+    return fmt.Sprintf("deserializing cancelled at subset %v, bit %v: %v", e.Subset, e.Pos, e.Err)
+}
+
+// Cause returns the error of the context so that errors.Cause finds it
+func (e *CancelledError) Cause() error {
+    return e.Err
+}
+
+// Unwrap returns the error of the context so that errors.Is finds it
+func (e *CancelledError) Unwrap() error {
+    return e.Err
+}
@@ -2,6 +2,7 @@
 package deserialize
 
 import (
+    "context"
     "fmt"
     "io"
     "github.com/pkg/errors"
@@ -25,6 +26,9 @@ const (
     BINARY
 )
 
+// Delayed replication factor descriptor added to delayed replications of CREX templates
+const CREX_REPLICATION_FACTOR = 31001
+
 // Factory provides enabling operations for building BUFR object.
 // The factory object is to be driven by Lua scripts.
 type Factory interface {
@@ -45,18 +49,32 @@ type Factory interface {
     // NewField creates a new Field and returns it (does not add to the current section)
     NewField(name string, dataType DataType, nbits int, proxy bool) (*bufr.Field, error)
 
+    // NewVirtualField creates a new virtual field of the given value and adds it to
+    // the current section. It reads nothing from the input.
+    NewVirtualField(name string, value interface{}, proxy bool) *bufr.Field
+
     // NewTemplateField creates a new field holding the template values and returns it.
     // The number of bits for F, X, Y is passed as fbits, xbits and ybits.
     // The section length is to ensure that the read will stop at the section boundary
     // (as the template field is the last field of a section).
     NewTemplateField(name string, fbits, xbits, ybits int, sectionLengthInBytes uint) (*bufr.Field, error)
 
+    // NewCrexTemplateField creates a new field holding the template given as text,
+    // e.g. D07022 R01000 B12101 of CREX, and returns it. A delayed replication factor
+    // descriptor is added to each delayed replication that has none, as the factor
+    // comes with the data in CREX but BUFR describes it in the template.
+    NewCrexTemplateField(name string) (*bufr.Field, error)
+
     // NewPayloadField creates a new field holding payload data and returns it.
-    NewPayloadField(name string, nsubsets int, compressed bool) (*bufr.Field, error)
+    // Deserializing stops with a *CancelledError once the context is done.
+    NewPayloadField(ctx context.Context, name string, nsubsets int, compressed bool) (*bufr.Field, error)
 
     // Padding creates a new field by reading remaining bits in the current section and returns it.
     Padding(sectionLengthInBytes uint) (*bufr.Field, error)
 
+    // SkipMessage discards the remaining bytes of the current message without decoding them.
+    SkipMessage(totalLengthInBytes uint) error
+
     // CheckEOF checks whether the EOF is reached.
     CheckEOF() (bool, error)
 
@@ -64,6 +82,8 @@ type Factory interface {
     PeekEditionNumber() (uint, error)
 
     // SeekStartSignature read the input stream until the start signature is found.
+    // Any GTS bulletin envelope skipped over is recorded as metadata of the next new message.
+    // It returns io.EOF if no more start signature can be found.
     SeekStartSignature() error
 }
 
@@ -80,6 +100,9 @@ type DefaultFactory struct {
 
     // table group for lookup descriptors
     tableGroup table.TableGroup
+
+    // GTS bulletin heading found by the last seek of start signature
+    heading *tdcfio.Heading
 }
 
 func NewDefaultFactory(config *Config, r tdcfio.PeekableReader) *DefaultFactory {
@@ -92,6 +115,14 @@ func (fac *DefaultFactory) Message() *bufr.Message {
 
 func (fac *DefaultFactory) NewMessage(inputPath string) *bufr.Message {
     fac.message = bufr.NewMessage(inputPath)
+    if fac.heading != nil {
+        fac.message.SetMetadata("gtsSequenceNumber", fac.heading.SequenceNumber)
+        fac.message.SetMetadata("gtsTTAAii", fac.heading.TTAAii)
+        fac.message.SetMetadata("gtsCCCC", fac.heading.CCCC)
+        fac.message.SetMetadata("gtsYYGGgg", fac.heading.YYGGgg)
+        fac.message.SetMetadata("gtsBBB", fac.heading.BBB)
+        fac.heading = nil
+    }
     return fac.message
 }
 
@@ -147,6 +178,15 @@ func (fac *DefaultFactory) NewField(name string, dataType DataType, nbits int, p
     return field, nil
 }
 
+func (fac *DefaultFactory) NewVirtualField(name string, value interface{}, proxy bool) *bufr.Field {
+    field := bufr.NewVirtualField(name, value)
+    fac.section.AddField(field)
+    if proxy {
+        fac.message.SetProxyField(field)
+    }
+    return field
+}
+
 func (fac *DefaultFactory) NewTemplateField(
     name string, fbits, xbits, ybits int, sectionLengthInBytes uint) (*bufr.Field, error) {
     remainingBits := int(sectionLengthInBytes)*tdcfio.NBITS_PER_BYTE -
@@ -178,9 +218,45 @@ func (fac *DefaultFactory) NewTemplateField(
     return field, nil
 }
 
-func (fac *DefaultFactory) NewPayloadField(name string, nsubsets int, compressed bool) (*bufr.Field, error) {
+func (fac *DefaultFactory) NewCrexTemplateField(name string) (*bufr.Field, error) {
+    dr, ok := fac.r.(tdcfio.DescriptorReader)
+    if !ok {
+        return nil, fmt.Errorf("reader cannot read descriptors as text: %T", fac.r)
+    }
+    spos := fac.r.Pos()
+    xs, err := dr.ReadDescriptors()
+    if err != nil {
+        return nil, errors.Wrap(err, "cannot read descriptors")
+    }
+    if len(xs) == 0 {
+        return nil, fmt.Errorf("no descriptor for %v", name)
+    }
+
+    var ids []table.ID
+    for i, x := range xs {
+        id := table.ID(x)
+        ids = append(ids, id)
+        if id.F() == table.F_REPLICATION && id.Y() == 0 &&
+            (i+1 == len(xs) || table.ID(xs[i+1]).X() != 31) {
+            ids = append(ids, table.ID(CREX_REPLICATION_FACTOR))
+        }
+    }
+    fac.ut = table.NewUnexpandedTemplate(ids, 2, 6, 8)
+    field := bufr.NewField(name, fac.ut, fac.r.Pos()-spos)
+    fac.section.AddField(field)
+    fac.message.SetProxyField(field)
+
+    return field, nil
+}
+
+func (fac *DefaultFactory) NewPayloadField(ctx context.Context, name string, nsubsets int, compressed bool) (*bufr.Field, error) {
+    if max := fac.config.Limits.MaxSubsets; max > 0 && nsubsets > max {
+        return nil, fmt.Errorf("number of subsets exceeds the limit of %v: %v", max, nsubsets)
+    }
     spos := fac.r.Pos()
-    tree, err := parser.NewParser(fac.tableGroup).Parse(fac.ut)
+    p := parser.NewParser(fac.tableGroup)
+    p.MaxDepth = fac.config.Limits.MaxTemplateDepth
+    tree, err := p.Parse(fac.ut)
     if err != nil {
         return nil, errors.Wrap(err, "cannot parse template")
     }
@@ -190,7 +266,7 @@ func (fac *DefaultFactory) NewPayloadField(name string, nsubsets int, compressed
         tree.Accept(v)
     }
 
-    desvis, err := payload.NewDeserializeVisitor(fac.config.toDesVisitorConfig(compressed), fac.r, nsubsets)
+    desvis, err := payload.NewDeserializeVisitor(ctx, fac.config.toDesVisitorConfig(compressed), fac.r, nsubsets)
     if err != nil {
         return nil, err
     }
@@ -201,7 +277,14 @@ func (fac *DefaultFactory) NewPayloadField(name string, nsubsets int, compressed
     }
     pay := &bufr.Payload{Compressed: compressed}
     for i := 0; i < n; i++ {
+        if err := ctx.Err(); err != nil {
+            return nil, &CancelledError{Subset: i, Pos: fac.r.Pos(), Err: err}
+        }
         if err := tree.Accept(desvis); err != nil {
+            // The visitor stops with the error of the context, possibly wrapped
+            if ctxErr := ctx.Err(); ctxErr != nil {
+                return nil, &CancelledError{Subset: i, Pos: fac.r.Pos(), Err: ctxErr}
+            }
             return nil, err
         }
         if err := desvis.Produce(pay); err != nil {
@@ -219,6 +302,10 @@ func (fac *DefaultFactory) Padding(sectionLengthInBytes uint) (*bufr.Field, erro
     bitsRead := fac.r.Pos() - fac.section.StartByteIndex*tdcfio.NBITS_PER_BYTE
     bitsPadding := int(bitsTotal - bitsRead)
 
+    if namer, ok := fac.r.(tdcfio.FieldNamer); ok {
+        return fac.namedPadding(namer, bitsPadding)
+    }
+
     switch {
     case bitsPadding == 0:
         return nil, nil
@@ -237,6 +324,46 @@ func (fac *DefaultFactory) Padding(sectionLengthInBytes uint) (*bufr.Field, erro
 
 }
 
+// namedPadding reads the padding only if it is present, e.g. hidden fields may be
+// left out of a text dump. The position cannot tell the size of the padding as
+// compressed data are read subset by subset from such formats.
+func (fac *DefaultFactory) namedPadding(namer tdcfio.FieldNamer, bitsPadding int) (*bufr.Field, error) {
+    name, err := namer.PeekFieldName()
+    if err != nil && errors.Cause(err) != io.EOF {
+        return nil, err
+    }
+    if name != "padding" {
+        return nil, nil
+    }
+    if bitsPadding < 0 {
+        bitsPadding = 0
+    }
+    binary, err := fac.r.ReadBinary(bitsPadding)
+    if err != nil {
+        return nil, errors.Wrap(err, "cannot read padding")
+    }
+    fac.section.Padding = binary.Nbits()
+    field := bufr.NewHiddenField("padding", binary, binary.Nbits())
+    fac.section.AddField(field)
+    return field, nil
+}
+
+func (fac *DefaultFactory) SkipMessage(totalLengthInBytes uint) error {
+    sections := fac.message.Sections()
+    if len(sections) == 0 {
+        return fmt.Errorf("cannot skip message without any section")
+    }
+    nbytes := int(totalLengthInBytes) - (fac.r.Pos()/tdcfio.NBITS_PER_BYTE - sections[0].StartByteIndex)
+    if nbytes < 0 {
+        return fmt.Errorf("message already read beyond its length: %v", totalLengthInBytes)
+    }
+    if skipper, ok := fac.r.(tdcfio.Skipper); ok {
+        return skipper.Skip(nbytes)
+    }
+    _, err := fac.r.ReadBytes(nbytes)
+    return err
+}
+
 func (fac *DefaultFactory) CheckEOF() (bool, error) {
     _, err := fac.r.PeekUint(0, 8)
     if err == io.EOF {
@@ -247,7 +374,16 @@ func (fac *DefaultFactory) CheckEOF() (bool, error) {
 
 // TODO: BUFR structural info leak
 func (fac *DefaultFactory) PeekEditionNumber() (uint, error) {
-    v, err := fac.r.PeekUint(7, 8)
+    skip, nbits := 7, 8
+    switch fac.config.InputType {
+    case tdcfio.BinaryInput:
+    case tdcfio.CrexInput:
+        // Characters of CREX++, T and the master table number
+        skip, nbits = 9, 16
+    default:
+        skip = 2 // values of start signature and total length
+    }
+    v, err := fac.r.PeekUint(skip, nbits)
     if err != nil {
         return 0, err
     }
@@ -255,16 +391,73 @@ func (fac *DefaultFactory) PeekEditionNumber() (uint, error) {
 }
 
 func (fac *DefaultFactory) SeekStartSignature() error {
+    var skipped envelopeWindow
     for {
         bs, err := fac.r.PeekBytes(0, 4)
         if err != nil {
+            if errors.Cause(err) == io.EOF {
+                return io.EOF
+            }
             return err
         }
         if len(bs) < 4 {
             return io.EOF
-        } else if string(bs) == "BUFR" {
+        } else if string(bs) == fac.startSignature() {
+            // Bytes in front of the message may be a GTS bulletin envelope
+            fac.heading = tdcfio.ParseEnvelope(skipped.Bytes())
             return nil
         }
-        fac.r.ReadBytes(1)
+        b, err := fac.r.ReadBytes(1)
+        if err != nil {
+            return err
+        }
+        // Only the bytes since the last SOH are kept, up to the length of an envelope,
+        // so that skipping over large garbage does not accumulate it
+        if b[0] == tdcfio.SOH {
+            skipped.Reset()
+        }
+        skipped.Add(b[0])
+    }
+}
+
+// envelopeWindow is a ring buffer keeping the last bytes skipped in front of a message,
+// up to the length of a GTS bulletin envelope.
+type envelopeWindow struct {
+    buf   [tdcfio.MAX_ENVELOPE_LENGTH]byte
+    start int
+    n     int
+}
+
+func (w *envelopeWindow) Reset() {
+    w.start, w.n = 0, 0
+}
+
+// Add appends a byte, dropping the oldest one when the window is full
+func (w *envelopeWindow) Add(b byte) {
+    if w.n < len(w.buf) {
+        w.buf[(w.start+w.n)%len(w.buf)] = b
+        w.n++
+        return
+    }
+    w.buf[w.start] = b
+    w.start = (w.start + 1) % len(w.buf)
+}
+
+// Bytes returns the bytes in the window from the oldest to the newest
+func (w *envelopeWindow) Bytes() []byte {
+    bs := make([]byte, 0, w.n)
+    end := w.start + w.n
+    if end <= len(w.buf) {
+        return append(bs, w.buf[w.start:end]...)
+    }
+    bs = append(bs, w.buf[w.start:]...)
+    return append(bs, w.buf[:end-len(w.buf)]...)
+}
+
+// startSignature returns the start signature of messages of the input type
+func (fac *DefaultFactory) startSignature() string {
+    if fac.config.InputType == tdcfio.CrexInput {
+        return "CREX"
     }
+    return "BUFR"
 }
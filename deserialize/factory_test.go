@@ -0,0 +1,24 @@
+package deserialize
+
+import (
+    "testing"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+func TestEnvelopeWindow(t *testing.T) {
+    var w envelopeWindow
+    for i := 0; i < tdcfio.MAX_ENVELOPE_LENGTH+10; i++ {
+        w.Add(byte(i))
+    }
+    bs := w.Bytes()
+    if len(bs) != tdcfio.MAX_ENVELOPE_LENGTH || bs[0] != 10 || bs[len(bs)-1] != tdcfio.MAX_ENVELOPE_LENGTH+9 {
+        t.Fatalf("unexpected window after wrapping: %v", bs)
+    }
+
+    w.Reset()
+    w.Add(tdcfio.SOH)
+    w.Add('\r')
+    if bs := w.Bytes(); string(bs) != "\x01\r" {
+        t.Fatalf("unexpected window after reset: %q", bs)
+    }
+}
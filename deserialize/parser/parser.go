@@ -20,6 +20,7 @@ import (
     "github.com/ywangd/gobufrkit/deserialize/ast"
     "fmt"
     "math/bits"
+    "github.com/pkg/errors"
 )
 
 // Parser creates a tree of ast.Node from an UnexpandedTemplate.
@@ -32,6 +33,13 @@ type Parser struct {
     tableGroup table.TableGroup
     // bit flags for states
     states uint
+
+    // Maximum depth of nested sequences, replications and operators. There is
+    // no limit if it is zero. This guards against, e.g. a sequence that contains
+    // itself from a malformed local table.
+    MaxDepth int
+    // Current depth of nesting
+    depth int
 }
 
 func NewParser(tableGroup table.TableGroup) *Parser {
@@ -74,9 +82,13 @@ func (p *Parser) parseElementNode(keeper *idsKeeper) (ast.Node, error) {
     )
     if p.getState(stateOpSkipLocal) {
         // Create an ad-hoc local descriptor so it does not error out
-        descriptor = table.NewLocalDescriptor(keeper.take())
+        id, err := keeper.take()
+        if err != nil {
+            return nil, err
+        }
+        descriptor = table.NewLocalDescriptor(id)
     } else {
-        if descriptor, err = p.tableGroup.Lookup(keeper.take()); err != nil {
+        if descriptor, err = p.lookup(keeper); err != nil {
             return nil, err
         }
     }
@@ -89,35 +101,57 @@ func (p *Parser) parseElementNode(keeper *idsKeeper) (ast.Node, error) {
     }
 }
 
+// lookup takes the next ID from the keeper and looks up its descriptor
+func (p *Parser) lookup(keeper *idsKeeper) (table.Descriptor, error) {
+    id, err := keeper.take()
+    if err != nil {
+        return nil, err
+    }
+    return p.tableGroup.Lookup(id)
+}
+
 func (p *Parser) parseSequenceNode(keeper *idsKeeper) (ast.Node, error) {
-    descriptor, err := p.tableGroup.Lookup(keeper.take())
+    descriptor, err := p.lookup(keeper)
     if err != nil {
         return nil, err
     }
-    return populateMembers(p, newIdsKeeper(descriptor.Entry().(*table.Dentry).Members),
+    entry, ok := descriptor.Entry().(*table.Dentry)
+    if !ok {
+        return nil, fmt.Errorf("no sequence entry for %v", descriptor)
+    }
+    return populateMembers(p, newIdsKeeper(entry.Members),
         &ast.SequenceNode{BaseNode: ast.NewBaseNode(descriptor)})
 }
 
 func (p *Parser) parseDelayedReplicationNode(keeper *idsKeeper) (ast.Node, error) {
-    descriptor, err := p.tableGroup.Lookup(keeper.take())
+    descriptor, err := p.lookup(keeper)
     if err != nil {
         return nil, err
     }
-    return populateMembers(p, newIdsKeeper(keeper.takeN(descriptor.X()+1)),
+    // The replicated descriptors are preceded by the delayed replication factor
+    ids, err := keeper.takeN(descriptor.X() + 1)
+    if err != nil {
+        return nil, errors.Wrapf(err, "not enough descriptors for %v", descriptor)
+    }
+    return populateMembers(p, newIdsKeeper(ids),
         &ast.DelayedReplicationNode{BaseNode: ast.NewBaseNode(descriptor)})
 }
 
 func (p *Parser) parseFixedReplicationNode(keeper *idsKeeper) (ast.Node, error) {
-    descriptor, err := p.tableGroup.Lookup(keeper.take())
+    descriptor, err := p.lookup(keeper)
     if err != nil {
         return nil, err
     }
-    return populateMembers(p, newIdsKeeper(keeper.takeN(descriptor.X())),
+    ids, err := keeper.takeN(descriptor.X())
+    if err != nil {
+        return nil, errors.Wrapf(err, "not enough descriptors for %v", descriptor)
+    }
+    return populateMembers(p, newIdsKeeper(ids),
         &ast.FixedReplicationNode{BaseNode: ast.NewBaseNode(descriptor)})
 }
 
 func (p *Parser) parseOperatorNode(keeper *idsKeeper) (ast.Node, error) {
-    descriptor, err := p.tableGroup.Lookup(keeper.take())
+    descriptor, err := p.lookup(keeper)
     if err != nil {
         return nil, err
     }
@@ -189,7 +223,7 @@ func (p *Parser) parseOperatorNode(keeper *idsKeeper) (ast.Node, error) {
 // parseBitmapNode creates a BitmapNode by reading from the given keeper.
 func (p *Parser) parseBitmapNode(keeper *idsKeeper) (ast.Node, error) {
     // The opening descriptor
-    descriptor, err := p.tableGroup.Lookup(keeper.take())
+    descriptor, err := p.lookup(keeper)
     if err != nil {
         return nil, err
     }
@@ -0,0 +1,46 @@
+package parser_test
+
+import (
+    "testing"
+    "encoding/binary"
+    "github.com/ywangd/gobufrkit/table"
+    "github.com/ywangd/gobufrkit/deserialize/parser"
+)
+
+// FuzzParser parses templates made of arbitrary descriptor IDs, three bytes each.
+// Parsing must return an error instead of panicking for malformed templates.
+func FuzzParser(f *testing.F) {
+    tableGroup, err := table.NewSingleTableGroup(
+        "../../_definitions/tables",
+        0, 0, 0, 28)
+    if err != nil {
+        f.Fatal(err)
+    }
+
+    f.Add(idsToBytes(301001, 102001, 4001, 4002, 103000, 31001, 5001, 5002, 6001))
+    f.Add(idsToBytes(222000, 236000, 101000, 31002, 31031, 1031, 1032, 8023, 237255))
+    f.Add(idsToBytes(204003, 31021, 206050, 5003, 204000, 203012, 1031, 203255))
+    f.Add(idsToBytes(101000, 31002))
+    f.Add(idsToBytes(309052))
+
+    f.Fuzz(func(t *testing.T, data []byte) {
+        var ids []table.ID
+        for i := 0; i+3 <= len(data); i += 3 {
+            x := uint32(data[i])<<16 | uint32(data[i+1])<<8 | uint32(data[i+2])
+            ids = append(ids, table.ID(x%400000))
+        }
+        p := parser.NewParser(tableGroup)
+        p.MaxDepth = 64
+        p.Parse(table.NewUnexpandedTemplate(ids, 2, 6, 8))
+    })
+}
+
+func idsToBytes(ids ...table.ID) []byte {
+    var data []byte
+    for _, id := range ids {
+        var b [4]byte
+        binary.BigEndian.PutUint32(b[:], uint32(id))
+        data = append(data, b[1:]...)
+    }
+    return data
+}
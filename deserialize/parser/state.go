@@ -4,6 +4,7 @@ import (
     "github.com/ywangd/gobufrkit/table"
     "github.com/ywangd/gobufrkit/deserialize/ast"
     "fmt"
+    "github.com/pkg/errors"
 )
 
 const (
@@ -28,12 +29,15 @@ func (k *idsKeeper) eof() bool {
     return k.index >= len(k.ids)
 }
 
-// take returns the take ID and advance the reader head, i.e. index
-// TODO: Error handling when there is no more ID to take?
-func (k *idsKeeper) take() table.ID {
+// take returns the take ID and advance the reader head, i.e. index.
+// It is an error if there is no more ID to take.
+func (k *idsKeeper) take() (table.ID, error) {
+    if k.eof() {
+        return 0, fmt.Errorf("unexpected end of descriptors after %v descriptors", k.index)
+    }
     id := k.ids[k.index]
     k.index += 1
-    return id
+    return id, nil
 }
 
 // back decreases the reader head by one
@@ -41,22 +45,21 @@ func (k *idsKeeper) back() {
     k.index -= 1
 }
 
-// peek returns the next ID without advance the reader head, i.e. index
+// peek returns the next ID without advance the reader head, i.e. index.
+// It must not be called when the keeper has reached to the end.
 func (k *idsKeeper) peek() table.ID {
-    id := k.take()
-    k.back()
-    return id
+    return k.ids[k.index]
 }
 
-// takeN returns a slice of ID containing the next N IDs from the keeper
-// TODO: Error handling when there are not enough IDs? This can be checked with LINT
-// TODO: optimise by record start and stop index and make the slice in one go?
-func (k *idsKeeper) takeN(n int) []table.ID {
-    ids := make([]table.ID, n)
-    for i := 0; i < n && !k.eof(); i++ {
-        ids[i] = k.take()
+// takeN returns a slice of ID containing the next N IDs from the keeper.
+// It is an error if there are not enough IDs.
+func (k *idsKeeper) takeN(n int) ([]table.ID, error) {
+    if n < 0 || k.index+n > len(k.ids) {
+        return nil, fmt.Errorf("expect %v descriptors, only %v left", n, len(k.ids)-k.index)
     }
-    return ids
+    ids := k.ids[k.index : k.index+n]
+    k.index += n
+    return ids, nil
 }
 
 // takeWhile takes next ID repeatedly while the given predicate returns true.
@@ -73,7 +76,7 @@ func (k *idsKeeper) takeWhile(predicate func(table.ID) bool) []table.ID {
     started := false
     ids := []table.ID{}
     for !k.eof() {
-        id := k.take()
+        id, _ := k.take()
         match := predicate(id)
         if match && !started {
             started = true
@@ -90,7 +93,7 @@ func (k *idsKeeper) takeWhile(predicate func(table.ID) bool) []table.ID {
 func (k *idsKeeper) takeTill(predicate func(table.ID) bool) []table.ID {
     ids := []table.ID{}
     for !k.eof() {
-        id := k.take()
+        id, _ := k.take()
         if predicate(id) {
             k.back()
             break
@@ -145,7 +148,13 @@ func processThrough(p *Parser, keeper *idsKeeper) (nodes []ast.Node, err error)
 }
 
 // populateMembers sets members of the given node by processing through all IDs provided by the keeper.
+// The depth of nesting is checked against the maximum depth of the parser.
 func populateMembers(p *Parser, keeper *idsKeeper, node ast.Node) (ast.Node, error) {
+    p.depth++
+    defer func() { p.depth-- }()
+    if p.MaxDepth > 0 && p.depth > p.MaxDepth {
+        return nil, fmt.Errorf("template nested deeper than %v levels", p.MaxDepth)
+    }
     members, err := processThrough(p, keeper)
     if err != nil {
         return nil, err
@@ -177,22 +186,34 @@ func assembleOpNewRefvalNode(p *Parser, keeper *idsKeeper, descriptor table.Desc
 func assembleOpAssocFieldNode(p *Parser, keeper *idsKeeper, descriptor table.Descriptor) (ast.Node, error) {
     node := &ast.OpAssocFieldNode{BaseNode: ast.NewBaseNode(descriptor)}
     if descriptor.Y() != 0 {
-        return populateMembers(p, newIdsKeeper(keeper.takeN(1)), node)
+        ids, err := keeper.takeN(1)
+        if err != nil {
+            return nil, errors.Wrapf(err, "no significance descriptor for %v", descriptor)
+        }
+        return populateMembers(p, newIdsKeeper(ids), node)
     }
     return node, nil
 }
 
 func assembleOpSkipLocalNode(p *Parser, keeper *idsKeeper, descriptor table.Descriptor) (ast.Node, error) {
+    ids, err := keeper.takeN(1)
+    if err != nil {
+        return nil, errors.Wrapf(err, "no local descriptor for %v", descriptor)
+    }
     p.setState(stateOpSkipLocal)
     defer p.unsetState(stateOpSkipLocal)
-    return populateMembers(p, newIdsKeeper(keeper.takeN(1)),
+    return populateMembers(p, newIdsKeeper(ids),
         &ast.OpSkipLocalNode{BaseNode: ast.NewBaseNode(descriptor)})
 }
 
 func assembleOpDataNotPresentNode(p *Parser, keeper *idsKeeper, descriptor table.Descriptor) (ast.Node, error) {
+    ids, err := keeper.takeN(descriptor.Y())
+    if err != nil {
+        return nil, errors.Wrapf(err, "not enough descriptors for %v", descriptor)
+    }
     p.setState(stateOpDataNotPresent)
     defer p.unsetState(stateOpDataNotPresent)
-    return populateMembers(p, newIdsKeeper(keeper.takeN(descriptor.Y())),
+    return populateMembers(p, newIdsKeeper(ids),
         &ast.OpDataNotPresentNode{BaseNode: ast.NewBaseNode(descriptor)})
 }
 
@@ -67,6 +67,9 @@ func (bm *BitmapManager) CancelBackref() {
 
 // Bits returns the bit values of the current bitmap
 func (bm *BitmapManager) Bits() ([]uint, error) {
+    if bm.currentBitmap == nil {
+        return nil, fmt.Errorf("no bitmap is defined")
+    }
     i0, i1 := bm.currentBitmap.Index0, bm.currentBitmap.Index1
     bits := make([]uint, i1-i0)
     for i := i0; i < i1; i++ {
@@ -109,7 +112,13 @@ func (bm *BitmapManager) InitTargetNodes() ([]*bufr.ValuedNode, error) {
     return bm.targetNodes, nil
 }
 
-func (bm *BitmapManager) NextTargetNode() (node *bufr.ValuedNode) {
-    node, bm.targetNodes = bm.targetNodes[0], bm.targetNodes[1:]
-    return
+// NextTargetNode returns the next target node for a marker node. It is an error
+// if all target nodes are already taken.
+func (bm *BitmapManager) NextTargetNode() (*bufr.ValuedNode, error) {
+    if len(bm.targetNodes) == 0 {
+        return nil, fmt.Errorf("no more bitmapped target node")
+    }
+    node := bm.targetNodes[0]
+    bm.targetNodes = bm.targetNodes[1:]
+    return node, nil
 }
@@ -5,6 +5,8 @@ import (
     "github.com/ywangd/gobufrkit/deserialize/ast"
     "github.com/pkg/errors"
     "github.com/ywangd/gobufrkit/table"
+    "github.com/ywangd/gobufrkit/deserialize/unpack"
+    "fmt"
 )
 
 // assocPair is a wrapper of a pair of data representing the number of bits
@@ -23,8 +25,12 @@ func (p *assocPairs) Push(nbits int) {
     p.pairs = append(p.pairs, &assocPair{Nbits: nbits})
 }
 
-func (p *assocPairs) Pop() {
+func (p *assocPairs) Pop() error {
+    if len(p.pairs) == 0 {
+        return fmt.Errorf("no associated field to cancel")
+    }
     p.pairs = p.pairs[:len(p.pairs)-1]
+    return nil
 }
 
 // SetNode is a no-op if there is no assocPair
@@ -42,6 +48,9 @@ func (p *assocPairs) Pairs() []*assocPair {
 func buildBlock(v *DesVisitor, members []ast.Node) error {
     v.treeBuilder.Push(bufr.NewBlock())
     defer v.treeBuilder.Pop()
+    if err := v.checkCells(); err != nil {
+        return err
+    }
     for _, m := range members { // loop of each replicated block
         if err := m.Accept(v); err != nil {
             return errors.Wrap(err, "cannot process replicated block")
@@ -83,7 +92,18 @@ func buildValuedNode(v *DesVisitor, descriptor table.Descriptor) (*bufr.ValuedNo
 // buildValueNodeWithInfo unpack value(s) of the given descriptor, assemble the ValuedNode
 // and also call treeBuilder and cellsBuilder to add the node and value(s).
 func buildValuedNodeWithInfo(v *DesVisitor, descriptor table.Descriptor, info *bufr.PackingInfo) (*bufr.ValuedNode, error) {
-    val, err := v.unpacker.Unpack(info)
+    if err := v.countCells(); err != nil {
+        return nil, err
+    }
+    var (
+        val interface{}
+        err error
+    )
+    if du, ok := v.unpacker.(unpack.DescriptorUnpacker); ok {
+        val, err = du.UnpackDescriptor(descriptor, info)
+    } else {
+        val, err = v.unpacker.Unpack(info)
+    }
     if err != nil {
         return nil, errors.Wrap(err, "cannot unpack value")
     }
@@ -1,6 +1,7 @@
 package payload
 
 import (
+    "context"
     "github.com/ywangd/gobufrkit/deserialize/unpack"
     "github.com/ywangd/gobufrkit/deserialize/ast"
     "github.com/ywangd/gobufrkit/tdcfio"
@@ -16,12 +17,19 @@ type DesVisitorConfig struct {
     // compatible mode for BUFRDC (i.e. insert 0 for some operator descriptors, e.g. 236000
     Compatible bool
     Verbose    bool
+
+    // Maximum number of repetitions of a delayed replication. No limit if it is zero.
+    MaxReplications int
+    // Maximum number of cells, i.e. values, and nodes of all subsets. No limit if it is zero.
+    MaxCells int
 }
 
 // DesVisitor is an implementation of ast.Visitor that constructs bufr.Subset by
 // deserializing from an tdcfio.Reader.
 type DesVisitor struct {
     config *DesVisitorConfig
+    // Replications stop with the error of the context once it is done
+    ctx context.Context
 
     nsubsets int
 
@@ -40,15 +48,19 @@ type DesVisitor struct {
     nbitsString    int                           // 208YYY
 
     bitmapManager *BitmapManager
+
+    // Number of cells deserialized for all subsets so far
+    ncells int
 }
 
-func NewDeserializeVisitor(config *DesVisitorConfig, reader tdcfio.Reader, nsubsets int) (*DesVisitor, error) {
+func NewDeserializeVisitor(ctx context.Context, config *DesVisitorConfig, reader tdcfio.Reader, nsubsets int) (*DesVisitor, error) {
     unpacker, err := unpack.NewUnpacker(reader, nsubsets, config.Compressed, config.InputType)
     if err != nil {
         return nil, err
     }
     return &DesVisitor{
         config:   config,
+        ctx:      ctx,
         nsubsets: nsubsets,
         unpacker: unpacker,
     }, nil
@@ -80,7 +92,7 @@ func (v *DesVisitor) reset() {
 
     v.nbitsOffset = 0
     v.scaleOffset = 0
-    v.newRefvalNodes = nil
+    v.newRefvalNodes = map[table.ID]*bufr.ValuedNode{}
     v.assocPairs = &assocPairs{}
     v.nbitsIncrement = 0
     v.scaleIncrement = 0
@@ -136,6 +148,9 @@ func (v *DesVisitor) VisitFixedReplicationNode(node *ast.FixedReplicationNode) e
     v.treeBuilder.Push(&bufr.ValuelessNode{Descriptor: node.Descriptor()})
     defer v.treeBuilder.Pop()
     for i := 0; i < node.Descriptor().Y(); i++ {
+        if err := v.ctx.Err(); err != nil {
+            return err
+        }
         if err := buildBlock(v, node.Members()); err != nil {
             return errors.Wrap(err, "cannot process fixed replication node")
         }
@@ -146,6 +161,9 @@ func (v *DesVisitor) VisitFixedReplicationNode(node *ast.FixedReplicationNode) e
 func (v *DesVisitor) VisitDelayedReplicationNode(node *ast.DelayedReplicationNode) error {
     v.treeBuilder.Push(&bufr.ValuelessNode{Descriptor: node.Descriptor()})
     defer v.treeBuilder.Pop()
+    if len(node.Members()) == 0 {
+        return fmt.Errorf("no delayed replication factor for %v", node.Descriptor())
+    }
     if err := node.Members()[0].Accept(v); err != nil {
         return errors.Wrap(err, "cannot process delayed replication factor")
     }
@@ -157,7 +175,14 @@ func (v *DesVisitor) VisitDelayedReplicationNode(node *ast.DelayedReplicationNod
     if err != nil {
         return errors.Wrap(err, "cannot get delayed replication factor value as uint")
     }
+    if v.config.MaxReplications > 0 && nreplications > uint(v.config.MaxReplications) {
+        return fmt.Errorf("delayed replication factor exceeds the limit of %v: %v",
+            v.config.MaxReplications, nreplications)
+    }
     for i := uint(0); i < nreplications; i++ { // loop of replication
+        if err := v.ctx.Err(); err != nil {
+            return err
+        }
         if err := buildBlock(v, node.Members()[1:]); err != nil {
             return errors.Wrap(err, "cannot process delayed replication")
         }
@@ -218,7 +243,9 @@ func (v *DesVisitor) VisitOpNewRefvalNode(node *ast.OpNewRefvalNode) error {
 func (v *DesVisitor) VisitOpAssocFieldNode(node *ast.OpAssocFieldNode) error {
     v.treeBuilder.Add(&bufr.ValuelessNode{Descriptor: node.Descriptor()})
     if node.Descriptor().Y() == 0 {
-        v.assocPairs.Pop()
+        if err := v.assocPairs.Pop(); err != nil {
+            return err
+        }
     } else {
         v.assocPairs.Push(node.Descriptor().Y())
     }
@@ -232,7 +259,8 @@ func (v *DesVisitor) VisitOpAssocFieldNode(node *ast.OpAssocFieldNode) error {
 
 func (v *DesVisitor) VisitOpInsertStringNode(node *ast.OpInsertStringNode) error {
     // Decide this should not process associated fields
-    info := &bufr.PackingInfo{Unit: table.STRING, Nbits: node.Descriptor().Y() * tdcfio.NBITS_PER_BYTE}
+    info := &bufr.PackingInfo{Unit: table.STRING, UnitString: "CCITT IA5",
+        Nbits: node.Descriptor().Y() * tdcfio.NBITS_PER_BYTE}
     _, err := buildValuedNodeWithInfo(v, node.Descriptor(), info)
     return err
 }
@@ -240,6 +268,9 @@ func (v *DesVisitor) VisitOpInsertStringNode(node *ast.OpInsertStringNode) error
 func (v *DesVisitor) VisitOpSkipLocalNode(node *ast.OpSkipLocalNode) error {
     // Decide this should not process associated fields
     v.treeBuilder.Add(&bufr.ValuelessNode{Descriptor: node.Descriptor()})
+    if len(node.Members()) == 0 {
+        return fmt.Errorf("no local descriptor for %v", node.Descriptor())
+    }
     info := &bufr.PackingInfo{Unit: table.BINARY, Nbits: node.Descriptor().Y()}
     _, err := buildValuedNodeWithInfo(v, node.Members()[0].Descriptor(), info)
     return err
@@ -305,6 +336,10 @@ func (v *DesVisitor) VisitOpAssessmentNode(node *ast.OpAssessmentNode) error {
     if err != nil {
         return errors.Wrap(err, "cannot deserialize bitmapping source nodes")
     }
+    if len(snodes) > len(targetNodes) {
+        return fmt.Errorf("more bitmapping source values than target values: %v > %v",
+            len(snodes), len(targetNodes))
+    }
     for i, snode := range snodes {
         targetNodes[i].AddMember(snode)
         for _, anode := range anodes {
@@ -315,8 +350,13 @@ func (v *DesVisitor) VisitOpAssessmentNode(node *ast.OpAssessmentNode) error {
 }
 
 func (v *DesVisitor) VisitOpMarkerNode(node *ast.OpMarkerNode) error {
-    targetNode := v.bitmapManager.NextTargetNode()
-    fmt.Println("target node ", targetNode.Descriptor)
+    targetNode, err := v.bitmapManager.NextTargetNode()
+    if err != nil {
+        return err
+    }
+    if v.config.Verbose {
+        fmt.Println("target node ", targetNode.Descriptor)
+    }
     packingInfo, err := calcPackingInfo(v, targetNode.Descriptor)
     if err != nil {
         return err
@@ -343,10 +383,10 @@ func (v *DesVisitor) VisitOpCancelBitmapNode(node *ast.OpCancelBitmapNode) error
 func (v *DesVisitor) VisitBitmapNode(node *ast.BitmapNode) error {
     if node.Descriptor() != nil {
         buildZeroNode(v, node.Descriptor())
-    }
-    if node.Descriptor().Id() == table.ID_237000 {
-        v.bitmapManager.RecallBitmap()
-        return nil
+        if node.Descriptor().Id() == table.ID_237000 {
+            v.bitmapManager.RecallBitmap()
+            return nil
+        }
     }
     v.bitmapManager.NewBitmap(node.Descriptor() != nil)
     defer v.bitmapManager.EndBitmap()
@@ -372,3 +412,23 @@ func (v *DesVisitor) visitNodesForValuedNodes(astNodes []ast.Node) ([]*bufr.Valu
     }
     return nodes, nil
 }
+
+// countCells counts a new cell for each subset and checks against the limit
+func (v *DesVisitor) countCells() error {
+    if v.config.Compressed && v.config.InputType == tdcfio.BinaryInput {
+        v.ncells += v.nsubsets
+    } else {
+        v.ncells += 1
+    }
+    return v.checkCells()
+}
+
+// checkCells checks the numbers of cells and nodes built so far against the limit.
+// Nodes are counted as well since replications of valueless nodes, e.g. operators,
+// grow the tree without adding any cell.
+func (v *DesVisitor) checkCells() error {
+    if v.config.MaxCells > 0 && v.ncells+v.treeBuilder.Len() > v.config.MaxCells {
+        return fmt.Errorf("number of values and nodes exceeds the limit of %v", v.config.MaxCells)
+    }
+    return nil
+}
@@ -9,9 +9,12 @@ import (
 )
 
 func calcPackingInfo(v *DesVisitor, descriptor table.Descriptor) (*bufr.PackingInfo, error) {
-    entry := descriptor.Entry().(*table.Bentry)
+    entry, ok := descriptor.Entry().(*table.Bentry)
+    if !ok {
+        return nil, fmt.Errorf("no element entry for %v", descriptor)
+    }
 
-    info := &bufr.PackingInfo{Unit: entry.Unit}
+    info := &bufr.PackingInfo{Unit: entry.Unit, UnitString: entry.UnitString}
 
     switch entry.Unit {
     case table.STRING:
@@ -48,5 +51,8 @@ func calcPackingInfo(v *DesVisitor, descriptor table.Descriptor) (*bufr.PackingI
         info.Nbits = entry.Nbits
     }
 
+    if info.Nbits < 0 {
+        return nil, fmt.Errorf("negative number of bits for %v: %v", descriptor, info.Nbits)
+    }
     return info, nil
 }
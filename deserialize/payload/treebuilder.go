@@ -12,6 +12,8 @@ type TreeBuilder struct {
 
     node  bufr.Node
     stack []bufr.Node
+    // Number of nodes added or pushed so far
+    nnodes int
 }
 
 func NewTreeBuilder(node bufr.Node) *TreeBuilder {
@@ -28,6 +30,7 @@ func (tb *TreeBuilder) Add(node bufr.Node) {
         fmt.Println(node)
     }
     tb.node.AddMember(node)
+    tb.nnodes++
 }
 
 // Add given node as a child to the current parent node and push it as the new parent
@@ -39,6 +42,8 @@ func (tb *TreeBuilder) Push(node bufr.Node) {
     if debug.DEBUG {
         fmt.Println(len(tb.stack), node)
     }
+    tb.node.AddMember(node)
+    tb.nnodes++
     tb.stack = append(tb.stack, tb.node)
     tb.node = node
     if debug.DEBUG {
@@ -61,6 +66,11 @@ func (tb *TreeBuilder) Pop() {
     }
 }
 
+// Len returns the number of nodes added or pushed so far
+func (tb *TreeBuilder) Len() int {
+    return tb.nnodes
+}
+
 func (tb *TreeBuilder) Root() (bufr.Node, error) {
     if len(tb.stack) != 0 {
         return nil, fmt.Errorf("tree builder stack is not empty when root node is required")
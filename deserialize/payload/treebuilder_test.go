@@ -0,0 +1,35 @@
+package payload
+
+import (
+    "testing"
+    "github.com/ywangd/gobufrkit/bufr"
+)
+
+func TestTreeBuilder_Push(t *testing.T) {
+    tb := NewTreeBuilder(bufr.NewBlock())
+    sequence := &bufr.ValuelessNode{}
+    replication := &bufr.ValuelessNode{}
+    factor := &bufr.ValuedNode{Index: 0}
+    value := &bufr.ValuedNode{Index: 1}
+
+    tb.Push(sequence)
+    tb.Push(replication)
+    tb.Add(factor)
+    tb.Add(value)
+    tb.Pop()
+    tb.Pop()
+
+    root, err := tb.Root()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if members := root.Members(); len(members) != 1 || members[0] != sequence {
+        t.Fatalf("root members = %v, want the sequence node", members)
+    }
+    if members := sequence.Members(); len(members) != 1 || members[0] != replication {
+        t.Fatalf("sequence members = %v, want the replication node", members)
+    }
+    if members := replication.Members(); len(members) != 2 || members[0] != factor || members[1] != value {
+        t.Fatalf("replication members = %v, want the factor and value nodes", members)
+    }
+}
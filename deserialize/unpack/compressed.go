@@ -171,7 +171,7 @@ func (up *CompressedBitUnpacker) unpackCode(info *bufr.PackingInfo) (interface{}
                 ret.Values[i] = nil
             } else {
                 // TODO: in theory the uint diff could be out of the int range
-                ret.Values[i] = ret.Values[i].(int) + int(diff)
+                ret.Values[i] = ret.MinValue.(int) + int(diff)
             }
         }
     }
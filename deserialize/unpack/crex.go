@@ -0,0 +1,100 @@
+package unpack
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "github.com/pkg/errors"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/table"
+    "github.com/ywangd/gobufrkit/tdcfio"
+    "github.com/ywangd/gobufrkit/units"
+)
+
+// CrexUnpacker unpacks values from CREX messages. Values are given in the units,
+// scales and widths of the CREX columns of Table B. Numeric values are converted
+// to the units and precision of BUFR so that the values are the same as the ones
+// of the equivalent BUFR message. Flag values are given as octal numbers.
+type CrexUnpacker struct {
+    r *tdcfio.CrexReader
+}
+
+func (up *CrexUnpacker) Unpack(info *bufr.PackingInfo) (interface{}, error) {
+    return nil, fmt.Errorf("CREX values cannot be unpacked without their descriptors")
+}
+
+func (up *CrexUnpacker) UnpackDescriptor(descriptor table.Descriptor, info *bufr.PackingInfo) (interface{}, error) {
+    ci, err := units.NewCrexInfo(descriptor, info)
+    if err != nil {
+        return nil, err
+    }
+    nbits := ci.Nchars * tdcfio.NBITS_PER_BYTE
+
+    missing, err := up.r.ReadMissing(nbits)
+    if err != nil {
+        return nil, err
+    }
+    if info.Unit == table.STRING {
+        if missing {
+            return missingBytes(info.Nbits / tdcfio.NBITS_PER_BYTE), nil
+        }
+        return up.r.ReadBytes(ci.Nchars)
+    }
+    if missing {
+        return nil, nil
+    }
+
+    s, err := up.r.ReadGroup(nbits)
+    if err != nil {
+        return nil, err
+    }
+    switch info.Unit {
+    case table.CODE:
+        x, err := strconv.ParseInt(s, 10, 64)
+        return int(x), valueError(err, descriptor, s)
+
+    case table.NONNEG_CODE:
+        x, err := strconv.ParseUint(s, 10, 64)
+        return uint(x), valueError(err, descriptor, s)
+
+    case table.FLAG:
+        x, err := strconv.ParseUint(s, 8, 64)
+        return uint(x), valueError(err, descriptor, s)
+
+    case table.NUMERIC:
+        x, err := strconv.ParseInt(s, 10, 64)
+        if err != nil {
+            return nil, valueError(err, descriptor, s)
+        }
+        v := float64(x) / math.Pow10(ci.Scale)
+        c, err := units.CrexConversion(ci.UnitString, info.UnitString)
+        if err != nil {
+            return nil, errors.Wrapf(err, "cannot convert value of %v", descriptor.Id())
+        }
+        if c != nil {
+            v = c.Convert(v)
+        }
+        // Same precision as values unpacked from BUFR
+        p := math.Pow10(info.Scale)
+        return math.Round(v*p) / p, nil
+
+    default:
+        return nil, fmt.Errorf("unsupported unit for CREX: %v", info.Unit)
+    }
+}
+
+// missingBytes returns a missing character value of n bytes, i.e. all bits set
+func missingBytes(n int) []byte {
+    b := make([]byte, n)
+    for i := range b {
+        b[i] = 0xff
+    }
+    return b
+}
+
+func valueError(err error, descriptor table.Descriptor, s string) error {
+    if err != nil {
+        return fmt.Errorf("invalid value of %v: %q", descriptor.Id(), s)
+    }
+    return nil
+}
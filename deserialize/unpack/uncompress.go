@@ -69,3 +69,93 @@ func (up *JsonUnpacker) Unpack(info *bufr.PackingInfo) (interface{}, error) {
         return nil, fmt.Errorf("unrecognised unit: %v", info.Unit)
     }
 }
+
+// TextUnpacker unpacks values from text dumps, where missing values are given as <nil>
+type TextUnpacker struct {
+    r *tdcfio.FlatTextReader
+}
+
+func (up *TextUnpacker) Unpack(info *bufr.PackingInfo) (interface{}, error) {
+    missing, err := up.r.ReadMissing(info.Nbits)
+    if err != nil || missing {
+        return nil, err
+    }
+    return (&JsonUnpacker{r: up.r}).Unpack(info)
+}
+
+// ValueUnpacker unpacks values given in memory. Unlike other unpackers, a nil
+// value is accepted as missing value for all units.
+type ValueUnpacker struct {
+    r *tdcfio.ValueReader
+}
+
+func (up *ValueUnpacker) Unpack(info *bufr.PackingInfo) (interface{}, error) {
+    // Zero bits data has no corresponding value, same as binary input
+    if (info.Unit == table.NONNEG_CODE || info.Unit == table.FLAG) && info.Nbits == 0 {
+        return uint(0), nil
+    }
+
+    v, err := up.r.ReadValue(info.Nbits)
+    if err != nil {
+        return nil, err
+    }
+
+    switch info.Unit {
+    case table.STRING:
+        nbytes := info.Nbits / tdcfio.NBITS_PER_BYTE
+        if v == nil {
+            b := make([]byte, nbytes)
+            for i := range b {
+                b[i] = 0xff
+            }
+            return b, nil
+        }
+        b, err := tdcfio.ValueToBytes(v)
+        if err != nil {
+            return nil, err
+        }
+        if len(b) > nbytes {
+            return nil, fmt.Errorf("string longer than %v bytes: %q", nbytes, b)
+        }
+        // Pad with spaces to the full width
+        for len(b) < nbytes {
+            b = append(b, ' ')
+        }
+        return b, nil
+
+    case table.CODE:
+        if v == nil {
+            return nil, nil
+        }
+        x, err := tdcfio.ValueToFloat64(v)
+        if err != nil {
+            return nil, err
+        }
+        return int(x), nil
+
+    case table.NONNEG_CODE, table.FLAG:
+        if v == nil {
+            return nil, nil
+        }
+        x, err := tdcfio.ValueToFloat64(v)
+        if err != nil {
+            return nil, err
+        }
+        if x < 0 {
+            return nil, fmt.Errorf("code or flag value is negative: %v", x)
+        }
+        return uint(x), nil
+
+    case table.NUMERIC:
+        if v == nil {
+            return nil, nil
+        }
+        return tdcfio.ValueToFloat64(v)
+
+    case table.BINARY:
+        return tdcfio.ValueToBinary(v)
+
+    default:
+        return nil, fmt.Errorf("unrecognised unit: %v", info.Unit)
+    }
+}
@@ -5,12 +5,19 @@ import (
     "math"
     "fmt"
     "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/table"
 )
 
 type Unpacker interface {
     Unpack(info *bufr.PackingInfo) (interface{}, error)
 }
 
+// DescriptorUnpacker is implemented by unpackers that need the descriptor of a value
+// in addition to its packing info, e.g. to look up the CREX columns of Table B.
+type DescriptorUnpacker interface {
+    UnpackDescriptor(descriptor table.Descriptor, info *bufr.PackingInfo) (interface{}, error)
+}
+
 // NewUnpacker returns an appropriate Unpacker implementation based on given reader type
 func NewUnpacker(reader tdcfio.Reader, nsubsets int, compressed bool, inputType tdcfio.InputType) (Unpacker, error) {
     switch inputType {
@@ -21,6 +28,24 @@ func NewUnpacker(reader tdcfio.Reader, nsubsets int, compressed bool, inputType
         return &UncompressBitUnpacker{r: reader}, nil
     case tdcfio.FlatJsonInput:
         return &JsonUnpacker{r: reader}, nil
+    case tdcfio.FlatTextInput:
+        tr, ok := reader.(*tdcfio.FlatTextReader)
+        if !ok {
+            return nil, fmt.Errorf("flat text input requires a flat text reader: %T", reader)
+        }
+        return &TextUnpacker{r: tr}, nil
+    case tdcfio.CrexInput:
+        cr, ok := reader.(*tdcfio.CrexReader)
+        if !ok {
+            return nil, fmt.Errorf("CREX input requires a CREX reader: %T", reader)
+        }
+        return &CrexUnpacker{r: cr}, nil
+    case tdcfio.ValueInput:
+        vr, ok := reader.(*tdcfio.ValueReader)
+        if !ok {
+            return nil, fmt.Errorf("value input requires a value reader: %T", reader)
+        }
+        return &ValueUnpacker{r: vr}, nil
     default:
         return nil, fmt.Errorf("no candidate unpacker for %v", inputType)
     }
@@ -0,0 +1,298 @@
+// Package diff compares decoded messages semantically. Header fields are aligned
+// by section number and field name, and subset values are aligned by their
+// descriptor path in the hierarchical node tree, e.g.
+//   subset 2/301011/004001
+//   subset 2/101000[3]/012101
+//   subset 2/222000/031002/033007@1
+// where [n] is the n-th repetition of a replication and @n is the n-th attribute
+// of a value, e.g. an associated field.
+package diff
+
+import (
+    "fmt"
+    "math"
+    "strings"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/table"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+// Kinds of differences
+const (
+    // Values at the same path are different
+    ValueDifference = "value"
+    // Structures are different, e.g. templates, replication counts or missing attributes
+    StructureDifference = "structure"
+)
+
+// Options controls how values are compared
+type Options struct {
+    // Maximum absolute difference for numbers to be considered equal
+    Tolerance float64
+}
+
+// Difference describes a single difference between two messages
+type Difference struct {
+    // One based number of the message in the inputs
+    Message     int         `json:"message"`
+    Path        string      `json:"path"`
+    Kind        string      `json:"kind"`
+    Description string      `json:"description,omitempty"`
+    A           interface{} `json:"a"`
+    B           interface{} `json:"b"`
+}
+
+func (d *Difference) String() string {
+    s := fmt.Sprintf("message %v: %v: %v != %v", d.Message, d.Path, format(d.A), format(d.B))
+    if d.Description != "" {
+        s = fmt.Sprintf("message %v: %v: %v: %v != %v",
+            d.Message, d.Path, d.Description, format(d.A), format(d.B))
+    }
+    return s
+}
+
+// CompareAll compares two lists of messages aligned by their order
+func CompareAll(as, bs []*bufr.Message, options *Options) []*Difference {
+    var diffs []*Difference
+    for i := 0; i < len(as) || i < len(bs); i++ {
+        switch {
+        case i >= len(as):
+            diffs = append(diffs, &Difference{Message: i + 1, Path: "message",
+                Kind: StructureDifference, Description: "message presence", A: false, B: true})
+        case i >= len(bs):
+            diffs = append(diffs, &Difference{Message: i + 1, Path: "message",
+                Kind: StructureDifference, Description: "message presence", A: true, B: false})
+        default:
+            for _, d := range Compare(as[i], bs[i], options) {
+                d.Message = i + 1
+                diffs = append(diffs, d)
+            }
+        }
+    }
+    return diffs
+}
+
+// Compare compares two messages. Message numbers of the returned differences are zero.
+func Compare(a, b *bufr.Message, options *Options) []*Difference {
+    c := &comparer{options: options}
+    c.compareMessages(a, b)
+    return c.diffs
+}
+
+type comparer struct {
+    options *Options
+    diffs   []*Difference
+}
+
+func (c *comparer) add(path, kind, description string, a, b interface{}) {
+    c.diffs = append(c.diffs, &Difference{
+        Path: path, Kind: kind, Description: description, A: jsonValue(a), B: jsonValue(b)})
+}
+
+func (c *comparer) compareMessages(a, b *bufr.Message) {
+    sectionsB := map[int]*bufr.Section{}
+    for _, s := range b.Sections() {
+        sectionsB[s.Number()] = s
+    }
+    for _, sa := range a.Sections() {
+        sb, ok := sectionsB[sa.Number()]
+        path := fmt.Sprintf("section %v", sa.Number())
+        if !ok {
+            c.add(path, StructureDifference, "section presence", true, false)
+            continue
+        }
+        delete(sectionsB, sa.Number())
+        c.compareSections(path, sa, sb)
+    }
+    for _, sb := range b.Sections() {
+        if _, ok := sectionsB[sb.Number()]; ok {
+            c.add(fmt.Sprintf("section %v", sb.Number()), StructureDifference, "section presence", false, true)
+        }
+    }
+}
+
+func (c *comparer) compareSections(path string, a, b *bufr.Section) {
+    keysA, fieldsA := fieldsByKey(a)
+    keysB, fieldsB := fieldsByKey(b)
+
+    var templateDiffers bool
+    for _, key := range keysA {
+        fa, fb := fieldsA[key], fieldsB[key]
+        fpath := path + "/" + key
+        if fb == nil {
+            c.add(fpath, StructureDifference, "field presence", true, false)
+            continue
+        }
+
+        switch va := fa.Value.(type) {
+        case *table.UnexpandedTemplate:
+            vb, ok := fb.Value.(*table.UnexpandedTemplate)
+            if !ok || va.String() != vb.String() {
+                templateDiffers = true
+                c.add(fpath, StructureDifference, "template", va, fb.Value)
+            }
+        case *bufr.Payload:
+            vb, ok := fb.Value.(*bufr.Payload)
+            if !ok {
+                c.add(fpath, StructureDifference, "payload presence", true, false)
+            } else if !templateDiffers {
+                c.comparePayloads(va, vb)
+            }
+        default:
+            if !c.equal(fa.Value, fb.Value) {
+                c.add(fpath, ValueDifference, "", fa.Value, fb.Value)
+            }
+        }
+    }
+    for _, key := range keysB {
+        if fieldsA[key] == nil {
+            c.add(path+"/"+key, StructureDifference, "field presence", false, true)
+        }
+    }
+}
+
+// fieldsByKey returns fields of a section keyed by their names. Repeated names,
+// e.g. spare bits, are suffixed with their occurrences, e.g. spareBits#2.
+func fieldsByKey(section *bufr.Section) ([]string, map[string]*bufr.Field) {
+    var keys []string
+    fields := map[string]*bufr.Field{}
+    occurrences := map[string]int{}
+    for _, field := range section.Fields() {
+        occurrences[field.Name]++
+        key := field.Name
+        if n := occurrences[field.Name]; n > 1 {
+            key = fmt.Sprintf("%v#%v", key, n)
+        }
+        keys = append(keys, key)
+        fields[key] = field
+    }
+    return keys, fields
+}
+
+func (c *comparer) comparePayloads(a, b *bufr.Payload) {
+    subsetsA, subsetsB := a.Subsets(), b.Subsets()
+    if len(subsetsA) != len(subsetsB) {
+        c.add("payload", StructureDifference, "number of subsets", len(subsetsA), len(subsetsB))
+    }
+    for i := 0; i < len(subsetsA) && i < len(subsetsB); i++ {
+        sa, sb := subsetsA[i], subsetsB[i]
+        c.compareNodes(fmt.Sprintf("subset %v", i+1), sa, sb, sa.Root(), sb.Root())
+    }
+}
+
+// compareNodes compares members of two nodes which are at the same path
+func (c *comparer) compareNodes(path string, sa, sb *bufr.Subset, a, b bufr.Node) {
+    ma, mb := a.Members(), b.Members()
+    if len(ma) != len(mb) {
+        description := "number of members"
+        if vn, ok := a.(*bufr.ValuelessNode); ok && vn.Descriptor.F() == table.F_REPLICATION {
+            description = "replication count"
+        }
+        c.add(path, StructureDifference, description, len(ma), len(mb))
+    }
+
+    occurrences := map[string]int{}
+    nblocks := 0
+    for i := 0; i < len(ma) && i < len(mb); i++ {
+        var name string
+        if _, ok := ma[i].(*bufr.Block); ok {
+            nblocks++
+            name = fmt.Sprintf("%v[%v]", path, nblocks)
+        } else {
+            id := nodeId(ma[i])
+            occurrences[id]++
+            name = path + "/" + id
+            if n := occurrences[id]; n > 1 {
+                name = fmt.Sprintf("%v#%v", name, n)
+            }
+        }
+        if nodeId(ma[i]) != nodeId(mb[i]) {
+            c.add(name, StructureDifference, "descriptor", nodeId(ma[i]), nodeId(mb[i]))
+            continue
+        }
+
+        na, okA := ma[i].(*bufr.ValuedNode)
+        nb, okB := mb[i].(*bufr.ValuedNode)
+        switch {
+        case okA != okB:
+            c.add(name, StructureDifference, "value presence", okA, okB)
+        case okA:
+            va, vb := sa.Cell(na.Index).Value(), sb.Cell(nb.Index).Value()
+            if !c.equal(va, vb) {
+                c.add(name, ValueDifference, "", va, vb)
+            }
+            c.compareAttributes(name, sa, sb, na, nb)
+        default:
+            c.compareNodes(name, sa, sb, ma[i], mb[i])
+        }
+    }
+}
+
+// compareAttributes compares values of the attribute members of two valued nodes
+func (c *comparer) compareAttributes(path string, sa, sb *bufr.Subset, a, b *bufr.ValuedNode) {
+    ma, mb := a.Members(), b.Members()
+    for i := 0; i < len(ma) || i < len(mb); i++ {
+        name := fmt.Sprintf("%v@%v", path, i+1)
+        if i >= len(ma) || i >= len(mb) {
+            c.add(name, StructureDifference, "attribute presence", i < len(ma), i < len(mb))
+            continue
+        }
+        na, okA := ma[i].(*bufr.ValuedNode)
+        nb, okB := mb[i].(*bufr.ValuedNode)
+        if !okA || !okB {
+            continue
+        }
+        if nodeId(na) != nodeId(nb) {
+            c.add(name, StructureDifference, "descriptor", nodeId(na), nodeId(nb))
+            continue
+        }
+        va, vb := sa.Cell(na.Index).Value(), sb.Cell(nb.Index).Value()
+        if !c.equal(va, vb) {
+            c.add(name, ValueDifference, "", va, vb)
+        }
+    }
+}
+
+// equal compares two values with the numeric tolerance
+func (c *comparer) equal(a, b interface{}) bool {
+    if a == nil || b == nil {
+        return a == nil && b == nil
+    }
+    if xa, err := tdcfio.ValueToFloat64(a); err == nil {
+        xb, err := tdcfio.ValueToFloat64(b)
+        return err == nil && math.Abs(xa-xb) <= c.options.Tolerance
+    }
+    return fmt.Sprint(jsonValue(a)) == fmt.Sprint(jsonValue(b))
+}
+
+func nodeId(node bufr.Node) string {
+    switch n := node.(type) {
+    case *bufr.ValuedNode:
+        return n.Descriptor.Id().String()
+    case *bufr.ValuelessNode:
+        return n.Descriptor.Id().String()
+    case *bufr.Block:
+        return "block"
+    }
+    return fmt.Sprintf("%T", node)
+}
+
+// jsonValue converts values to a readable form, e.g. strings instead of bytes
+func jsonValue(v interface{}) interface{} {
+    switch v := v.(type) {
+    case []byte:
+        return strings.TrimRight(string(v), " \x00")
+    case *tdcfio.Binary:
+        return v.String()
+    case *table.UnexpandedTemplate:
+        return v.String()
+    }
+    return v
+}
+
+func format(v interface{}) string {
+    if s, ok := v.(string); ok {
+        return fmt.Sprintf("%q", s)
+    }
+    return fmt.Sprint(v)
+}
@@ -0,0 +1,54 @@
+package diff
+
+import (
+    "testing"
+    "strings"
+    assert2 "github.com/seanpont/assert"
+    "github.com/ywangd/gobufrkit/builder"
+    "github.com/ywangd/gobufrkit/bufr"
+)
+
+func buildMessage(t *testing.T, temperatures []interface{}, compressed bool) *bufr.Message {
+    assert := assert2.Assert(t)
+    b := builder.NewBuilder("../_definitions/tables")
+    assert.Nil(b.Set("masterTableVersion", 25))
+    b.SetTemplate(301011, 101000, 31001, 12101)
+    b.AddSubset(2018, 1, 2, temperatures)
+    b.SetCompressed(compressed)
+    message, err := b.Build()
+    assert.Nil(err)
+    return message
+}
+
+func TestCompare(t *testing.T) {
+    assert := assert2.Assert(t)
+    options := &Options{Tolerance: 0.05}
+
+    a := buildMessage(t, []interface{}{273.15, 274.15}, false)
+    // Compression changes only the header
+    diffs := Compare(a, buildMessage(t, []interface{}{273.15, 274.15}, true), options)
+    for _, d := range diffs {
+        assert.True(strings.HasPrefix(d.Path, "section"))
+    }
+    assert.True(len(diffs) > 0)
+
+    // Within tolerance
+    diffs = Compare(a, buildMessage(t, []interface{}{273.15, 274.17}, false), options)
+    assert.Equal(len(diffs), 0)
+
+    diffs = Compare(a, buildMessage(t, []interface{}{273.15, nil}, false), options)
+    assert.Equal(len(diffs), 1)
+    assert.Equal(diffs[0].Kind, ValueDifference)
+    assert.Equal(diffs[0].Path, "subset 1/101000[2]/012101")
+
+    diffs = CompareAll([]*bufr.Message{a}, []*bufr.Message{
+        buildMessage(t, []interface{}{273.15, 274.15, 275.15}, false)}, options)
+    var paths []string
+    for _, d := range diffs {
+        if d.Kind == StructureDifference {
+            paths = append(paths, d.Path)
+            assert.Equal(d.Description, "replication count")
+        }
+    }
+    assert.Equal(paths, []string{"subset 1/101000"})
+}
@@ -0,0 +1,163 @@
+// Package filter provides predicate expressions for selecting messages by their
+// header fields, i.e. proxy fields of sections 0 to 3, e.g.
+//   dataCategory == 0 && originatingCentre in (98, 7)
+//   typicalDate >= "2012-11-01" && nSubsets > 100
+//
+// Supported operators are ==, !=, <, <=, >, >=, in, &&, || and ! with parentheses
+// for grouping. Values can be numbers, quoted strings, true or false.
+package filter
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Lookup returns the value of the named field
+type Lookup func(name string) (interface{}, error)
+
+// Expr is a parsed predicate expression
+type Expr interface {
+    Eval(lookup Lookup) (bool, error)
+}
+
+type notExpr struct {
+    x Expr
+}
+
+func (e *notExpr) Eval(lookup Lookup) (bool, error) {
+    v, err := e.x.Eval(lookup)
+    return !v, err
+}
+
+type andExpr struct {
+    x, y Expr
+}
+
+func (e *andExpr) Eval(lookup Lookup) (bool, error) {
+    v, err := e.x.Eval(lookup)
+    if err != nil || !v {
+        return false, err
+    }
+    return e.y.Eval(lookup)
+}
+
+type orExpr struct {
+    x, y Expr
+}
+
+func (e *orExpr) Eval(lookup Lookup) (bool, error) {
+    v, err := e.x.Eval(lookup)
+    if err != nil || v {
+        return v, err
+    }
+    return e.y.Eval(lookup)
+}
+
+// compareExpr compares a field with a literal value
+type compareExpr struct {
+    name  string
+    op    string
+    value interface{}
+}
+
+func (e *compareExpr) Eval(lookup Lookup) (bool, error) {
+    v, err := lookup(e.name)
+    if err != nil {
+        return false, err
+    }
+    c, err := compare(v, e.value)
+    if err != nil {
+        return false, fmt.Errorf("cannot compare %v: %v", e.name, err)
+    }
+    switch e.op {
+    case "==":
+        return c == 0, nil
+    case "!=":
+        return c != 0, nil
+    case "<":
+        return c < 0, nil
+    case "<=":
+        return c <= 0, nil
+    case ">":
+        return c > 0, nil
+    case ">=":
+        return c >= 0, nil
+    }
+    return false, fmt.Errorf("unknown operator: %v", e.op)
+}
+
+// inExpr checks whether a field equals to any of a list of literal values
+type inExpr struct {
+    name   string
+    values []interface{}
+}
+
+func (e *inExpr) Eval(lookup Lookup) (bool, error) {
+    v, err := lookup(e.name)
+    if err != nil {
+        return false, err
+    }
+    for _, value := range e.values {
+        c, err := compare(v, value)
+        if err != nil {
+            return false, fmt.Errorf("cannot compare %v: %v", e.name, err)
+        }
+        if c == 0 {
+            return true, nil
+        }
+    }
+    return false, nil
+}
+
+// compare returns -1, 0 or 1 if the field value is less than, equal to or greater
+// than the literal value, which is either float64, string or bool. Booleans are
+// only compared for equality, so any different boolean is reported as greater.
+func compare(v interface{}, literal interface{}) (int, error) {
+    switch literal := literal.(type) {
+    case float64:
+        x, ok := toFloat64(v)
+        if !ok {
+            return 0, fmt.Errorf("not a number: %v", v)
+        }
+        switch {
+        case x < literal:
+            return -1, nil
+        case x > literal:
+            return 1, nil
+        }
+        return 0, nil
+    case string:
+        var s string
+        switch v := v.(type) {
+        case string:
+            s = v
+        case []byte:
+            s = strings.TrimRight(string(v), " \x00")
+        default:
+            return 0, fmt.Errorf("not a string: %v", v)
+        }
+        return strings.Compare(s, literal), nil
+    case bool:
+        b, ok := v.(bool)
+        if !ok {
+            return 0, fmt.Errorf("not a boolean: %v", v)
+        }
+        if b == literal {
+            return 0, nil
+        }
+        return 1, nil
+    }
+    return 0, fmt.Errorf("unsupported literal: %v", literal)
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+    switch v := v.(type) {
+    case uint:
+        return float64(v), true
+    case int:
+        return float64(v), true
+    case float64:
+        return v, true
+    }
+    return 0, false
+}
@@ -0,0 +1,50 @@
+package filter
+
+import (
+    "testing"
+    assert2 "github.com/seanpont/assert"
+    "fmt"
+)
+
+func TestParseAndEval(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    fields := map[string]interface{}{
+        "dataCategory":      uint(0),
+        "originatingCentre": uint(98),
+        "nSubsets":          uint(128),
+        "isCompressed":      true,
+        "typicalDate":       "2012-11-02",
+    }
+    lookup := func(name string) (interface{}, error) {
+        v, ok := fields[name]
+        if !ok {
+            return nil, fmt.Errorf("unknown field: %v", name)
+        }
+        return v, nil
+    }
+
+    for s, expected := range map[string]bool{
+        "dataCategory==0 && originatingCentre in (98,7)":              true,
+        "dataCategory==0 && originatingCentre in (7, 74)":             false,
+        "nSubsets>100":                                                true,
+        "!(nSubsets <= 100) || dataCategory != 0":                     true,
+        "typicalDate >= '2012-11-01' && typicalDate < \"2012-11-03\"": true,
+        "isCompressed == false":                                       false,
+    } {
+        expr, err := Parse(s)
+        assert.Nil(err)
+        v, err := expr.Eval(lookup)
+        assert.Nil(err)
+        assert.Equal(v, expected)
+    }
+
+    for _, s := range []string{"dataCategory", "dataCategory == ", "(nSubsets > 1", "nSubsets ~ 1", "0 == 0"} {
+        _, err := Parse(s)
+        assert.NotNil(err)
+    }
+
+    expr, _ := Parse("noSuchField == 1")
+    _, err := expr.Eval(lookup)
+    assert.NotNil(err)
+}
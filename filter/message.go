@@ -0,0 +1,30 @@
+package filter
+
+import (
+    "fmt"
+    "github.com/ywangd/gobufrkit/bufr"
+)
+
+// MessageLookup returns a Lookup for the proxy fields of the given message.
+// The typical date and time of section 1 are also available as ISO 8601 strings
+// named typicalDateTime, e.g. 2012-11-02T00:30:00, and typicalDate, e.g. 2012-11-02.
+func MessageLookup(message *bufr.Message) Lookup {
+    return func(name string) (interface{}, error) {
+        switch name {
+        case "typicalDateTime":
+            return message.TypicalDateTime(), nil
+        case "typicalDate":
+            return message.TypicalDateTime()[:len("2006-01-02")], nil
+        }
+        field, err := message.ProxyField(name)
+        if err != nil {
+            return nil, fmt.Errorf("unknown field: %v", name)
+        }
+        return field.Value, nil
+    }
+}
+
+// Match checks whether the message satisfies the expression
+func Match(expr Expr, message *bufr.Message) (bool, error) {
+    return expr.Eval(MessageLookup(message))
+}
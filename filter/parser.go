@@ -0,0 +1,244 @@
+package filter
+
+import (
+    "fmt"
+    "strconv"
+    "unicode"
+)
+
+// Token kinds
+const (
+    tokenEOF    = iota
+    tokenIdent
+    tokenNumber
+    tokenString
+    tokenOp
+)
+
+type token struct {
+    kind int
+    text string
+}
+
+// tokenize splits an expression into tokens
+func tokenize(s string) ([]token, error) {
+    var tokens []token
+    rs := []rune(s)
+    for i := 0; i < len(rs); {
+        r := rs[i]
+        switch {
+        case unicode.IsSpace(r):
+            i++
+
+        case unicode.IsLetter(r) || r == '_':
+            j := i
+            for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_') {
+                j++
+            }
+            tokens = append(tokens, token{tokenIdent, string(rs[i:j])})
+            i = j
+
+        case unicode.IsDigit(r) || r == '-' || r == '.':
+            j := i + 1
+            for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.' || rs[j] == 'e' || rs[j] == 'E' ||
+                (rs[j] == '-' || rs[j] == '+') && (rs[j-1] == 'e' || rs[j-1] == 'E')) {
+                j++
+            }
+            tokens = append(tokens, token{tokenNumber, string(rs[i:j])})
+            i = j
+
+        case r == '"' || r == '\'':
+            j := i + 1
+            for j < len(rs) && rs[j] != r {
+                j++
+            }
+            if j == len(rs) {
+                return nil, fmt.Errorf("unterminated string at %v", i)
+            }
+            tokens = append(tokens, token{tokenString, string(rs[i+1:j])})
+            i = j + 1
+
+        default:
+            op := ""
+            if i+1 < len(rs) {
+                switch two := string(rs[i:i+2]); two {
+                case "==", "!=", "<=", ">=", "&&", "||":
+                    op = two
+                }
+            }
+            if op == "" {
+                switch r {
+                case '<', '>', '!', '(', ')', ',':
+                    op = string(r)
+                default:
+                    return nil, fmt.Errorf("unexpected character %q at %v", r, i)
+                }
+            }
+            tokens = append(tokens, token{tokenOp, op})
+            i += len(op)
+        }
+    }
+    return append(tokens, token{kind: tokenEOF}), nil
+}
+
+type parser struct {
+    tokens []token
+    pos    int
+}
+
+// Parse parses a predicate expression
+func Parse(s string) (Expr, error) {
+    tokens, err := tokenize(s)
+    if err != nil {
+        return nil, err
+    }
+    p := &parser{tokens: tokens}
+    expr, err := p.parseOr()
+    if err != nil {
+        return nil, err
+    }
+    if t := p.peek(); t.kind != tokenEOF {
+        return nil, fmt.Errorf("unexpected token: %v", t.text)
+    }
+    return expr, nil
+}
+
+func (p *parser) peek() token {
+    return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+    t := p.tokens[p.pos]
+    if t.kind != tokenEOF {
+        p.pos++
+    }
+    return t
+}
+
+func (p *parser) isOp(text string) bool {
+    t := p.peek()
+    return t.kind == tokenOp && t.text == text
+}
+
+func (p *parser) expectOp(text string) error {
+    if t := p.next(); t.kind != tokenOp || t.text != text {
+        return fmt.Errorf("expect %v but got: %q", text, t.text)
+    }
+    return nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+    x, err := p.parseAnd()
+    if err != nil {
+        return nil, err
+    }
+    for p.isOp("||") {
+        p.next()
+        y, err := p.parseAnd()
+        if err != nil {
+            return nil, err
+        }
+        x = &orExpr{x, y}
+    }
+    return x, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+    x, err := p.parseUnary()
+    if err != nil {
+        return nil, err
+    }
+    for p.isOp("&&") {
+        p.next()
+        y, err := p.parseUnary()
+        if err != nil {
+            return nil, err
+        }
+        x = &andExpr{x, y}
+    }
+    return x, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+    if p.isOp("!") {
+        p.next()
+        x, err := p.parseUnary()
+        if err != nil {
+            return nil, err
+        }
+        return &notExpr{x}, nil
+    }
+    if p.isOp("(") {
+        p.next()
+        x, err := p.parseOr()
+        if err != nil {
+            return nil, err
+        }
+        return x, p.expectOp(")")
+    }
+    return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+    t := p.next()
+    if t.kind != tokenIdent {
+        return nil, fmt.Errorf("expect field name but got: %q", t.text)
+    }
+    name := t.text
+
+    op := p.next()
+    if op.kind == tokenIdent && op.text == "in" {
+        if err := p.expectOp("("); err != nil {
+            return nil, err
+        }
+        var values []interface{}
+        for {
+            value, err := p.parseValue()
+            if err != nil {
+                return nil, err
+            }
+            values = append(values, value)
+            if !p.isOp(",") {
+                break
+            }
+            p.next()
+        }
+        return &inExpr{name, values}, p.expectOp(")")
+    }
+
+    switch op.text {
+    case "==", "!=", "<", "<=", ">", ">=":
+    default:
+        return nil, fmt.Errorf("expect comparison operator after %v but got: %q", name, op.text)
+    }
+    value, err := p.parseValue()
+    if err != nil {
+        return nil, err
+    }
+    if _, ok := value.(bool); ok && op.text != "==" && op.text != "!=" {
+        return nil, fmt.Errorf("cannot compare %v with boolean using %v", name, op.text)
+    }
+    return &compareExpr{name, op.text, value}, nil
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+    t := p.next()
+    switch t.kind {
+    case tokenNumber:
+        v, err := strconv.ParseFloat(t.text, 64)
+        if err != nil {
+            return nil, fmt.Errorf("invalid number: %q", t.text)
+        }
+        return v, nil
+    case tokenString:
+        return t.text, nil
+    case tokenIdent:
+        switch t.text {
+        case "true":
+            return true, nil
+        case "false":
+            return false, nil
+        }
+    }
+    return nil, fmt.Errorf("expect value but got: %q", t.text)
+}
@@ -0,0 +1,58 @@
+package filter
+
+import (
+    "testing"
+    "reflect"
+)
+
+func TestParse(t *testing.T) {
+    for _, c := range []struct {
+        s        string
+        expected Expr
+    }{
+        {"nSubsets > 1e-3", &compareExpr{"nSubsets", ">", 1e-3}},
+        {"nSubsets > 2.5E+2", &compareExpr{"nSubsets", ">", 250.0}},
+        {"nSubsets>-1", &compareExpr{"nSubsets", ">", -1.0}},
+        {"isCompressed != true", &compareExpr{"isCompressed", "!=", true}},
+        {"typicalDate == '2012-11-02'", &compareExpr{"typicalDate", "==", "2012-11-02"}},
+        {"dataCategory in (0, 1)", &inExpr{"dataCategory", []interface{}{0.0, 1.0}}},
+        {"!(nSubsets <= 1)", &notExpr{&compareExpr{"nSubsets", "<=", 1.0}}},
+        {
+            "a == 1 || b == 2 && c == 3",
+            &orExpr{&compareExpr{"a", "==", 1.0},
+                &andExpr{&compareExpr{"b", "==", 2.0}, &compareExpr{"c", "==", 3.0}}},
+        },
+    } {
+        expr, err := Parse(c.s)
+        if err != nil {
+            t.Errorf("%q: %v", c.s, err)
+            continue
+        }
+        if !reflect.DeepEqual(expr, c.expected) {
+            t.Errorf("%q: got %#v, expected %#v", c.s, expr, c.expected)
+        }
+    }
+}
+
+func TestParseErrors(t *testing.T) {
+    for _, s := range []string{
+        "",
+        "dataCategory",
+        "dataCategory == ",
+        "(nSubsets > 1",
+        "nSubsets > 1)",
+        "nSubsets ~ 1",
+        "nSubsets > 1e-",
+        "nSubsets > 1-3",
+        "typicalDate == '2012",
+        "dataCategory in 0",
+        "dataCategory in (0,)",
+        "isCompressed < true",
+        "isCompressed >= false",
+        "0 == 0",
+    } {
+        if _, err := Parse(s); err == nil {
+            t.Errorf("%q: expected an error", s)
+        }
+    }
+}
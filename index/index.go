@@ -0,0 +1,132 @@
+// package index provides an index of messages in BUFR files, i.e. the location and
+// the header information of each message, for quick lookup and random access.
+package index
+
+import (
+    "io"
+    "fmt"
+    "bufio"
+    "encoding/json"
+    "github.com/pkg/errors"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/table"
+)
+
+// Entry describes a single message and where to find it.
+type Entry struct {
+    // Path of the input, including the member name if the message is from an archive
+    InputPath string `json:"inputPath"`
+    // One based number of the message in the input
+    Number int `json:"number"`
+    // Zero based byte offset of the message in the (decompressed) input
+    Offset int `json:"offset"`
+    Length int `json:"length"`
+
+    Edition            int      `json:"edition"`
+    Centre             int      `json:"centre"`
+    SubCentre          int      `json:"subCentre"`
+    DataCategory       int      `json:"dataCategory"`
+    MasterTableVersion int      `json:"masterTableVersion"`
+    LocalTableVersion  int      `json:"localTableVersion"`
+    TypicalDateTime    string   `json:"typicalDateTime"`
+    NSubsets           int      `json:"nSubsets"`
+    Compressed         bool     `json:"compressed"`
+    Template           []string `json:"template"`
+}
+
+// NewEntry creates an index entry from a message which has at least its header
+// sections, i.e. sections 0 to 3, deserialized.
+func NewEntry(message *bufr.Message) (*Entry, error) {
+    sections := message.Sections()
+    if len(sections) == 0 {
+        return nil, fmt.Errorf("message has no section")
+    }
+
+    entry := &Entry{Offset: sections[0].StartByteIndex}
+    entry.InputPath, _ = message.Metadata("inputPath").(string)
+    entry.Number, _ = message.Metadata("number").(int)
+
+    for name, p := range map[string]*int{
+        "totalLengthInBytes":   &entry.Length,
+        "bufrEditionNumber":    &entry.Edition,
+        "originatingCentre":    &entry.Centre,
+        "originatingSubCentre": &entry.SubCentre,
+        "dataCategory":         &entry.DataCategory,
+        "masterTableVersion":   &entry.MasterTableVersion,
+        "localTableVersion":    &entry.LocalTableVersion,
+        "nSubsets":             &entry.NSubsets,
+    } {
+        v, err := proxyUint(message, name)
+        if err != nil {
+            return nil, err
+        }
+        *p = int(v)
+    }
+
+    compressed, err := message.ProxyField("isCompressed")
+    if err != nil {
+        return nil, err
+    }
+    entry.Compressed, _ = compressed.Value.(bool)
+
+    entry.TypicalDateTime = message.TypicalDateTime()
+
+    field, err := message.ProxyField("unexpandedTemplate")
+    if err != nil {
+        return nil, err
+    }
+    ut, ok := field.Value.(*table.UnexpandedTemplate)
+    if !ok {
+        return nil, fmt.Errorf("invalid unexpanded template: %T", field.Value)
+    }
+    for _, id := range ut.Ids() {
+        entry.Template = append(entry.Template, id.String())
+    }
+
+    return entry, nil
+}
+
+// Write writes the entries as JSON lines
+func Write(w io.Writer, entries ...*Entry) error {
+    enc := json.NewEncoder(w)
+    for _, entry := range entries {
+        if err := enc.Encode(entry); err != nil {
+            return errors.Wrap(err, "cannot write index entry")
+        }
+    }
+    return nil
+}
+
+// Read reads all entries from JSON lines
+func Read(r io.Reader) ([]*Entry, error) {
+    var entries []*Entry
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(nil, 1024*1024)
+    for scanner.Scan() {
+        if len(scanner.Bytes()) == 0 {
+            continue
+        }
+        entry := &Entry{}
+        if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+            return nil, errors.Wrapf(err, "cannot read index entry %v", len(entries)+1)
+        }
+        entries = append(entries, entry)
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, errors.Wrap(err, "cannot read index")
+    }
+    return entries, nil
+}
+
+// proxyUint returns the value of a proxy field as an uint
+func proxyUint(message *bufr.Message, name string) (uint, error) {
+    field, err := message.ProxyField(name)
+    if err != nil {
+        return 0, err
+    }
+    v, ok := field.Value.(uint)
+    if !ok {
+        return 0, fmt.Errorf("proxy field %v is not an uint: %T", name, field.Value)
+    }
+    return v, nil
+}
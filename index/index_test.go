@@ -0,0 +1,25 @@
+package index
+
+import (
+    "testing"
+    assert2 "github.com/seanpont/assert"
+    "bytes"
+)
+
+func TestWriteAndRead(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    entries := []*Entry{
+        {InputPath: "a.bufr", Number: 1, Offset: 0, Length: 94, Edition: 4, Template: []string{"301001"}},
+        {InputPath: "a.bufr", Number: 2, Offset: 94, Length: 760, Edition: 3, Compressed: true},
+    }
+    buf := new(bytes.Buffer)
+    assert.Nil(Write(buf, entries...))
+
+    read, err := Read(buf)
+    assert.Nil(err)
+    assert.Equal(len(read), 2)
+    assert.Equal(read[0].Template, []string{"301001"})
+    assert.Equal(read[1].Offset, 94)
+    assert.True(read[1].Compressed)
+}
@@ -0,0 +1,60 @@
+package regression
+
+import (
+    "testing"
+    "context"
+    "errors"
+    "os"
+    "bytes"
+    "path/filepath"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/deserialize"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+// expiringContext reports its deadline exceeded after its error is checked n times
+type expiringContext struct {
+    context.Context
+    n int
+}
+
+func (c *expiringContext) Err() error {
+    if c.n <= 0 {
+        return context.DeadlineExceeded
+    }
+    c.n--
+    return nil
+}
+
+func newTestRuntime(t *testing.T, name string) *api.Runtime {
+    data, err := os.ReadFile(filepath.Join(testdataPath, name))
+    if err != nil {
+        t.Fatal(err)
+    }
+    rt, err := api.NewRuntime(newConfig(), tdcfio.NewPeekableBitReader(bytes.NewReader(data)))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return rt
+}
+
+func TestCancel(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if _, err := newTestRuntime(t, "profiler_european.bufr").Run(ctx); err != context.Canceled {
+        t.Errorf("got error %v, want %v", err, context.Canceled)
+    }
+
+    _, err := newTestRuntime(t, "profiler_european.bufr").Run(
+        &expiringContext{Context: context.Background(), n: 10})
+    cancelled, ok := err.(*deserialize.CancelledError)
+    if !ok {
+        t.Fatalf("got error %v, want a *deserialize.CancelledError", err)
+    }
+    if !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("got error %v, want %v", cancelled.Err, context.DeadlineExceeded)
+    }
+    if cancelled.Pos == 0 {
+        t.Errorf("no progress recorded: %v", cancelled)
+    }
+}
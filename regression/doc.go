@@ -0,0 +1,11 @@
+// Package regression has no code of its own. Its tests decode every message under
+// _testdata and compare the bare JSON output against the golden files under
+// _regression, reporting all differences down to individual cell values. Files
+// under _testdata/benchmark_data are only checked to decode without error.
+//
+// All decodable files are also encoded again and checked to be identical to the
+// original messages, or to have the same values where encoding normalises them.
+//
+// Golden files are regenerated with
+//   go test ./regression -update
+package regression
@@ -0,0 +1,149 @@
+package regression
+
+import (
+    "testing"
+    "os"
+    "fmt"
+    "bytes"
+    "strings"
+    "strconv"
+    "path/filepath"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/serialize"
+    "github.com/ywangd/gobufrkit/table"
+)
+
+// dumpMessages dumps all messages of the given file and returns the lines of each dump
+func dumpMessages(t *testing.T, name string) ([]*bufr.Message, [][]byte, [][]string) {
+    path := filepath.Join(testdataPath, name)
+    data, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    raws := rawMessages(data)
+    messages := mustDecode(t, path)
+    var dumps [][]string
+    for i, message := range messages {
+        buf := new(bytes.Buffer)
+        dumper := serialize.NewBitDumper(buf)
+        dumper.ShowBits = true
+        if err := dumper.Dump(message, raws[i]); err != nil {
+            t.Fatalf("%v: message %v: %v", name, i+1, err)
+        }
+        dumps = append(dumps, strings.Split(strings.TrimSpace(buf.String()), "\n"))
+    }
+    return messages, raws, dumps
+}
+
+func TestBitDump(t *testing.T) {
+    for _, name := range []string{"contrived.bufr", "ISMD01_OKPR.bufr", "207003.bufr", "amv2_87.bufr"} {
+        messages, raws, dumps := dumpMessages(t, name)
+        for i, lines := range dumps {
+            // The stop signature ends every message
+            last := strings.Fields(lines[len(lines)-1])
+            if want := (len(raws[i]) - 4) * 8; last[0] != strconv.Itoa(want) || last[3] != "stopSignature" {
+                t.Errorf("%v: message %v: last line %q, want stopSignature at bit %v",
+                    name, i+1, lines[len(lines)-1], want)
+            }
+            // Sections are numbered as in the message, e.g. edition 3 without section 2
+            var banners []string
+            for _, line := range lines {
+                if strings.HasPrefix(line, "<<<<<<") {
+                    banners = append(banners, line)
+                }
+            }
+            for j, section := range messages[i].Sections() {
+                if want := fmt.Sprintf("<<<<<< section %d >>>>>>", section.Number()); banners[j] != want {
+                    t.Errorf("%v: message %v: banner %q, want %q", name, i+1, banners[j], want)
+                }
+            }
+        }
+    }
+}
+
+// dumpRow is a row of the bit dump
+type dumpRow struct {
+    nbits int
+    raw   uint64
+    role  string
+}
+
+func parseDumpRow(line string) dumpRow {
+    fields := strings.Fields(line)
+    nbits, _ := strconv.Atoi(fields[1])
+    raw, _ := strconv.ParseUint(fields[2], 10, 64)
+    role := fields[3]
+    if role == "subset" {
+        role += " " + fields[4]
+    }
+    return dumpRow{nbits: nbits, raw: raw, role: role}
+}
+
+func TestBitDumpCompressed(t *testing.T) {
+    messages, _, dumps := dumpMessages(t, "amv2_87.bufr")
+    payload := messages[0].Sections()[4].FieldByName("payload").Value.(*bufr.Payload)
+    subsets := payload.Subsets()
+
+    lines := dumps[0]
+    k := 0
+    for !strings.HasPrefix(lines[k], "######") {
+        k++
+    }
+    if want := fmt.Sprintf("###### %d compressed subsets ######", len(subsets)); lines[k] != want {
+        t.Fatalf("banner %q, want %q", lines[k], want)
+    }
+    k++
+
+    // Each value is packed as the minimum, the width of increments and the increment
+    // of each subset, which added to the minimum is the packed value of the subset
+    next := func() dumpRow {
+        row := parseDumpRow(lines[k])
+        k++
+        return row
+    }
+    for j, cell := range subsets[0].Cells() {
+        node := cell.Node()
+        if node.PackingInfo.Nbits == 0 {
+            continue
+        }
+        min := next()
+        if min.role != "min" || min.nbits != node.PackingInfo.Nbits {
+            t.Fatalf("value %v: min row %+v", j+1, min)
+        }
+        nbitsDiff := next()
+        if nbitsDiff.role != "nbitsDiff" || nbitsDiff.nbits != 6 || int(nbitsDiff.raw) != node.NbitsDiff {
+            t.Fatalf("value %v: nbitsDiff row %+v, want %v", j+1, nbitsDiff, node.NbitsDiff)
+        }
+        if nbitsDiff.raw == 0 {
+            continue
+        }
+        for i, subset := range subsets {
+            increment := next()
+            if increment.role != fmt.Sprintf("subset %d", i+1) {
+                t.Fatalf("value %v: increment row %+v of subset %v", j+1, increment, i+1)
+            }
+            if node.PackingInfo.Unit == table.STRING {
+                continue
+            }
+            if increment.nbits != node.NbitsDiff {
+                t.Fatalf("value %v: increment row %+v of subset %v", j+1, increment, i+1)
+            }
+            packed, err := subset.Cell(j).PackedValue()
+            if err != nil {
+                t.Fatal(err)
+            }
+            if subset.Cell(j).Value() == nil {
+                if increment.raw != 1<<uint(increment.nbits)-1 {
+                    t.Errorf("value %v: increment %v of missing value of subset %v", j+1, increment.raw, i+1)
+                }
+            } else if uint(min.raw+increment.raw) != packed {
+                t.Errorf("value %v: min %v + increment %v of subset %v, want %v",
+                    j+1, min.raw, increment.raw, i+1, packed)
+            }
+        }
+    }
+    // Followed by the padding of section 4
+    if fields := strings.Fields(lines[k]); len(fields) < 4 || fields[3] != "padding" {
+        t.Errorf("values end at %q", lines[k])
+    }
+}
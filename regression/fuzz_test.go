@@ -0,0 +1,115 @@
+package regression
+
+import (
+    "testing"
+    "context"
+    "os"
+    "io"
+    "bytes"
+    "strings"
+    "runtime"
+    "path/filepath"
+    "github.com/pkg/errors"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/builder"
+    "github.com/ywangd/gobufrkit/serialize"
+    "github.com/ywangd/gobufrkit/deserialize"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+// Limits small enough to keep each fuzzing run quick
+var fuzzLimits = deserialize.Limits{
+    MaxSubsets:       1000,
+    MaxReplications:  1000,
+    MaxTemplateDepth: 16,
+    MaxCells:         100000,
+}
+
+// FuzzDecode decodes arbitrary bytes seeded from the test files. Malformed input
+// must be reported as errors instead of panics or exits.
+func FuzzDecode(f *testing.F) {
+    paths, err := filepath.Glob(filepath.Join(testdataPath, "*.bufr"))
+    if err != nil {
+        f.Fatal(err)
+    }
+    for _, path := range paths {
+        data, err := os.ReadFile(path)
+        if err != nil {
+            f.Fatal(err)
+        }
+        f.Add(data)
+    }
+
+    f.Fuzz(func(t *testing.T, data []byte) {
+        config := newConfig()
+        config.Limits = &fuzzLimits
+        rt, err := api.NewRuntime(config, tdcfio.NewPeekableBitReader(bytes.NewReader(data)))
+        if err != nil {
+            t.Fatal(err)
+        }
+        for {
+            if _, err := rt.Run(context.Background()); err != nil {
+                // Panics of Go functions called by the definitions scripts are
+                // recovered by the Lua runtime and returned as errors
+                if _, ok := errors.Cause(err).(runtime.Error); ok {
+                    t.Fatalf("panic: %v", err)
+                }
+                return
+            }
+        }
+    })
+}
+
+func TestLimits(t *testing.T) {
+    path := filepath.Join(testdataPath, "amv2_87.bufr")
+    for _, test := range []struct {
+        limits deserialize.Limits
+        want   string
+    }{
+        {deserialize.Limits{MaxSubsets: 10}, "number of subsets exceeds the limit of 10"},
+        {deserialize.Limits{MaxCells: 100}, "number of values and nodes exceeds the limit of 100"},
+        {deserialize.Limits{MaxTemplateDepth: 1}, "template nested deeper than 1 levels"},
+    } {
+        config := newConfig()
+        config.Limits = &test.limits
+        data, err := os.ReadFile(path)
+        if err != nil {
+            t.Fatal(err)
+        }
+        rt, err := api.NewRuntime(config, tdcfio.NewPeekableBitReader(bytes.NewReader(data)))
+        if err != nil {
+            t.Fatal(err)
+        }
+        _, err = rt.Run(context.Background())
+        if err == nil || err == io.EOF || !strings.Contains(err.Error(), test.want) {
+            t.Errorf("limits %+v: got error %v, want %q", test.limits, err, test.want)
+        }
+    }
+}
+
+// TestLimitsValuelessReplications checks that replicating nodes without values,
+// which never adds any cell, is still bounded by MaxCells
+func TestLimitsValuelessReplications(t *testing.T) {
+    b := builder.NewBuilder("../_definitions/tables")
+    b.Set("masterTableVersion", 25)
+    // 255 times 255 operators changing the data width and one cancelling it, followed
+    // by a temperature
+    b.SetTemplate(102255, 101255, 201129, 201000, 12101)
+    b.AddSubset(273.15)
+    message, err := b.Build()
+    if err != nil {
+        t.Fatal(err)
+    }
+    buf := new(bytes.Buffer)
+    if err := serialize.NewBinarySerializer(buf).Serialize(message); err != nil {
+        t.Fatal(err)
+    }
+
+    config := newConfig()
+    config.Limits = &deserialize.Limits{MaxCells: 10000}
+    _, err = api.DecodeMessageAt(context.Background(), config, bytes.NewReader(buf.Bytes()), 0)
+    want := "number of values and nodes exceeds the limit of 10000"
+    if err == nil || !strings.Contains(err.Error(), want) {
+        t.Errorf("got error %v, want %q", err, want)
+    }
+}
@@ -0,0 +1,40 @@
+package regression
+
+import (
+    "testing"
+    "os"
+    "context"
+    "path/filepath"
+    "github.com/ywangd/gobufrkit/api"
+)
+
+// TestDecodeMessageAt checks that decoding a message at its offset gives the same
+// locations and GTS heading as decoding the file from its beginning.
+func TestDecodeMessageAt(t *testing.T) {
+    path := filepath.Join(testdataPath, "ISMD01_OKPR.bufr")
+    decoded := mustDecode(t, path)
+    ins, err := os.Open(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer ins.Close()
+
+    for i, want := range decoded {
+        offset := want.Sections()[0].StartByteIndex
+        got, err := api.DecodeMessageAt(context.Background(), newConfig(), ins, int64(offset))
+        if err != nil {
+            t.Fatalf("message %v: %v", i+1, err)
+        }
+        for j, section := range got.Sections() {
+            if section.StartByteIndex != want.Sections()[j].StartByteIndex {
+                t.Errorf("message %v: section %v starts at %v, want %v",
+                    i+1, j, section.StartByteIndex, want.Sections()[j].StartByteIndex)
+            }
+        }
+        for _, name := range []string{"gtsSequenceNumber", "gtsTTAAii", "gtsCCCC", "gtsYYGGgg", "gtsBBB"} {
+            if got.Metadata(name) == nil || got.Metadata(name) != want.Metadata(name) {
+                t.Errorf("message %v: %v is %v, want %v", i+1, name, got.Metadata(name), want.Metadata(name))
+            }
+        }
+    }
+}
@@ -0,0 +1,311 @@
+package regression
+
+import (
+    "testing"
+    "context"
+    "flag"
+    "os"
+    "io"
+    "fmt"
+    "bytes"
+    "reflect"
+    "path/filepath"
+    "strings"
+    "encoding/json"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/serialize"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+var update = flag.Bool("update", false, "Regenerate golden files under _regression")
+
+const (
+    testdataPath   = "../_testdata"
+    benchmarkPath  = "../_testdata/benchmark_data"
+    regressionPath = "../_regression"
+)
+
+// Maximum number of differences reported for each file
+const maxReported = 50
+
+// Reasons of known failures
+const (
+    moreMarkersThanTargets = "more bitmap marker values than bitmapped target values"
+    moreBitsThanValues     = "bitmap has more bits than the values preceding it"
+)
+
+// Files that cannot be decoded yet, keyed by their paths relative to _testdata.
+// An entry should be removed once the file decodes.
+var knownFailures = map[string]string{
+    "g2nd_208.bufr":                moreMarkersThanTargets,
+    "mpco_217.bufr":                moreMarkersThanTargets,
+    "rado_250.bufr":                moreBitsThanValues,
+    "benchmark_data/g2nd_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/g2to_206.bufr": moreMarkersThanTargets,
+    "benchmark_data/gsd1_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/gsd2_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/gsd3_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/ifco_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/ikco_217.bufr": moreMarkersThanTargets,
+    "benchmark_data/itrg_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/kond_209.bufr": moreMarkersThanTargets,
+    "benchmark_data/maer_207.bufr": moreMarkersThanTargets,
+    "benchmark_data/mloz_206.bufr": moreMarkersThanTargets,
+    "benchmark_data/mpco_217.bufr": moreMarkersThanTargets,
+    "benchmark_data/nomi_206.bufr": moreMarkersThanTargets,
+    "benchmark_data/nos1_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/nos2_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/nos3_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/nos4_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/nos5_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/nos6_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/nos7_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/nos8_208.bufr": moreMarkersThanTargets,
+    "benchmark_data/pilo_91.bufr":  moreBitsThanValues,
+    "benchmark_data/rada_250.bufr": moreBitsThanValues,
+    "benchmark_data/rado_250.bufr": moreBitsThanValues,
+    "benchmark_data/sb19_206.bufr": moreMarkersThanTargets,
+    "benchmark_data/sbu8_206.bufr": moreMarkersThanTargets,
+    "benchmark_data/temp_101.bufr": moreBitsThanValues,
+    "benchmark_data/temp_102.bufr": moreBitsThanValues,
+    "benchmark_data/temp_106.bufr": moreBitsThanValues,
+}
+
+func newConfig() *api.Config {
+    return &api.Config{
+        DefinitionsPath: "../_definitions",
+        TablesPath:      "../_definitions/tables",
+        InputType:       tdcfio.BinaryInput,
+    }
+}
+
+// decodeFile decodes all messages of the given file
+func decodeFile(path string) ([]*bufr.Message, error) {
+    return decodeFileWith(newConfig(), path)
+}
+
+// decodeFileWith decodes all messages of the given file with the given config
+func decodeFileWith(config *api.Config, path string) ([]*bufr.Message, error) {
+    ins, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer ins.Close()
+
+    rt, err := api.NewRuntime(config, tdcfio.NewPeekableBitReader(ins))
+    if err != nil {
+        return nil, err
+    }
+    var messages []*bufr.Message
+    for {
+        message, err := rt.Run(context.Background())
+        if err == io.EOF {
+            return messages, nil
+        }
+        if err != nil {
+            return nil, fmt.Errorf("message %v: %v", len(messages)+1, err)
+        }
+        messages = append(messages, message)
+    }
+}
+
+// mustDecode decodes the given file or stops the test. Known failures are skipped.
+func mustDecode(t *testing.T, path string) []*bufr.Message {
+    messages, err := decodeFile(path)
+    key, _ := filepath.Rel(testdataPath, path)
+    if reason, ok := knownFailures[filepath.ToSlash(key)]; ok {
+        if err == nil {
+            t.Fatalf("known failure now decodes, remove it from knownFailures: %v", reason)
+        }
+        t.Skipf("known failure: %v: %v", reason, err)
+    }
+    if err != nil {
+        t.Fatalf("cannot decode: %v", err)
+    }
+    return messages
+}
+
+// readJsonValues reads all JSON values, one for each message, from the input
+func readJsonValues(r io.Reader) ([]interface{}, error) {
+    var values []interface{}
+    dec := json.NewDecoder(r)
+    for {
+        var v interface{}
+        if err := dec.Decode(&v); err == io.EOF {
+            return values, nil
+        } else if err != nil {
+            return nil, err
+        }
+        values = append(values, v)
+    }
+}
+
+func TestGoldenFiles(t *testing.T) {
+    paths, err := filepath.Glob(filepath.Join(testdataPath, "*.bufr"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(paths) == 0 {
+        t.Fatalf("no test file found under %v", testdataPath)
+    }
+
+    for _, path := range paths {
+        path := path
+        name := filepath.Base(path)
+        t.Run(name, func(t *testing.T) {
+            messages := mustDecode(t, path)
+
+            buf := new(bytes.Buffer)
+            serializer := serialize.NewFlatJsonSerializer(buf, &serialize.Config{ShowHidden: true})
+            for _, message := range messages {
+                if err := serializer.Serialize(message); err != nil {
+                    t.Fatalf("cannot serialize: %v", err)
+                }
+            }
+
+            goldenPath := filepath.Join(regressionPath, name+".json")
+            if *update {
+                if err := os.WriteFile(goldenPath, buf.Bytes(), 0644); err != nil {
+                    t.Fatal(err)
+                }
+                return
+            }
+
+            ins, err := os.Open(goldenPath)
+            if err != nil {
+                t.Fatalf("cannot open golden file, run with -update to create it: %v", err)
+            }
+            defer ins.Close()
+            want, err := readJsonValues(ins)
+            if err != nil {
+                t.Fatalf("cannot read golden file: %v", err)
+            }
+            got, err := readJsonValues(buf)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            diffs := compareMessages(messages, got, want)
+            for i, d := range diffs {
+                if i == maxReported {
+                    t.Errorf("... %v more differences", len(diffs)-maxReported)
+                    break
+                }
+                t.Error(d)
+            }
+        })
+    }
+}
+
+func TestBenchmarkData(t *testing.T) {
+    paths, err := filepath.Glob(filepath.Join(benchmarkPath, "*.bufr"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(paths) == 0 {
+        t.Skipf("no benchmark file found under %v", benchmarkPath)
+    }
+
+    for _, path := range paths {
+        path := path
+        t.Run(filepath.Base(path), func(t *testing.T) {
+            t.Parallel()
+            if messages := mustDecode(t, path); len(messages) == 0 {
+                t.Fatal("no message found")
+            }
+        })
+    }
+}
+
+// compareMessages compares the bare JSON values of decoded messages against the
+// expected ones. The messages are used to name the fields and cells of differences.
+func compareMessages(messages []*bufr.Message, got, want []interface{}) []string {
+    var diffs []string
+    if len(got) != len(want) {
+        diffs = append(diffs, fmt.Sprintf("number of messages: got %v, want %v", len(got), len(want)))
+    }
+    for i := 0; i < len(got) && i < len(want); i++ {
+        path := fmt.Sprintf("message %v", i+1)
+        sectionsGot, sectionsWant := got[i].([]interface{}), asList(want[i])
+        if len(sectionsGot) != len(sectionsWant) {
+            diffs = append(diffs, fmt.Sprintf("%v: number of sections: got %v, want %v",
+                path, len(sectionsGot), len(sectionsWant)))
+            continue
+        }
+        for j, section := range messages[i].Sections() {
+            diffs = append(diffs, compareSection(
+                fmt.Sprintf("%v/section %v", path, section.Number()), section,
+                sectionsGot[j].([]interface{}), asList(sectionsWant[j]))...)
+        }
+    }
+    return diffs
+}
+
+func compareSection(path string, section *bufr.Section, got, want []interface{}) []string {
+    var diffs []string
+    if len(got) != len(want) {
+        return append(diffs, fmt.Sprintf("%v: number of fields: got %v, want %v", path, len(got), len(want)))
+    }
+    // Virtual fields are not serialized as bare JSON values
+    i := -1
+    for _, field := range section.Fields() {
+        if field.Virtual {
+            continue
+        }
+        i++
+        fpath := path + "/" + field.Name
+        if payload, ok := field.Value.(*bufr.Payload); ok {
+            diffs = append(diffs, comparePayload(fpath, payload, got[i].([]interface{}), asList(want[i]))...)
+        } else if !reflect.DeepEqual(got[i], want[i]) {
+            diffs = append(diffs, difference(fpath, got[i], want[i]))
+        }
+    }
+    return diffs
+}
+
+func comparePayload(path string, payload *bufr.Payload, got, want []interface{}) []string {
+    var diffs []string
+    if len(got) != len(want) {
+        diffs = append(diffs, fmt.Sprintf("%v: number of subsets: got %v, want %v", path, len(got), len(want)))
+    }
+    for i := 0; i < len(got) && i < len(want); i++ {
+        spath := fmt.Sprintf("%v/subset %v", path, i+1)
+        cells := payload.Subsets()[i].Cells()
+        valuesGot, valuesWant := got[i].([]interface{}), asList(want[i])
+        if len(valuesGot) != len(valuesWant) {
+            diffs = append(diffs, fmt.Sprintf("%v: number of values: got %v, want %v",
+                spath, len(valuesGot), len(valuesWant)))
+        }
+        for j := 0; j < len(valuesGot) && j < len(valuesWant); j++ {
+            if !reflect.DeepEqual(valuesGot[j], valuesWant[j]) {
+                diffs = append(diffs, difference(
+                    fmt.Sprintf("%v/value %v %v", spath, j+1, cells[j].Node().Descriptor.Id()),
+                    valuesGot[j], valuesWant[j]))
+            }
+        }
+    }
+    return diffs
+}
+
+func difference(path string, got, want interface{}) string {
+    return fmt.Sprintf("%v: got %v, want %v", path, jsonString(got), jsonString(want))
+}
+
+func jsonString(v interface{}) string {
+    b, err := json.Marshal(v)
+    if err != nil {
+        return fmt.Sprint(v)
+    }
+    s := string(b)
+    if len(s) > 80 {
+        s = s[:77] + "..."
+    }
+    return strings.TrimSpace(s)
+}
+
+// asList returns the given JSON value as a list, or an empty list if it is not one
+func asList(v interface{}) []interface{} {
+    list, _ := v.([]interface{})
+    return list
+}
@@ -0,0 +1,428 @@
+package regression
+
+import (
+    "testing"
+    "context"
+    "os"
+    "fmt"
+    "bytes"
+    "strings"
+    "math/bits"
+    "path/filepath"
+    "encoding/binary"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/builder"
+    "github.com/ywangd/gobufrkit/deserialize"
+    "github.com/ywangd/gobufrkit/diff"
+    "github.com/ywangd/gobufrkit/serialize"
+    "github.com/ywangd/gobufrkit/serialize/pack"
+    "github.com/ywangd/gobufrkit/table"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+// Names of fields which are normalised on encoding, i.e. they may legitimately
+// differ from the original message as long as all values are preserved.
+var normalisedFields = []string{"lengthInBytes", "totalLengthInBytes", "padding"}
+
+// Files which are not encoded to identical bytes but must keep their values, keyed
+// by their paths relative to _testdata
+var nonIdentical = map[string]string{
+    "benchmark_data/sentinel1.bufr": "compressed missing values have increments of all ones of " +
+        "the value width instead of the increment width",
+}
+
+func TestRoundTrip(t *testing.T) {
+    for _, pattern := range []string{
+        filepath.Join(testdataPath, "*.bufr"),
+        filepath.Join(benchmarkPath, "*.bufr"),
+    } {
+        paths, err := filepath.Glob(pattern)
+        if err != nil {
+            t.Fatal(err)
+        }
+        for _, path := range paths {
+            path := path
+            key, _ := filepath.Rel(testdataPath, path)
+            key = filepath.ToSlash(key)
+            t.Run(key, func(t *testing.T) {
+                t.Parallel()
+                _, valuesOnly := nonIdentical[key]
+                checkRoundTrip(t, path, valuesOnly)
+            })
+        }
+    }
+}
+
+// checkRoundTrip decodes all messages of the given file, encodes them again and
+// checks that the encoded messages are identical to the original ones. Messages
+// differing only in normalised fields, or in anything if valuesOnly is true, must
+// have the same values when decoded again.
+func checkRoundTrip(t *testing.T, path string, valuesOnly bool) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    messages := mustDecode(t, path)
+    raws := rawMessages(data)
+    if len(raws) != len(messages) {
+        t.Fatalf("number of messages: decoded %v, found %v", len(messages), len(raws))
+    }
+
+    for i, message := range messages {
+        // Locate differences using the layout of the original message before encoding
+        // updates its lengths
+        layout := newLayout(message)
+
+        buf := new(bytes.Buffer)
+        if err := serialize.NewBinarySerializer(buf).Serialize(message); err != nil {
+            t.Errorf("message %v: cannot encode: %v", i+1, err)
+            continue
+        }
+        offset := firstDifferentBit(raws[i], buf.Bytes())
+        if offset < 0 {
+            continue
+        }
+
+        loc := layout.locate(offset)
+        if !valuesOnly && !loc.isNormalised() {
+            t.Errorf("message %v: first difference at bit %v: %v", i+1, offset, loc)
+            continue
+        }
+        decoded, err := api.DecodeMessageAt(context.Background(), newConfig(), bytes.NewReader(buf.Bytes()), 0)
+        if err != nil {
+            t.Errorf("message %v: cannot decode encoded message: %v", i+1, err)
+            continue
+        }
+        for _, d := range diff.Compare(message, decoded, &diff.Options{}) {
+            if !isNormalisedPath(d.Path) {
+                t.Errorf("message %v: first difference at bit %v: %v: %v", i+1, offset, loc, d)
+            }
+        }
+    }
+}
+
+func TestFlatTextRoundTrip(t *testing.T) {
+    paths, err := filepath.Glob(filepath.Join(testdataPath, "*.bufr"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, path := range paths {
+        path := path
+        t.Run(filepath.Base(path), func(t *testing.T) {
+            t.Parallel()
+            checkFlatTextRoundTrip(t, path)
+        })
+    }
+}
+
+// checkFlatTextRoundTrip writes all messages of the given file as text dumps, reads
+// them back and checks that the messages have the same values.
+func checkFlatTextRoundTrip(t *testing.T, path string) {
+    for i, message := range mustDecode(t, path) {
+        buf := new(bytes.Buffer)
+        s := serialize.NewFlatTextSerializer(buf, &serialize.Config{ShowHidden: true})
+        if err := s.Serialize(message); err != nil {
+            t.Fatalf("message %v: cannot write text: %v", i+1, err)
+        }
+
+        config := newConfig()
+        config.InputType = tdcfio.FlatTextInput
+        rt, err := api.NewRuntime(config, tdcfio.NewFlatTextReader(buf))
+        if err != nil {
+            t.Fatal(err)
+        }
+        decoded, err := rt.Run(context.Background())
+        if err != nil {
+            t.Errorf("message %v: cannot read text: %v", i+1, err)
+            continue
+        }
+        for _, d := range diff.Compare(message, decoded, &diff.Options{}) {
+            if !isNormalisedPath(d.Path) {
+                t.Errorf("message %v: %v", i+1, d)
+            }
+        }
+    }
+}
+
+// Files whose values can all be represented in CREX
+var crexFiles = []string{
+    "contrived.bufr",
+    "ISMD01_OKPR.bufr",
+    "IUSK73_AMMC_182300.bufr",
+}
+
+func TestCrexRoundTrip(t *testing.T) {
+    for _, name := range crexFiles {
+        name := name
+        t.Run(name, func(t *testing.T) {
+            t.Parallel()
+            checkCrexRoundTrip(t, filepath.Join(testdataPath, name))
+        })
+    }
+}
+
+func TestCrexNotRepresentable(t *testing.T) {
+    // Local descriptors have no CREX representation in the WMO tables
+    messages := mustDecode(t, filepath.Join(testdataPath, "amv2_87.bufr"))
+    buf := new(bytes.Buffer)
+    if err := serialize.NewCrexSerializer(buf).Serialize(messages[0]); err == nil {
+        t.Fatal("no error for local descriptor")
+    }
+    if buf.Len() > 0 {
+        t.Errorf("partial message written: %q", buf.String())
+    }
+}
+
+func TestCrexHeader(t *testing.T) {
+    // The update sequence number has only two digits in CREX
+    b := newCrexBuilder(t)
+    if err := b.Set("updateSequenceNumber", 100); err != nil {
+        t.Fatal(err)
+    }
+    b.SetTemplate(12101)
+    b.AddSubset(273.15)
+    message, err := b.Build()
+    if err != nil {
+        t.Fatal(err)
+    }
+    buf := new(bytes.Buffer)
+    err = serialize.NewCrexSerializer(buf).Serialize(message)
+    if err == nil || !strings.Contains(err.Error(), "updateSequenceNumber") {
+        t.Errorf("no error for update sequence number of 3 digits: %v", err)
+    }
+    if buf.Len() > 0 {
+        t.Errorf("partial message written: %q", buf.String())
+    }
+}
+
+func TestCrexDelayedFactors(t *testing.T) {
+    // Factors of any width come with the data in CREX
+    for _, factor := range []table.ID{31000, 31001, 31002} {
+        b := newCrexBuilder(t)
+        b.SetTemplate(101000, factor, 12101)
+        b.AddSubset(1, 273.15)
+        b.AddSubset(0)
+        message, err := b.Build()
+        if err != nil {
+            t.Fatal(err)
+        }
+        buf := new(bytes.Buffer)
+        if err := serialize.NewCrexSerializer(buf).Serialize(message); err != nil {
+            t.Fatal(err)
+        }
+        if strings.Contains(buf.String(), "B31") {
+            t.Errorf("%v: factor descriptor written: %q", factor, buf.String())
+        }
+        checkCrexMessage(t, factor.String(), message)
+    }
+}
+
+// newCrexBuilder returns a builder with a header that fits in CREX
+func newCrexBuilder(t *testing.T) *builder.Builder {
+    b := builder.NewBuilder(newConfig().TablesPath)
+    for name, value := range map[string]int{"originatingCentre": 1, "masterTableVersion": 25, "year": 2018} {
+        if err := b.Set(name, value); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return b
+}
+
+// checkCrexRoundTrip writes all messages of the given file as CREX, reads them back,
+// converts them to BUFR and checks that the messages have the same values.
+func checkCrexRoundTrip(t *testing.T, path string) {
+    for i, message := range mustDecode(t, path) {
+        checkCrexMessage(t, fmt.Sprintf("message %v", i+1), message)
+    }
+}
+
+// checkCrexMessage checks the CREX round trip of a single message
+func checkCrexMessage(t *testing.T, name string, message *bufr.Message) {
+    buf := new(bytes.Buffer)
+    if err := serialize.NewCrexSerializer(buf).Serialize(message); err != nil {
+        t.Fatalf("%v: cannot write CREX: %v", name, err)
+    }
+
+    config := newConfig()
+    config.InputType = tdcfio.CrexInput
+    rt, err := api.NewRuntime(config, tdcfio.NewCrexReader(buf))
+    if err != nil {
+        t.Fatal(err)
+    }
+    decoded, err := rt.Run(context.Background())
+    if err != nil {
+        t.Errorf("%v: cannot read CREX: %v", name, err)
+        return
+    }
+    converted, err := builder.Convert(decoded)
+    if err != nil {
+        t.Errorf("%v: cannot convert to BUFR: %v", name, err)
+        return
+    }
+    // Header fields of CREX differ from the ones of BUFR and factors of delayed
+    // replications are read as 031001
+    for _, d := range diff.Compare(message, converted, &diff.Options{}) {
+        if strings.HasPrefix(d.Path, "section") ||
+            d.Description == "descriptor" && d.B == table.ID(deserialize.CREX_REPLICATION_FACTOR).String() {
+            continue
+        }
+        t.Errorf("%v: %v", name, d)
+    }
+}
+
+// rawMessages returns the bytes of each message found in the data, skipping anything
+// between messages, e.g. GTS headings
+func rawMessages(data []byte) [][]byte {
+    var raws [][]byte
+    for {
+        i := bytes.Index(data, []byte("BUFR"))
+        if i < 0 || i+8 > len(data) {
+            return raws
+        }
+        data = data[i:]
+        length := int(binary.BigEndian.Uint32(data[4:8]) >> 8)
+        if length < 8 || length > len(data) {
+            data = data[4:]
+            continue
+        }
+        raws = append(raws, data[:length])
+        data = data[length:]
+    }
+}
+
+// firstDifferentBit returns the offset of the first different bit of two byte slices
+// or -1 if they are identical
+func firstDifferentBit(a, b []byte) int {
+    for i := 0; i < len(a) && i < len(b); i++ {
+        if x := a[i] ^ b[i]; x != 0 {
+            return i*tdcfio.NBITS_PER_BYTE + bits.LeadingZeros8(x)
+        }
+    }
+    if len(a) != len(b) {
+        n := len(a)
+        if len(b) < n {
+            n = len(b)
+        }
+        return n * tdcfio.NBITS_PER_BYTE
+    }
+    return -1
+}
+
+// span is a range of bits of a message holding a field or a value of the payload
+type span struct {
+    start, stop int
+    section     int
+    field       string
+    // Zero based subset and cell indices of a payload value. The subset is -1
+    // for values of compressed subsets.
+    subset, cell int
+    descriptor   table.Descriptor
+}
+
+// layout lists the bit ranges of all fields and payload values of a message
+// as they were decoded
+type layout struct {
+    spans []span
+    // Start offset of each section
+    sectionStarts map[int]int
+}
+
+func newLayout(message *bufr.Message) *layout {
+    l := &layout{sectionStarts: map[int]int{}}
+    pos := 0
+    for _, section := range message.Sections() {
+        l.sectionStarts[section.Number()] = pos
+        for _, field := range section.Fields() {
+            if payload, ok := field.Value.(*bufr.Payload); ok {
+                l.addPayload(section.Number(), field.Name, pos, payload)
+            }
+            l.spans = append(l.spans, span{start: pos, stop: pos + field.Nbits,
+                section: section.Number(), field: field.Name, subset: -1, cell: -1})
+            pos += field.Nbits
+        }
+    }
+    return l
+}
+
+func (l *layout) addPayload(section int, name string, pos int, payload *bufr.Payload) {
+    add := func(subset, cell int, node *bufr.ValuedNode, nbits int) {
+        l.spans = append(l.spans, span{start: pos, stop: pos + nbits, section: section,
+            field: name, subset: subset, cell: cell, descriptor: node.Descriptor})
+        pos += nbits
+    }
+
+    subsets := payload.Subsets()
+    if !payload.Compressed {
+        for i, subset := range subsets {
+            for j, cell := range subset.Cells() {
+                add(i, j, cell.Node(), cell.Node().PackingInfo.Nbits)
+            }
+        }
+        return
+    }
+
+    if len(subsets) == 0 {
+        return
+    }
+    for j, cell := range subsets[0].Cells() {
+        node := cell.Node()
+        nbits := node.PackingInfo.Nbits
+        if nbits > 0 {
+            nbitsDiff := node.NbitsDiff
+            if node.PackingInfo.Unit == table.STRING {
+                nbitsDiff *= tdcfio.NBITS_PER_BYTE
+            }
+            nbits += pack.NBITS_FOR_NBITS_DIFF + nbitsDiff*len(subsets)
+        }
+        add(-1, j, node, nbits)
+    }
+}
+
+// location describes where a bit is in a message
+type location struct {
+    offset int
+    *layout
+    // The innermost span containing the bit
+    span *span
+}
+
+func (l *layout) locate(offset int) *location {
+    loc := &location{offset: offset, layout: l}
+    for i := range l.spans {
+        s := &l.spans[i]
+        if offset >= s.start && offset < s.stop && (loc.span == nil || s.descriptor != nil) {
+            loc.span = s
+        }
+    }
+    return loc
+}
+
+func (loc *location) isNormalised() bool {
+    return loc.span != nil && loc.span.descriptor == nil && isNormalisedPath(loc.span.field)
+}
+
+func (loc *location) String() string {
+    s := loc.span
+    if s == nil {
+        return "beyond the end of the original message"
+    }
+    str := fmt.Sprintf("section %v, bit %v of the section, field %v",
+        s.section, loc.offset-loc.sectionStarts[s.section], s.field)
+    if s.descriptor != nil {
+        if s.subset >= 0 {
+            str += fmt.Sprintf(", subset %v", s.subset+1)
+        }
+        str += fmt.Sprintf(", value %v, descriptor %v", s.cell+1, s.descriptor.Id())
+    }
+    return str
+}
+
+func isNormalisedPath(path string) bool {
+    for _, name := range normalisedFields {
+        if strings.HasSuffix(path, name) || strings.Contains(path, "/"+name+"#") {
+            return true
+        }
+    }
+    return false
+}
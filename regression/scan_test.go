@@ -0,0 +1,65 @@
+package regression
+
+import (
+    "testing"
+    "bytes"
+    "io/ioutil"
+    "path/filepath"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/index"
+)
+
+// TestScan checks that scanning, which skips the payload of each message, locates
+// the same messages as decoding them in full.
+func TestScan(t *testing.T) {
+    for _, name := range []string{"IUSK73_AMMC_182300.bufr", "ISMD01_OKPR.bufr", "asr3_190.bufr"} {
+        t.Run(name, func(t *testing.T) {
+            checkScan(t, filepath.Join(testdataPath, name))
+        })
+    }
+}
+
+func checkScan(t *testing.T, path string) {
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    config := newConfig()
+    config.Script = api.SCAN_SCRIPT
+    scanned, err := decodeFileWith(config, path)
+    if err != nil {
+        t.Fatalf("cannot scan: %v", err)
+    }
+    decoded := mustDecode(t, path)
+    raws := rawMessages(data)
+    if len(scanned) != len(decoded) || len(scanned) != len(raws) {
+        t.Fatalf("scanned %v messages, decoded %v, found %v", len(scanned), len(decoded), len(raws))
+    }
+
+    for i, message := range scanned {
+        got, err := index.NewEntry(message)
+        if err != nil {
+            t.Fatalf("message %v: %v", i+1, err)
+        }
+        want, err := index.NewEntry(decoded[i])
+        if err != nil {
+            t.Fatalf("message %v: %v", i+1, err)
+        }
+        if got.Offset != want.Offset || got.Length != want.Length {
+            t.Errorf("message %v: scanned offset=%v length=%v, decoded offset=%v length=%v",
+                i+1, got.Offset, got.Length, want.Offset, want.Length)
+            continue
+        }
+
+        sections := decoded[i].Sections()
+        end := sections[len(sections)-1].StartByteIndex + 4
+        if end != got.Offset+got.Length {
+            t.Errorf("message %v: decoded message ends at %v, scanned at %v",
+                i+1, end, got.Offset+got.Length)
+        }
+        if end > len(data) || !bytes.Equal(data[got.Offset:end], raws[i]) {
+            t.Errorf("message %v: bytes at offset %v are not the message", i+1, got.Offset)
+        }
+    }
+}
@@ -0,0 +1,36 @@
+package regression
+
+import (
+    "testing"
+    "context"
+    assert2 "github.com/seanpont/assert"
+)
+
+func TestVirtualFields(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    for _, c := range []struct {
+        name                                          string
+        typicalDateTime, dataCategoryName, centreName string
+    }{
+        {"contrived.bufr", "2016-02-18T23:00:00",
+            "Vertical soundings (other than satellite)", "Melbourne"},
+        // Edition 3 has only the year of century
+        {"207003.bufr", "2012-11-02T00:00:00", "Radiances (satellite measured)",
+            "European Centre for Medium-Range Weather Forecasts (ECMWF)"},
+    } {
+        message, err := newTestRuntime(t, c.name).Run(context.Background())
+        assert.Nil(err)
+        for name, value := range map[string]string{
+            "typicalDateTime":  c.typicalDateTime,
+            "dataCategoryName": c.dataCategoryName,
+            "centreName":       c.centreName,
+        } {
+            field, err := message.ProxyField(name)
+            assert.Nil(err)
+            assert.Equal(field.Value, value)
+            assert.True(field.Virtual)
+            assert.Equal(field.Nbits, 0)
+        }
+    }
+}
@@ -7,6 +7,7 @@ import (
     "github.com/ywangd/gobufrkit/table"
     "fmt"
     "github.com/ywangd/gobufrkit/serialize/pack"
+    "bytes"
 )
 
 type BinaryVisitor struct {
@@ -39,6 +40,10 @@ func (v *BinaryVisitor) VisitSection(section *bufr.Section) error {
 }
 
 func (v *BinaryVisitor) VisitField(field *bufr.Field) error {
+    // Virtual fields have no bits in the message
+    if field.Virtual {
+        return nil
+    }
     var err error
     switch value := field.Value.(type) {
     case uint:
@@ -60,7 +65,7 @@ func (v *BinaryVisitor) VisitField(field *bufr.Field) error {
             v.w.WriteUint(uint(id.Y()), value.Ybits())
         }
     case *bufr.Payload:
-        value.Accept(v)
+        err = value.Accept(v)
     default:
         err = fmt.Errorf("unsupported value type: %T", value)
     }
@@ -71,6 +76,10 @@ func (v *BinaryVisitor) VisitPayload(payload *bufr.Payload) error {
     if payload.Compressed {
         v.packer = pack.NewCompressedPacker(v.w)
         nsubsets := len(payload.Subsets())
+        // Values of compressed subsets are packed by the nodes of the first subset
+        if nsubsets == 0 {
+            return fmt.Errorf("compressed payload has no subset")
+        }
         subset0 := payload.Subset(0)
         ncells := len(subset0.Cells())
         for i := 0; i < ncells; i++ {
@@ -119,3 +128,135 @@ func (v *BinaryVisitor) VisitValuedNode(node *bufr.ValuedNode) error {
 func (v *BinaryVisitor) VisitBlock(block *bufr.Block) error {
     panic("implement me")
 }
+
+// UpdateLengths recomputes the length of each section and the total length of
+// a message after its content is changed, e.g. subsets are added or removed.
+// Padding of a section is kept if the section length still agrees with its
+// content. Otherwise the section is padded to whole bytes, or even number of
+// bytes for edition 3.
+func UpdateLengths(message *bufr.Message) error {
+    _, err := encodeMessage(message)
+    return err
+}
+
+// lengthPatch is a length field whose value is only known after the bits
+// following it are encoded
+type lengthPatch struct {
+    field *bufr.Field
+    pos   int
+}
+
+// encodeMessage encodes a message while updating its lengths as UpdateLengths
+// does. Each section is encoded once and its length fields are patched afterwards.
+func encodeMessage(message *bufr.Message) ([]byte, error) {
+    edition := uint(4)
+    if field, err := message.ProxyField("bufrEditionNumber"); err == nil {
+        edition, _ = field.Value.(uint)
+    }
+
+    var (
+        buf         bytes.Buffer
+        patches     []lengthPatch
+        totalLength *lengthPatch
+        nbytes      int
+    )
+    v := NewBinaryVisitor(&buf)
+    for _, section := range message.Sections() {
+        start := v.w.Pos()
+        var (
+            lengthField *bufr.Field
+            padding     *bufr.Field
+        )
+        for _, field := range section.Fields() {
+            if isPaddingField(field) {
+                padding = field
+                continue
+            }
+            switch field.Name {
+            case "lengthInBytes":
+                lengthField = field
+                patches = append(patches, lengthPatch{field, v.w.Pos()})
+            case "totalLengthInBytes":
+                totalLength = &lengthPatch{field, v.w.Pos()}
+            }
+            if err := field.Accept(v); err != nil {
+                return nil, err
+            }
+        }
+        nbits := v.w.Pos() - start
+
+        if lengthField != nil {
+            length, _ := lengthField.Value.(uint)
+            if nbits+section.Padding != int(length)*tdcfio.NBITS_PER_BYTE {
+                length = uint((nbits + tdcfio.NBITS_PER_BYTE - 1) / tdcfio.NBITS_PER_BYTE)
+                if edition < 4 && length%2 != 0 {
+                    length += 1
+                }
+                var err error
+                if padding, err = setPadding(section, int(length)*tdcfio.NBITS_PER_BYTE-nbits); err != nil {
+                    return nil, err
+                }
+                lengthField.Value = length
+            }
+            nbytes += int(length)
+        } else {
+            nbytes += (nbits + tdcfio.NBITS_PER_BYTE - 1) / tdcfio.NBITS_PER_BYTE
+        }
+        if padding != nil {
+            if err := padding.Accept(v); err != nil {
+                return nil, err
+            }
+        }
+    }
+
+    if totalLength == nil {
+        return nil, fmt.Errorf("message has no total length field")
+    }
+    totalLength.field.Value = uint(nbytes)
+    b := buf.Bytes()
+    for _, patch := range append(patches, *totalLength) {
+        patchUint(b, patch.pos, patch.field.Nbits, patch.field.Value.(uint))
+    }
+    return b, nil
+}
+
+// patchUint overwrites n bits starting at the given bit position with the value
+func patchUint(b []byte, pos int, n int, value uint) {
+    for i := 0; i < n; i++ {
+        p := pos + i
+        mask := byte(1) << uint(tdcfio.NBITS_PER_BYTE-1-p%tdcfio.NBITS_PER_BYTE)
+        if value>>uint(n-1-i)&1 == 1 {
+            b[p/tdcfio.NBITS_PER_BYTE] |= mask
+        } else {
+            b[p/tdcfio.NBITS_PER_BYTE] &^= mask
+        }
+    }
+}
+
+// setPadding replaces the padding of a section with given number of zero bits.
+// It returns the padding field, which is nil if the section has none.
+func setPadding(section *bufr.Section, nbits int) (*bufr.Field, error) {
+    nbytes := (nbits + tdcfio.NBITS_PER_BYTE - 1) / tdcfio.NBITS_PER_BYTE
+    binary, err := tdcfio.NewBinary(make([]byte, nbytes), nbits)
+    if err != nil {
+        return nil, err
+    }
+    section.Padding = nbits
+    for _, field := range section.Fields() {
+        if isPaddingField(field) {
+            field.Value = binary
+            field.Nbits = nbits
+            return field, nil
+        }
+    }
+    if nbits == 0 {
+        return nil, nil
+    }
+    field := bufr.NewHiddenField("padding", binary, nbits)
+    section.AddField(field)
+    return field, nil
+}
+
+func isPaddingField(field *bufr.Field) bool {
+    return field.Hidden && field.Name == "padding"
+}
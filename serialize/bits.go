@@ -0,0 +1,178 @@
+package serialize
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "strconv"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/serialize/pack"
+    "github.com/ywangd/gobufrkit/table"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+// Maximum number of raw bits shown for a field or value. Longer ones are cut.
+const BIT_DUMP_MAX_NBITS = 64
+
+// BitDumper writes the bit layout of messages, i.e. the absolute offset, width and
+// raw bits of every header field and payload value, along with the raw integer, the
+// decoded value and the descriptor. It is mostly useful for locating bad bits produced
+// by encoders.
+//
+// Values of compressed subsets are dumped descriptor by descriptor as they are packed,
+// i.e. the minimum value, the width of increments and the increment of each subset.
+type BitDumper struct {
+    w *bufio.Writer
+    // Show the raw bits in addition to the raw integer
+    ShowBits bool
+}
+
+func NewBitDumper(writer io.Writer) *BitDumper {
+    return &BitDumper{w: bufio.NewWriter(writer)}
+}
+
+// Dump writes the layout of the message by reading its fields and values from the
+// given raw bytes of the message, which start with section 0.
+func (d *BitDumper) Dump(message *bufr.Message, data []byte) error {
+    bd := &bitDump{BitDumper: d, data: data}
+    if err := bd.message(message); err != nil {
+        return err
+    }
+    return d.w.Flush()
+}
+
+// bitDump keeps the bit position while dumping a single message
+type bitDump struct {
+    *BitDumper
+    data []byte
+    pos  int
+}
+
+func (bd *bitDump) message(message *bufr.Message) error {
+    s := fmt.Sprintf("%10s %6s %20s %-12s %-*s value", "offset", "nbits", "raw", "",
+        tdcfio.FLAT_TEXT_DESCRIPTOR_WIDTH, "field")
+    if bd.ShowBits {
+        s = fmt.Sprintf("%-*s bits", len(s)+24-len("value"), s)
+    }
+    fmt.Fprintln(bd.w, s)
+
+    for _, section := range message.Sections() {
+        fmt.Fprintf(bd.w, "<<<<<< section %d >>>>>>\n", section.Number())
+        for _, field := range section.Fields() {
+            if field.Virtual {
+                continue
+            }
+            start := bd.pos
+            payload, ok := field.Value.(*bufr.Payload)
+            if !ok {
+                if err := bd.row(field.Nbits, "", field.Name, field.Value); err != nil {
+                    return err
+                }
+                continue
+            }
+            if err := bd.payload(payload); err != nil {
+                return err
+            }
+            if bd.pos != start+field.Nbits {
+                return fmt.Errorf("payload takes %v bits but its field has %v",
+                    bd.pos-start, field.Nbits)
+            }
+        }
+    }
+    return nil
+}
+
+func (bd *bitDump) payload(payload *bufr.Payload) error {
+    subsets := payload.Subsets()
+    if !payload.Compressed {
+        for i, subset := range subsets {
+            fmt.Fprintf(bd.w, "###### subset %d of %d ######\n", i+1, len(subsets))
+            for _, cell := range subset.Cells() {
+                if err := bd.row(cell.Node().PackingInfo.Nbits, "",
+                    fmt.Sprint(cell.Node().Descriptor), cell.Value()); err != nil {
+                    return err
+                }
+            }
+        }
+        return nil
+    }
+
+    fmt.Fprintf(bd.w, "###### %d compressed subsets ######\n", len(subsets))
+    if len(subsets) == 0 {
+        return nil
+    }
+    for j, cell := range subsets[0].Cells() {
+        node := cell.Node()
+        label := fmt.Sprint(node.Descriptor)
+        nbits := node.PackingInfo.Nbits
+        if nbits == 0 {
+            continue
+        }
+        if err := bd.row(nbits, "min", label, node.MinValue); err != nil {
+            return err
+        }
+        if err := bd.row(pack.NBITS_FOR_NBITS_DIFF, "nbitsDiff", label, node.NbitsDiff); err != nil {
+            return err
+        }
+        nbitsDiff := node.NbitsDiff
+        if node.PackingInfo.Unit == table.STRING {
+            nbitsDiff *= tdcfio.NBITS_PER_BYTE
+        }
+        if nbitsDiff == 0 {
+            continue
+        }
+        for k, subset := range subsets {
+            if err := bd.row(nbitsDiff, fmt.Sprintf("subset %d", k+1), label,
+                subset.Cell(j).Value()); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// row writes a line for the given number of bits at the current position and moves
+// the position past them
+func (bd *bitDump) row(nbits int, role string, label string, value interface{}) error {
+    if bd.pos+nbits > len(bd.data)*tdcfio.NBITS_PER_BYTE {
+        return fmt.Errorf("bits %v-%v of %v are beyond the end of the message",
+            bd.pos, bd.pos+nbits, label)
+    }
+    bits := bd.bits(nbits)
+    raw := ""
+    if nbits > 0 && nbits <= BIT_DUMP_MAX_NBITS {
+        x, _ := strconv.ParseUint(bits, 2, 64)
+        raw = strconv.FormatUint(x, 10)
+    }
+    if len(bits) > BIT_DUMP_MAX_NBITS {
+        bits = bits[:BIT_DUMP_MAX_NBITS] + "..."
+    }
+    if len(label) > tdcfio.FLAT_TEXT_DESCRIPTOR_WIDTH {
+        label = label[:tdcfio.FLAT_TEXT_DESCRIPTOR_WIDTH-3] + "..."
+    }
+
+    var s string
+    if b, ok := value.([]byte); ok {
+        s = fmt.Sprintf("%q", string(b))
+    } else {
+        s = fmt.Sprintf("%v", value)
+    }
+    if bd.ShowBits {
+        s = fmt.Sprintf("%-24s %s", s, bits)
+    }
+    s = fmt.Sprintf("%10d %6d %20s %-12s %-*s %s", bd.pos, nbits, raw, role,
+        tdcfio.FLAT_TEXT_DESCRIPTOR_WIDTH, label, s)
+    _, err := fmt.Fprintln(bd.w, s)
+    bd.pos += nbits
+    return err
+}
+
+// bits returns the given number of bits at the current position as a string of 0 and 1
+func (bd *bitDump) bits(nbits int) string {
+    b := make([]byte, nbits)
+    for i := range b {
+        pos := bd.pos + i
+        b[i] = '0' + (bd.data[pos/tdcfio.NBITS_PER_BYTE]>>uint(7-pos%tdcfio.NBITS_PER_BYTE))&1
+    }
+    return string(b)
+}
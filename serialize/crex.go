@@ -0,0 +1,212 @@
+package serialize
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "math"
+    "strconv"
+    "strings"
+    "github.com/pkg/errors"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/table"
+    "github.com/ywangd/gobufrkit/tdcfio"
+    "github.com/ywangd/gobufrkit/units"
+)
+
+// Line ending of CREX messages as in other alphanumeric codes
+const CREX_LINE_END = "\r\r\n"
+
+// CrexSerializer writes messages in the CREX edition 2 format. Messages can be
+// decoded from BUFR or CREX as the header groups are written from proxy fields,
+// e.g. originatingCentre and year, and values are converted to the units, scales
+// and widths of the CREX columns of Table B. Characters that cannot be printed
+// are written as spaces.
+type CrexSerializer struct {
+    w io.Writer
+}
+
+func NewCrexSerializer(writer io.Writer) *CrexSerializer {
+    return &CrexSerializer{w: writer}
+}
+
+func (s *CrexSerializer) Serialize(message *bufr.Message) error {
+    field, err := message.ProxyField("unexpandedTemplate")
+    if err != nil {
+        return err
+    }
+    ut, ok := field.Value.(*table.UnexpandedTemplate)
+    if !ok {
+        return fmt.Errorf("invalid unexpanded template: %T", field.Value)
+    }
+    if field, err = message.ProxyField("payload"); err != nil {
+        return err
+    }
+    payload, ok := field.Value.(*bufr.Payload)
+    if !ok {
+        return fmt.Errorf("invalid payload: %T", field.Value)
+    }
+
+    // Nothing is written unless the whole message can be represented
+    w := new(bytes.Buffer)
+    w.WriteString("CREX++" + CREX_LINE_END)
+
+    // Groups of the header have fixed numbers of digits
+    var headerErr error
+    digits := func(name string, value uint, n int) string {
+        s := fmt.Sprintf("%0*d", n, value)
+        if len(s) > n && headerErr == nil {
+            headerErr = fmt.Errorf("%v of %v does not fit in the %v digits of the CREX header", name, value, n)
+        }
+        return s
+    }
+    value := func(name string, n int) string {
+        return digits(name, proxyUint(message, name), n)
+    }
+    w.WriteString("T" + value("masterTableNumber", 2) + digits("edition", 2, 2) +
+        value("masterTableVersion", 2) + value("localTableVersion", 2))
+    w.WriteString(" A" + value("dataCategory", 3) + value("dataI18nSubCategory", 3))
+    w.WriteString(" " + value("originatingCentre", 5) + value("originatingSubCentre", 5))
+    w.WriteString(" U" + value("updateSequenceNumber", 2))
+    w.WriteString(" S" + digits("nSubsets", uint(len(payload.Subsets())), 3))
+    w.WriteString(" Y" + digits("year", bufr.FullYear(proxyUint(message, "year")), 4) +
+        value("month", 2) + value("day", 2))
+    w.WriteString(" H" + value("hour", 2) + value("minute", 2))
+    if headerErr != nil {
+        return headerErr
+    }
+    for _, d := range crexDescriptors(ut.Ids()) {
+        w.WriteString(" " + d)
+    }
+    w.WriteString("++" + CREX_LINE_END)
+
+    subsets := payload.Subsets()
+    for i, subset := range subsets {
+        for j, cell := range subset.Cells() {
+            group, err := crexGroup(cell)
+            if err != nil {
+                return errors.Wrapf(err, "subset %v, value %v", i+1, j+1)
+            }
+            if j > 0 {
+                w.WriteString(" ")
+            }
+            w.WriteString(group)
+        }
+        if i+1 < len(subsets) {
+            w.WriteString("+" + CREX_LINE_END)
+        }
+    }
+    w.WriteString("++" + CREX_LINE_END)
+    w.WriteString("7777" + CREX_LINE_END)
+    _, err = s.w.Write(w.Bytes())
+    return err
+}
+
+// proxyUint returns the value of the proxy field of the given name or zero if the
+// message has no such field
+func proxyUint(message *bufr.Message, name string) uint {
+    field, err := message.ProxyField(name)
+    if err != nil {
+        return 0
+    }
+    x, err := tdcfio.ValueToFloat64(field.Value)
+    if err != nil || x < 0 {
+        return 0
+    }
+    return uint(x)
+}
+
+// crexDescriptors returns the descriptors of the template as text, e.g. D07022. The
+// delayed replication and repetition factor descriptors following delayed replications
+// are left out as the factors come with the data in CREX.
+func crexDescriptors(ids []table.ID) []string {
+    var ds []string
+    for i := 0; i < len(ids); i++ {
+        id := ids[i]
+        ds = append(ds, fmt.Sprintf("%c%02d%03d", tdcfio.CREX_DESCRIPTOR_LETTERS[id.F()], id.X(), id.Y()))
+        if id.F() == table.F_REPLICATION && id.Y() == 0 &&
+            i+1 < len(ids) && ids[i+1].IsDelayedFactor() {
+            i++
+        }
+    }
+    return ds
+}
+
+// crexGroup returns the value of the cell as a group of characters
+func crexGroup(cell *bufr.Cell) (string, error) {
+    node := cell.Node()
+    info := node.PackingInfo
+    ci, err := units.NewCrexInfo(node.Descriptor, info)
+    if err != nil {
+        return "", err
+    }
+
+    if info.Unit == table.STRING {
+        return crexText(cell.Value(), ci.Nchars), nil
+    }
+    if cell.Value() == nil {
+        return strings.Repeat("/", ci.Nchars), nil
+    }
+    x, err := cell.FloatValue()
+    if err != nil {
+        return "", err
+    }
+
+    var digits string
+    switch info.Unit {
+    case table.FLAG:
+        digits = strconv.FormatUint(uint64(x), 8)
+    case table.CODE, table.NONNEG_CODE:
+        digits = strconv.FormatInt(int64(x), 10)
+    case table.NUMERIC:
+        c, err := units.CrexConversion(ci.UnitString, info.UnitString)
+        if err != nil {
+            return "", err
+        }
+        if c != nil {
+            x = c.Inverse().Convert(x)
+        }
+        digits = strconv.FormatInt(int64(math.Round(x*math.Pow10(ci.Scale))), 10)
+    default:
+        return "", fmt.Errorf("unsupported unit for CREX: %v", info.Unit)
+    }
+
+    // The minus sign does not count in the width
+    sign := ""
+    if strings.HasPrefix(digits, "-") {
+        sign, digits = "-", digits[1:]
+    }
+    if len(digits) > ci.Nchars {
+        return "", fmt.Errorf("value %v of %v does not fit in %v characters",
+            cell.Value(), node.Descriptor.Id(), ci.Nchars)
+    }
+    return sign + strings.Repeat("0", ci.Nchars-len(digits)) + digits, nil
+}
+
+// crexText returns a character value padded or cut to n characters. A missing value,
+// i.e. all bits set, is written as slashes.
+func crexText(value interface{}, n int) string {
+    var b []byte
+    switch v := value.(type) {
+    case []byte:
+        b = v
+    case string:
+        b = []byte(v)
+    }
+    missing := len(b) > 0
+    for _, c := range b {
+        missing = missing && c == 0xff
+    }
+    if value == nil || missing {
+        return strings.Repeat("/", n)
+    }
+
+    text := make([]byte, n)
+    for i := range text {
+        text[i] = ' '
+        if i < len(b) && b[i] >= ' ' && b[i] <= '~' {
+            text[i] = b[i]
+        }
+    }
+    return string(text)
+}
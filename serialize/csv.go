@@ -0,0 +1,91 @@
+package serialize
+
+import (
+    "encoding/csv"
+    "fmt"
+    "io"
+    "strconv"
+    "github.com/ywangd/gobufrkit/bufr"
+)
+
+// CsvSerializer writes the payload cells of messages as CSV records, one
+// record per cell. A header record is written before the first message.
+type CsvSerializer struct {
+    w      *csv.Writer
+    config *Config
+
+    headerWritten bool
+    nmessages     int
+}
+
+func NewCsvSerializer(writer io.Writer, config *Config) *CsvSerializer {
+    return &CsvSerializer{w: csv.NewWriter(writer), config: config}
+}
+
+func (s *CsvSerializer) Serialize(message *bufr.Message) error {
+    s.nmessages += 1
+    if !s.headerWritten {
+        header := []string{"message", "subset", "index", "id", "name", "value", "unit"}
+        if s.config.ShowPacking {
+            header = append(header, "packed", "scale", "refval", "nbits")
+        }
+        if err := s.w.Write(header); err != nil {
+            return err
+        }
+        s.headerWritten = true
+    }
+
+    for _, section := range message.Sections() {
+        for _, field := range section.Fields() {
+            payload, ok := field.Value.(*bufr.Payload)
+            if !ok {
+                continue
+            }
+            for _, subset := range payload.Subsets() {
+                for i, cell := range subset.Cells() {
+                    if err := s.w.Write(s.record(subset.Index(), i, cell)); err != nil {
+                        return err
+                    }
+                }
+            }
+        }
+    }
+    s.w.Flush()
+    return s.w.Error()
+}
+
+func (s *CsvSerializer) record(isubset, index int, cell *bufr.Cell) []string {
+    descriptor := cell.Node().Descriptor
+    value, unit := cellValue(cell, s.config.Units)
+    record := []string{
+        strconv.Itoa(s.nmessages),
+        strconv.Itoa(isubset + 1),
+        strconv.Itoa(index + 1),
+        descriptor.Id().String(),
+        descriptorName(descriptor),
+        csvValue(value),
+        unit,
+    }
+    if s.config.ShowPacking {
+        info := cell.Node().PackingInfo
+        packed := ""
+        if v, err := cell.PackedValue(); err == nil {
+            packed = strconv.FormatUint(uint64(v), 10)
+        }
+        record = append(record, packed, strconv.Itoa(info.Scale),
+            fmt.Sprint(info.Refval), strconv.Itoa(info.Nbits))
+    }
+    return record
+}
+
+// csvValue formats a cell value for CSV. Missing values are empty strings.
+func csvValue(value interface{}) string {
+    switch v := value.(type) {
+    case nil:
+        return ""
+    case []byte:
+        return string(v)
+    default:
+        return fmt.Sprint(v)
+    }
+}
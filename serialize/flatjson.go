@@ -67,3 +67,59 @@ func (v *FlatJsonVisitor) VisitValuedNode(node *bufr.ValuedNode) error {
 func (v *FlatJsonVisitor) VisitBlock(block *bufr.Block) error {
     panic("implement me")
 }
+
+// FlatJsonSerializer serializes a message as bare JSON, i.e. nested lists of values
+// without names or attributes. A message is a list of sections, each of which is a
+// list of field values. The payload is a list of subsets, each of which is a list
+// of cell values. This is also the format read by tdcfio.FlatJsonReader, so virtual
+// fields are left out as they are computed again when the values are read back.
+type FlatJsonSerializer struct {
+    enc    *json.Encoder
+    config *Config
+}
+
+func NewFlatJsonSerializer(writer io.Writer, config *Config) *FlatJsonSerializer {
+    return &FlatJsonSerializer{enc: json.NewEncoder(writer), config: config}
+}
+
+func (s *FlatJsonSerializer) Serialize(message *bufr.Message) error {
+    return s.enc.Encode(s.Values(message))
+}
+
+// Values returns the bare JSON form of the message before it is encoded
+func (s *FlatJsonSerializer) Values(message *bufr.Message) []interface{} {
+    sections := []interface{}{}
+    for _, section := range message.Sections() {
+        fields := []interface{}{}
+        for _, field := range section.Fields() {
+            if (field.Hidden && !s.config.ShowHidden) || field.Virtual {
+                continue
+            }
+            fields = append(fields, s.fieldValue(field))
+        }
+        sections = append(sections, fields)
+    }
+    return sections
+}
+
+func (s *FlatJsonSerializer) fieldValue(field *bufr.Field) interface{} {
+    switch value := field.Value.(type) {
+    case []byte:
+        return string(value)
+    case *bufr.Payload:
+        subsets := []interface{}{}
+        for _, subset := range value.Subsets() {
+            values := make([]interface{}, len(subset.Cells()))
+            for i, cell := range subset.Cells() {
+                values[i], _ = cellValue(cell, s.config.Units)
+                if b, ok := values[i].([]byte); ok {
+                    values[i] = string(b)
+                }
+            }
+            subsets = append(subsets, values)
+        }
+        return subsets
+    default:
+        return value
+    }
+}
@@ -4,12 +4,18 @@ import (
     "github.com/ywangd/gobufrkit/bufr"
     "io"
     "fmt"
+    "github.com/ywangd/gobufrkit/units"
+    "github.com/ywangd/gobufrkit/tdcfio"
 )
 
 type FlatTextVisitor struct {
     w io.Writer
 
     ShowHidden bool
+    // Show the packed integer and packing parameters after each cell value
+    ShowPacking bool
+    // Convert values to this unit system if it is not nil
+    Units *units.System
 }
 
 func NewFlatTextVisitor(w io.Writer) *FlatTextVisitor {
@@ -45,6 +51,10 @@ func (v *FlatTextVisitor) VisitField(field *bufr.Field) error {
     case *bufr.Payload:
         return value.Accept(v)
     default:
+        if field.Virtual {
+            _, err := fmt.Fprintf(v.w, "%s%s = %v\n", tdcfio.VIRTUAL_FIELD_PREFIX, field.Name, field.Value)
+            return err
+        }
         _, err := fmt.Fprintf(v.w, "%s = %v\n", field.Name, field.Value)
         return err
     }
@@ -77,7 +87,7 @@ func (v *FlatTextVisitor) VisitSubset(subset *bufr.Subset) error {
 
 func (v *FlatTextVisitor) VisitCell(cell *bufr.Cell) error {
     var s string
-    value := cell.Value()
+    value, unit := cellValue(cell, v.Units)
     switch value.(type) {
     case []byte:
         s = fmt.Sprintf("%q", string(value.([]byte)))
@@ -85,10 +95,38 @@ func (v *FlatTextVisitor) VisitCell(cell *bufr.Cell) error {
         s = fmt.Sprintf("%v", value)
     }
 
-    _, err := fmt.Fprintf(v.w, "%-60s%v\n", cell.Node().Descriptor, s)
+    if unit != "" {
+        s = fmt.Sprintf("%-20s %s", s, unit)
+    }
+    if v.ShowPacking {
+        s = fmt.Sprintf("%-40s %s", s, packingString(cell))
+    }
+
+    _, err := fmt.Fprintf(v.w, "%-*s %v\n", tdcfio.FLAT_TEXT_DESCRIPTOR_WIDTH, descriptorColumn(cell), s)
     return err
 }
 
+// descriptorColumn returns the descriptor of the cell cut to the width of its column
+// so that values always start at the same column, as expected by tdcfio.FlatTextReader.
+func descriptorColumn(cell *bufr.Cell) string {
+    s := fmt.Sprint(cell.Node().Descriptor)
+    if len(s) > tdcfio.FLAT_TEXT_DESCRIPTOR_WIDTH {
+        s = s[:tdcfio.FLAT_TEXT_DESCRIPTOR_WIDTH-3] + "..."
+    }
+    return s
+}
+
+// packingString describes the packed integer and packing parameters of the given cell
+func packingString(cell *bufr.Cell) string {
+    info := cell.Node().PackingInfo
+    packed, err := cell.PackedValue()
+    if err != nil {
+        return fmt.Sprintf("[nbits=%d]", info.Nbits)
+    }
+    return fmt.Sprintf("[packed=%d scale=%d refval=%v nbits=%d]",
+        packed, info.Scale, info.Refval, info.Nbits)
+}
+
 func (v *FlatTextVisitor) VisitValuelessNode(node *bufr.ValuelessNode) error {
     panic("implement me")
 }
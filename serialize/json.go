@@ -0,0 +1,126 @@
+package serialize
+
+import (
+    "encoding/json"
+    "io"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/table"
+)
+
+// jsonCell is the attributed JSON form of a bufr.Cell
+type jsonCell struct {
+    Id     string      `json:"id"`
+    Name   string      `json:"name"`
+    Value  interface{} `json:"value"`
+    Unit   string      `json:"unit,omitempty"`
+    Packed *uint       `json:"packed,omitempty"`
+    Scale  *int        `json:"scale,omitempty"`
+    Refval *float64    `json:"refval,omitempty"`
+    Nbits  *int        `json:"nbits,omitempty"`
+}
+
+type jsonPayload struct {
+    Compressed bool         `json:"compressed"`
+    Subsets    [][]jsonCell `json:"subsets"`
+}
+
+type jsonField struct {
+    Name    string      `json:"name"`
+    Value   interface{} `json:"value"`
+    Virtual bool        `json:"virtual,omitempty"`
+}
+
+type jsonSection struct {
+    Number      int         `json:"number"`
+    Description interface{} `json:"description"`
+    Fields      []jsonField `json:"fields"`
+}
+
+// JsonSerializer serializes a message as a JSON document with each value
+// attributed, i.e. payload cells come with their descriptor, name and unit.
+type JsonSerializer struct {
+    enc    *json.Encoder
+    config *Config
+}
+
+func NewJsonSerializer(writer io.Writer, config *Config) *JsonSerializer {
+    return &JsonSerializer{enc: json.NewEncoder(writer), config: config}
+}
+
+func (s *JsonSerializer) Serialize(message *bufr.Message) error {
+    sections := []jsonSection{}
+    for _, section := range message.Sections() {
+        jsec := jsonSection{
+            Number:      section.Number(),
+            Description: section.Metadata("description"),
+            Fields:      []jsonField{},
+        }
+        for _, field := range section.Fields() {
+            if field.Hidden && !s.config.ShowHidden {
+                continue
+            }
+            jsec.Fields = append(jsec.Fields, jsonField{
+                Name: field.Name, Value: s.fieldValue(field), Virtual: field.Virtual,
+            })
+        }
+        sections = append(sections, jsec)
+    }
+    return s.enc.Encode(map[string]interface{}{"sections": sections})
+}
+
+func (s *JsonSerializer) fieldValue(field *bufr.Field) interface{} {
+    switch value := field.Value.(type) {
+    case []byte:
+        return string(value)
+    case *bufr.Payload:
+        return s.payload(value)
+    default:
+        return value
+    }
+}
+
+func (s *JsonSerializer) payload(payload *bufr.Payload) *jsonPayload {
+    jpay := &jsonPayload{Compressed: payload.Compressed, Subsets: [][]jsonCell{}}
+    for _, subset := range payload.Subsets() {
+        cells := make([]jsonCell, len(subset.Cells()))
+        for i, cell := range subset.Cells() {
+            cells[i] = s.cell(cell)
+        }
+        jpay.Subsets = append(jpay.Subsets, cells)
+    }
+    return jpay
+}
+
+func (s *JsonSerializer) cell(cell *bufr.Cell) jsonCell {
+    descriptor := cell.Node().Descriptor
+    value, unit := cellValue(cell, s.config.Units)
+    jc := jsonCell{
+        Id:    descriptor.Id().String(),
+        Name:  descriptorName(descriptor),
+        Value: value,
+        Unit:  unit,
+    }
+    if b, ok := jc.Value.([]byte); ok {
+        jc.Value = string(b)
+    }
+    if s.config.ShowPacking {
+        info := cell.Node().PackingInfo
+        if packed, err := cell.PackedValue(); err == nil {
+            jc.Packed = &packed
+        }
+        jc.Scale, jc.Refval, jc.Nbits = &info.Scale, &info.Refval, &info.Nbits
+    }
+    return jc
+}
+
+// descriptorName returns the name of the given descriptor. Decorated descriptors,
+// e.g. associated fields, have their own names.
+func descriptorName(descriptor table.Descriptor) string {
+    if dd, ok := descriptor.(*table.DecorateDescriptor); ok {
+        return dd.Name
+    }
+    if entry := descriptor.Entry(); entry != nil {
+        return entry.Name()
+    }
+    return ""
+}
@@ -5,7 +5,7 @@ import (
     "github.com/ywangd/gobufrkit/table"
     "github.com/ywangd/gobufrkit/tdcfio"
     "fmt"
-    "math"
+    "math/bits"
 )
 
 type Packer interface {
@@ -21,10 +21,6 @@ func NewUncompressedPacker(w tdcfio.Writer) Packer {
 }
 
 func (p *UncompressedPacker) Pack(node *bufr.ValuedNode, value interface{}) error {
-    var (
-        err error
-        ok  bool
-    )
     info := node.PackingInfo
 
     switch info.Unit {
@@ -35,51 +31,38 @@ func (p *UncompressedPacker) Pack(node *bufr.ValuedNode, value interface{}) erro
         }
         return p.w.WriteBytes(v, info.Nbits/tdcfio.NBITS_PER_BYTE)
 
-    case table.NONNEG_CODE, table.FLAG:
-        var v uint
+    case table.CODE:
         if value == nil {
-            v, err = bufr.MissingValue(info.Nbits)
-            if err != nil {
-                return err
-            }
-        } else {
-            v, ok = value.(uint)
-            if !ok {
+            return p.writeMissing(info.Nbits)
+        }
+        v, ok := value.(int)
+        if !ok {
+            return fmt.Errorf("value is not an int: %v", value)
+        }
+        return p.w.WriteInt(v, info.Nbits)
+
+    case table.NONNEG_CODE, table.FLAG:
+        // Zero bits data, e.g. data not present, takes no space
+        if info.Nbits == 0 {
+            return nil
+        }
+        if value != nil {
+            if _, ok := value.(uint); !ok {
                 return fmt.Errorf("value is not an uint: %v", value)
             }
         }
+        v, err := bufr.NewCell(node, value).PackedValue()
+        if err != nil {
+            return err
+        }
         return p.w.WriteUint(v, info.Nbits)
 
     case table.NUMERIC:
-        if value == nil {
-            v, err := bufr.MissingValue(info.Nbits)
-            if err != nil {
-                return err
-            }
-            return p.w.WriteUint(v, info.Nbits)
-        } else {
-            switch value.(type) {
-            case int:
-                if info.Refval != 0 || info.Scale != 0 {
-                    return fmt.Errorf(
-                        "integer numeric value must have zero for both refval (%v) and scale (%v)",
-                        info.Refval, info.Scale)
-                }
-                return p.w.WriteInt(value.(int), info.Nbits)
-
-            case float64:
-                xfloat := value.(float64)
-                if info.Scale != 0 {
-                    xfloat *= math.Pow10(info.Scale)
-                }
-                if info.Refval != 0 {
-                    xfloat -= info.Refval
-                }
-                return p.w.WriteUint(uint(xfloat), info.Nbits)
-            default:
-                return fmt.Errorf("invalid data type for numeric value: %v", value)
-            }
+        v, err := bufr.NewCell(node, value).PackedValue()
+        if err != nil {
+            return err
         }
+        return p.w.WriteUint(v, info.Nbits)
 
     case table.BINARY:
         v, ok := value.(*tdcfio.Binary)
@@ -91,12 +74,24 @@ func (p *UncompressedPacker) Pack(node *bufr.ValuedNode, value interface{}) erro
     default:
         return fmt.Errorf("unrecognised data unit: %v", info.Unit)
     }
+}
 
-    return nil
+func (p *UncompressedPacker) writeMissing(nbits int) error {
+    v, err := bufr.MissingValue(nbits)
+    if err != nil {
+        return err
+    }
+    return p.w.WriteUint(v, nbits)
 }
 
 const NBITS_FOR_NBITS_DIFF = 6
 
+// CompressedPacker packs values of all subsets for a node in the compressed form,
+// i.e. a minimum value, the number of bits of the differences and the differences.
+//
+// The minimum value and number of bits recorded on the node during decoding are
+// reused if they can still represent the values, so that an unchanged message is
+// encoded to the same bytes. Otherwise they are computed from the values.
 type CompressedPacker struct {
     w tdcfio.Writer
 }
@@ -106,63 +101,239 @@ func NewCompressedPacker(w tdcfio.Writer) Packer {
 }
 
 func (p *CompressedPacker) Pack(node *bufr.ValuedNode, values interface{}) error {
+    vs, ok := values.([]interface{})
+    if !ok {
+        return fmt.Errorf("compressed values must be a list: %T", values)
+    }
 
     info := node.PackingInfo
-    // TODO: type assertion checking
     switch info.Unit {
     case table.STRING:
-        p.w.WriteBytes(node.MinValue.([]byte), info.Nbits/tdcfio.NBITS_PER_BYTE)
-        p.w.WriteInt(node.NbitsDiff, NBITS_FOR_NBITS_DIFF)
-        // NbitsDiff is actually nbytes for string type
-        if node.NbitsDiff != 0 {
-            for _, v := range values.([]interface{}) {
-                p.w.WriteBytes(v.([]byte), node.NbitsDiff)
-            }
-        }
+        return p.packString(info, vs)
 
-    case table.NONNEG_CODE, table.FLAG:
-        p.w.WriteUint(node.MinValue.(uint), info.Nbits)
-        p.w.WriteInt(node.NbitsDiff, NBITS_FOR_NBITS_DIFF)
-        if node.NbitsDiff != 0 {
-            for _, v := range values.([]interface{}) {
-                p.w.WriteUint(v.(uint)-node.MinValue.(uint), node.NbitsDiff)
-            }
-        }
+    case table.CODE:
+        return p.packCode(info, vs)
 
-    case table.NUMERIC:
-        p.w.WriteUint(node.MinValue.(uint), info.Nbits)
-        p.w.WriteInt(node.NbitsDiff, NBITS_FOR_NBITS_DIFF)
-        if node.NbitsDiff != 0 {
-            for _, v := range values.([]interface{}) {
-                switch {
-                case v == nil:
-                    xuint, _ := bufr.MissingValue(node.NbitsDiff)
-                    p.w.WriteUint(xuint, node.NbitsDiff)
-
-                case info.Refval != 0 || info.Scale != 0:
-                    xfloat := v.(float64)
-                    if info.Scale != 0 {
-                        xfloat *= math.Pow10(info.Scale)
-                    }
-                    if info.Refval != 0 {
-                        xfloat -= info.Refval
-                    }
-                    p.w.WriteUint(uint(xfloat)-node.MinValue.(uint), node.NbitsDiff)
-
-                default:
-                    xint := v.(int)
-                    p.w.WriteUint(uint(xint)-node.MinValue.(uint), node.NbitsDiff)
-                }
-            }
+    case table.NONNEG_CODE, table.FLAG:
+        if info.Nbits == 0 {
+            return nil
         }
+        return p.packOthers(node, vs)
 
-    case table.BINARY:
-        // TODO:
-        panic("NYI")
+    case table.NUMERIC, table.BINARY:
+        return p.packOthers(node, vs)
 
     default:
         return fmt.Errorf("unrecognised data unit: %v", info.Unit)
     }
+}
+
+func (p *CompressedPacker) packString(info *bufr.PackingInfo, values []interface{}) error {
+    nbytes := info.Nbits / tdcfio.NBITS_PER_BYTE
+    strs := make([][]byte, len(values))
+    same := true
+    for i, v := range values {
+        s, ok := v.([]byte)
+        if !ok {
+            return fmt.Errorf("value is not a string: %v", v)
+        }
+        strs[i] = s
+        if string(s) != string(strs[0]) {
+            same = false
+        }
+    }
+
+    if same && len(strs) > 0 {
+        if err := p.w.WriteBytes(strs[0], nbytes); err != nil {
+            return err
+        }
+        return p.w.WriteUint(0, NBITS_FOR_NBITS_DIFF)
+    }
+
+    // Different strings are packed with an empty minimum value and the
+    // number of bytes in place of number of bits
+    if nbytes >= 1<<NBITS_FOR_NBITS_DIFF {
+        return fmt.Errorf("cannot compress different strings of %v bytes", nbytes)
+    }
+    if err := p.w.WriteBytes(nil, nbytes); err != nil {
+        return err
+    }
+    if err := p.w.WriteUint(uint(nbytes), NBITS_FOR_NBITS_DIFF); err != nil {
+        return err
+    }
+    for _, s := range strs {
+        if err := p.w.WriteBytes(s, nbytes); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (p *CompressedPacker) packCode(info *bufr.PackingInfo, values []interface{}) error {
+    var (
+        ints    []int
+        missing bool
+    )
+    for _, v := range values {
+        if v == nil {
+            missing = true
+            continue
+        }
+        x, ok := v.(int)
+        if !ok {
+            return fmt.Errorf("value is not an int: %v", v)
+        }
+        ints = append(ints, x)
+    }
+    if len(ints) == 0 {
+        return p.writeAllMissing(info.Nbits)
+    }
 
+    min, max := ints[0], ints[0]
+    for _, x := range ints {
+        if x < min {
+            min = x
+        }
+        if x > max {
+            max = x
+        }
+    }
+
+    if err := p.w.WriteInt(min, info.Nbits); err != nil {
+        return err
+    }
+    nbitsDiff := nbitsForDiff(uint(max-min), missing)
+    if err := p.w.WriteUint(uint(nbitsDiff), NBITS_FOR_NBITS_DIFF); err != nil {
+        return err
+    }
+    if nbitsDiff == 0 {
+        return nil
+    }
+    for _, v := range values {
+        diff, _ := bufr.MissingValue(nbitsDiff)
+        if v != nil {
+            diff = uint(v.(int) - min)
+        }
+        if err := p.w.WriteUint(diff, nbitsDiff); err != nil {
+            return err
+        }
+    }
     return nil
 }
+
+// packOthers packs values which have an unsigned integer form
+func (p *CompressedPacker) packOthers(node *bufr.ValuedNode, values []interface{}) error {
+    info := node.PackingInfo
+    var (
+        packed  = make([]uint, len(values))
+        isValid = make([]bool, len(values))
+        missing bool
+        min     uint
+        max     uint
+        found   bool
+    )
+    for i, v := range values {
+        if v == nil {
+            missing = true
+            continue
+        }
+        x, err := packedValue(node, v)
+        if err != nil {
+            return err
+        }
+        packed[i], isValid[i] = x, true
+        if !found || x < min {
+            min = x
+        }
+        if !found || x > max {
+            max = x
+        }
+        found = true
+    }
+    if !found {
+        return p.writeAllMissing(info.Nbits)
+    }
+
+    nbitsDiff := nbitsForDiff(max-min, missing)
+    // Prefer what the values were decoded with if still applicable
+    if originalMin, ok := node.MinValue.(uint); ok && node.NbitsDiff > 0 && originalMin <= min &&
+        fitsDiff(max-originalMin, node.NbitsDiff, missing) {
+        min, nbitsDiff = originalMin, node.NbitsDiff
+    }
+
+    if err := p.w.WriteUint(min, info.Nbits); err != nil {
+        return err
+    }
+    if err := p.w.WriteUint(uint(nbitsDiff), NBITS_FOR_NBITS_DIFF); err != nil {
+        return err
+    }
+    if nbitsDiff == 0 {
+        return nil
+    }
+    for i := range values {
+        diff, _ := bufr.MissingValue(nbitsDiff)
+        if isValid[i] {
+            diff = packed[i] - min
+        }
+        if err := p.w.WriteUint(diff, nbitsDiff); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// writeAllMissing writes a missing minimum value with zero bits of differences
+func (p *CompressedPacker) writeAllMissing(nbits int) error {
+    v, err := bufr.MissingValue(nbits)
+    if err != nil {
+        return err
+    }
+    if err := p.w.WriteUint(v, nbits); err != nil {
+        return err
+    }
+    return p.w.WriteUint(0, NBITS_FOR_NBITS_DIFF)
+}
+
+// nbitsForDiff returns the minimum number of bits for differences up to maxDiff.
+// A difference must not be mistaken as missing value, i.e. all bits set, which
+// requires at least 2 bits to represent.
+func nbitsForDiff(maxDiff uint, missing bool) int {
+    if maxDiff == 0 && !missing {
+        return 0
+    }
+    nbits := bits.Len(maxDiff)
+    for !fitsDiff(maxDiff, nbits, missing) {
+        nbits++
+    }
+    return nbits
+}
+
+// fitsDiff checks whether differences up to maxDiff and optionally missing values
+// can be represented with the given number of bits
+func fitsDiff(maxDiff uint, nbits int, missing bool) bool {
+    if nbits < 1 || nbits > 64 || (missing && nbits < 2) {
+        return false
+    }
+    if nbits == 1 {
+        return maxDiff <= 1
+    }
+    return maxDiff < bufr.MISSING_VALUE_OF_NBITS[nbits]
+}
+
+// packedValue returns the unsigned integer form of a non-missing value
+func packedValue(node *bufr.ValuedNode, value interface{}) (uint, error) {
+    if binary, ok := value.(*tdcfio.Binary); ok {
+        if binary.Nbits() > 64 {
+            return 0, fmt.Errorf("binary value too long to be compressed: %v bits", binary.Nbits())
+        }
+        var x uint
+        for i := 0; i < binary.Nbits(); i++ {
+            x <<= 1
+            if binary.Bit(i) {
+                x |= 1
+            }
+        }
+        return x, nil
+    }
+    return bufr.NewCell(node, value).PackedValue()
+}
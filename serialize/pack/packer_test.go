@@ -0,0 +1,128 @@
+package pack
+
+import (
+    "testing"
+    "bytes"
+    assert2 "github.com/seanpont/assert"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/table"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+func TestNbitsForDiff(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    // Identical values need no difference
+    assert.Equal(nbitsForDiff(0, false), 0)
+    assert.Equal(nbitsForDiff(1, false), 1)
+    // 3 is the missing value of 2 bits
+    assert.Equal(nbitsForDiff(2, false), 2)
+    assert.Equal(nbitsForDiff(3, false), 3)
+    // Missing value needs at least 2 bits and must not clash with differences
+    assert.Equal(nbitsForDiff(0, true), 2)
+    assert.Equal(nbitsForDiff(1, true), 2)
+    assert.Equal(nbitsForDiff(7, true), 4)
+
+    assert.True(fitsDiff(6, 3, true))
+    assert.False(fitsDiff(7, 3, false))
+    assert.False(fitsDiff(1, 1, true))
+}
+
+func newNode(unit table.Unit, scale int, refval float64, nbits int) *bufr.ValuedNode {
+    return &bufr.ValuedNode{
+        PackingInfo: &bufr.PackingInfo{Unit: unit, Scale: scale, Refval: refval, Nbits: nbits},
+    }
+}
+
+// pack packs the value with the packer created by newPacker and returns a reader of
+// the packed bits padded to whole bytes
+func pack(t *testing.T, newPacker func(tdcfio.Writer) Packer,
+    node *bufr.ValuedNode, value interface{}) *tdcfio.BitReader {
+
+    buf := new(bytes.Buffer)
+    w := tdcfio.BitWriter(buf)
+    if err := newPacker(w).Pack(node, value); err != nil {
+        t.Fatal(err)
+    }
+    if n := w.Pos() % tdcfio.NBITS_PER_BYTE; n > 0 {
+        w.WriteUint(0, tdcfio.NBITS_PER_BYTE-n)
+    }
+    return tdcfio.NewBitReader(buf)
+}
+
+func TestUncompressedPacker(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    node := newNode(table.NUMERIC, 2, -100, 16)
+    r := pack(t, NewUncompressedPacker, node, 12.34)
+    x, _ := r.ReadUint(16)
+    assert.Equal(x, uint(1334))
+
+    r = pack(t, NewUncompressedPacker, node, nil)
+    x, _ = r.ReadUint(16)
+    assert.Equal(x, uint(0xffff))
+
+    r = pack(t, NewUncompressedPacker, newNode(table.CODE, 0, 0, 8), -3)
+    i, _ := r.ReadInt(8)
+    assert.Equal(i, -3)
+
+    r = pack(t, NewUncompressedPacker, newNode(table.STRING, 0, 0, 32), []byte("AB"))
+    b, _ := r.ReadBytes(4)
+    assert.Equal(b, []byte("AB\x00\x00"))
+
+    // Below the reference value
+    err := NewUncompressedPacker(tdcfio.BitWriter(new(bytes.Buffer))).Pack(node, -2.0)
+    assert.NotNil(err)
+}
+
+func TestCompressedPacker(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    // Minimum value, number of bits of increments and the increments, where a missing
+    // value has all bits set
+    r := pack(t, NewCompressedPacker, newNode(table.NUMERIC, 0, 0, 8), []interface{}{1.0, nil, 3.0})
+    for _, want := range []struct{ nbits int; x uint }{{8, 1}, {6, 2}, {2, 0}, {2, 3}, {2, 2}} {
+        x, _ := r.ReadUint(want.nbits)
+        assert.Equal(x, want.x)
+    }
+
+    // Signed values have a signed minimum value
+    r = pack(t, NewCompressedPacker, newNode(table.CODE, 0, 0, 8), []interface{}{-2, 3})
+    i, _ := r.ReadInt(8)
+    assert.Equal(i, -2)
+    for _, want := range []struct{ nbits int; x uint }{{6, 3}, {3, 0}, {3, 5}} {
+        x, _ := r.ReadUint(want.nbits)
+        assert.Equal(x, want.x)
+    }
+
+    // Identical values have no increments
+    r = pack(t, NewCompressedPacker, newNode(table.NUMERIC, 0, 0, 8), []interface{}{5.0, 5.0})
+    x, _ := r.ReadUint(8)
+    assert.Equal(x, uint(5))
+    x, _ = r.ReadUint(6)
+    assert.Equal(x, uint(0))
+
+    // Different strings have an empty minimum value and the number of bytes
+    r = pack(t, NewCompressedPacker, newNode(table.STRING, 0, 0, 16), []interface{}{[]byte("AB"), []byte("CD")})
+    b, _ := r.ReadBytes(2)
+    assert.Equal(b, []byte{0, 0})
+    x, _ = r.ReadUint(6)
+    assert.Equal(x, uint(2))
+    b, _ = r.ReadBytes(4)
+    assert.Equal(b, []byte("ABCD"))
+
+    // The number of bytes of different strings must fit in 6 bits
+    long := newNode(table.STRING, 0, 0, 64*tdcfio.NBITS_PER_BYTE)
+    packer := NewCompressedPacker(tdcfio.BitWriter(new(bytes.Buffer)))
+    assert.NotNil(packer.Pack(long, []interface{}{[]byte("AB"), []byte("CD")}))
+    assert.Nil(packer.Pack(long, []interface{}{[]byte("AB"), []byte("AB")}))
+
+    // The decoded minimum value and number of bits are kept if applicable
+    node := newNode(table.NUMERIC, 0, 0, 8)
+    node.MinValue, node.NbitsDiff = uint(0), 4
+    r = pack(t, NewCompressedPacker, node, []interface{}{1.0, 2.0})
+    for _, want := range []struct{ nbits int; x uint }{{8, 0}, {6, 4}, {4, 1}, {4, 2}} {
+        x, _ := r.ReadUint(want.nbits)
+        assert.Equal(x, want.x)
+    }
+}
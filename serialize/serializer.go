@@ -3,22 +3,64 @@ package serialize
 import (
     "github.com/ywangd/gobufrkit/bufr"
     "io"
+    "github.com/ywangd/gobufrkit/units"
 )
 
 type Serializer interface {
     Serialize(message *bufr.Message) error
 }
 
+// Config controls what is included in the serialized output.
+type Config struct {
+    // Include hidden fields, e.g. padding
+    ShowHidden bool
+    // Include the packed (unscaled) integer and the packing parameters of each cell.
+    // This is mostly useful for debugging encoders.
+    ShowPacking bool
+    // Convert values to this unit system if it is not nil
+    Units *units.System
+}
+
 type FlatTextSerializer struct {
     v *FlatTextVisitor
 }
 
-func NewFlatTextSerializer(writer io.Writer) *FlatTextSerializer {
-    return &FlatTextSerializer{
-        v: NewFlatTextVisitor(writer),
-    }
+func NewFlatTextSerializer(writer io.Writer, config *Config) *FlatTextSerializer {
+    v := NewFlatTextVisitor(writer)
+    v.ShowHidden = config.ShowHidden
+    v.ShowPacking = config.ShowPacking
+    v.Units = config.Units
+    return &FlatTextSerializer{v: v}
 }
 
 func (s *FlatTextSerializer) Serialize(message *bufr.Message) error {
     return message.Accept(s.v)
 }
+
+// cellValue returns the value and unit of the given cell. They are converted
+// with the given unit system unless it is nil.
+func cellValue(cell *bufr.Cell, system *units.System) (interface{}, string) {
+    if system == nil {
+        return cell.Value(), cell.Unit()
+    }
+    return system.ConvertCell(cell)
+}
+
+// BinarySerializer encodes messages in the BUFR binary format. Section lengths
+// are updated while encoding to reflect any change made to the message.
+type BinarySerializer struct {
+    w io.Writer
+}
+
+func NewBinarySerializer(writer io.Writer) *BinarySerializer {
+    return &BinarySerializer{w: writer}
+}
+
+func (s *BinarySerializer) Serialize(message *bufr.Message) error {
+    b, err := encodeMessage(message)
+    if err != nil {
+        return err
+    }
+    _, err = s.w.Write(b)
+    return err
+}
@@ -81,6 +81,19 @@ func (id ID) Y() int {
     return int(id) % 1000
 }
 
+// IsDelayedFactor checks whether the descriptor is a delayed replication
+// or repetition factor, i.e. 031000, 031001, 031002, 031011 or 031012
+func (id ID) IsDelayedFactor() bool {
+    if id.F() != F_ELEMENT || id.X() != 31 {
+        return false
+    }
+    switch id.Y() {
+    case 0, 1, 2, 11, 12:
+        return true
+    }
+    return false
+}
+
 // The Descriptor interface is a general form to represent a BUFR descriptor.
 // In addition to the F X Y semantics, it also has an associated Entry
 // whose actual type depends on the descriptor type.
@@ -0,0 +1,142 @@
+package tdcfio
+
+import (
+    "io"
+    "bufio"
+    "bytes"
+    "math"
+    "compress/gzip"
+    "compress/bzip2"
+    "archive/tar"
+    "github.com/ulikunitz/xz"
+    "github.com/pkg/errors"
+)
+
+// Magic bytes used for detecting compression and archive formats
+var (
+    gzipMagic  = []byte{0x1f, 0x8b}
+    bzip2Magic = []byte("BZh")
+    xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+    tarMagic   = []byte("ustar")
+)
+
+// Offset of the magic string in a tar header block
+const tarMagicOffset = 257
+
+// MemberSeparator separates the archive path and the member name in the name
+// of an archive member, e.g. bundle.tar.gz:IUSN01_LFPW.bufr
+const MemberSeparator = ":"
+
+// ArchiveReader provides sequential access to the input streams contained in
+// a possibly compressed or archived source.
+//
+// Gzip, bzip2 and xz compression are detected by their magic bytes and
+// decompressed transparently. If the (decompressed) source is a tar archive,
+// each regular file member is returned as a separate stream, which is itself
+// decompressed if necessary. Any other source is returned as a single stream.
+type ArchiveReader struct {
+    name string
+    r    io.Reader
+    tr   *tar.Reader
+    done bool
+}
+
+// NewArchiveReader returns a pointer to an ArchiveReader reading from the given
+// reader. The name is used for naming the returned streams, e.g. the path of
+// the input file.
+func NewArchiveReader(r io.Reader, name string) (*ArchiveReader, error) {
+    dr, err := Decompress(r)
+    if err != nil {
+        return nil, err
+    }
+    br := bufio.NewReader(dr)
+    ar := &ArchiveReader{name: name, r: br}
+    if isTar(br) {
+        ar.tr = tar.NewReader(br)
+    }
+    return ar, nil
+}
+
+// Next returns the name and reader of the next stream. It returns io.EOF when
+// there are no more streams.
+func (ar *ArchiveReader) Next() (string, io.Reader, error) {
+    if ar.tr == nil {
+        if ar.done {
+            return "", nil, io.EOF
+        }
+        ar.done = true
+        return ar.name, ar.r, nil
+    }
+
+    for {
+        header, err := ar.tr.Next()
+        if err != nil {
+            if err == io.EOF {
+                return "", nil, err
+            }
+            return "", nil, errors.Wrap(err, "cannot read tar header")
+        }
+        if header.Typeflag != tar.TypeReg {
+            continue
+        }
+        r, err := Decompress(ar.tr)
+        if err != nil {
+            return "", nil, errors.Wrapf(err, "cannot read tar member: %v", header.Name)
+        }
+        return ar.name + MemberSeparator + header.Name, r, nil
+    }
+}
+
+// Decompress returns a reader of the decompressed content if the given reader
+// is gzip, bzip2 or xz compressed. Otherwise the content is returned as is.
+func Decompress(r io.Reader) (io.Reader, error) {
+    br := bufio.NewReader(r)
+    magic, err := br.Peek(len(xzMagic))
+    if err != nil && err != io.EOF {
+        return nil, errors.Wrap(err, "cannot peek magic bytes")
+    }
+
+    switch {
+    case bytes.HasPrefix(magic, gzipMagic):
+        zr, err := gzip.NewReader(br)
+        if err != nil {
+            return nil, errors.Wrap(err, "cannot create gzip reader")
+        }
+        return zr, nil
+    case bytes.HasPrefix(magic, bzip2Magic):
+        return bzip2.NewReader(br), nil
+    case bytes.HasPrefix(magic, xzMagic):
+        zr, err := xz.NewReader(br)
+        if err != nil {
+            return nil, errors.Wrap(err, "cannot create xz reader")
+        }
+        return zr, nil
+    default:
+        return br, nil
+    }
+}
+
+// IsRandomAccessible checks whether messages can be read directly at their byte offsets
+// in the given input, i.e. the input is neither compressed nor a tar archive.
+func IsRandomAccessible(r io.ReaderAt) (bool, error) {
+    br := bufio.NewReader(io.NewSectionReader(r, 0, math.MaxInt64))
+    magic, err := br.Peek(len(xzMagic))
+    if err != nil && err != io.EOF {
+        return false, errors.Wrap(err, "cannot peek magic bytes")
+    }
+    for _, m := range [][]byte{gzipMagic, bzip2Magic, xzMagic} {
+        if bytes.HasPrefix(magic, m) {
+            return false, nil
+        }
+    }
+    return !isTar(br), nil
+}
+
+// isTar checks whether the reader is positioned at a tar header block
+func isTar(br *bufio.Reader) bool {
+    b, err := br.Peek(tarMagicOffset + len(tarMagic))
+    if err != nil {
+        return false
+    }
+    return bytes.Equal(b[tarMagicOffset:], tarMagic)
+}
@@ -0,0 +1,94 @@
+package tdcfio
+
+import (
+    "testing"
+    assert2 "github.com/seanpont/assert"
+    "bytes"
+    "io"
+    "io/ioutil"
+    "archive/tar"
+    "compress/gzip"
+)
+
+func TestArchiveReaderPlain(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    ar, err := NewArchiveReader(bytes.NewReader([]byte("BUFR")), "plain.bufr")
+    assert.Nil(err)
+
+    name, r, err := ar.Next()
+    assert.Nil(err)
+    assert.Equal(name, "plain.bufr")
+    b, err := ioutil.ReadAll(r)
+    assert.Nil(err)
+    assert.Equal(string(b), "BUFR")
+
+    _, _, err = ar.Next()
+    assert.Equal(err, io.EOF)
+}
+
+func TestArchiveReaderGzippedTar(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    // A gzipped member inside a gzipped tar
+    member := new(bytes.Buffer)
+    zw := gzip.NewWriter(member)
+    zw.Write([]byte("BUFR2"))
+    zw.Close()
+
+    archive := new(bytes.Buffer)
+    zw = gzip.NewWriter(archive)
+    tw := tar.NewWriter(zw)
+    for _, m := range []struct {
+        name string
+        data []byte
+    }{{"one.bufr", []byte("BUFR1")}, {"two.bufr.gz", member.Bytes()}} {
+        tw.WriteHeader(&tar.Header{Name: m.name, Mode: 0644, Size: int64(len(m.data)), Typeflag: tar.TypeReg})
+        tw.Write(m.data)
+    }
+    tw.Close()
+    zw.Close()
+
+    ar, err := NewArchiveReader(archive, "bundle.tar.gz")
+    assert.Nil(err)
+
+    name, r, err := ar.Next()
+    assert.Nil(err)
+    assert.Equal(name, "bundle.tar.gz:one.bufr")
+    b, _ := ioutil.ReadAll(r)
+    assert.Equal(string(b), "BUFR1")
+
+    name, r, err = ar.Next()
+    assert.Nil(err)
+    assert.Equal(name, "bundle.tar.gz:two.bufr.gz")
+    b, _ = ioutil.ReadAll(r)
+    assert.Equal(string(b), "BUFR2")
+
+    _, _, err = ar.Next()
+    assert.Equal(err, io.EOF)
+}
+
+func TestIsRandomAccessible(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    ok, err := IsRandomAccessible(bytes.NewReader([]byte("BUFR")))
+    assert.Nil(err)
+    assert.True(ok)
+
+    compressed := new(bytes.Buffer)
+    zw := gzip.NewWriter(compressed)
+    zw.Write([]byte("BUFR"))
+    zw.Close()
+    ok, err = IsRandomAccessible(bytes.NewReader(compressed.Bytes()))
+    assert.Nil(err)
+    assert.True(!ok)
+
+    archive := new(bytes.Buffer)
+    tw := tar.NewWriter(archive)
+    tw.WriteHeader(&tar.Header{Name: "one.bufr", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+    tw.Write([]byte("BUFR"))
+    tw.Close()
+    ok, err = IsRandomAccessible(bytes.NewReader(archive.Bytes()))
+    assert.Nil(err)
+    assert.True(!ok)
+}
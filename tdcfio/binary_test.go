@@ -3,7 +3,7 @@ package tdcfio_test
 import (
     "testing"
     "fmt"
-    "gobufrkit/tdcfio"
+    "github.com/ywangd/gobufrkit/tdcfio"
     assert2 "github.com/seanpont/assert"
 )
 
@@ -8,10 +8,13 @@ import (
     "bytes"
     "fmt"
     "github.com/pkg/errors"
+    "io/ioutil"
 )
 
 type BitReader struct {
     r *bitstream.BitReader
+    // The underlying reader for skipping bytes
+    ur io.Reader
 
     // Bit position of current read
     pos int
@@ -22,7 +25,8 @@ type BitReader struct {
 // BitReader implements the tdcfio.Reader interface for reading from a binary stream.
 func NewBitReader(r io.Reader) *BitReader {
     return &BitReader{
-        r:   bitstream.NewReader(r),
+        r:  bitstream.NewReader(r),
+        ur: r,
     }
 }
 
@@ -39,12 +43,21 @@ func (r *BitReader) ReadNumber(nbits int) (float64, error) {
 }
 
 func (r *BitReader) ReadUint(nbits int) (uint, error) {
+    if err := checkNbits(nbits); err != nil {
+        return 0, err
+    }
     r.pos += nbits
     value, err := r.r.ReadBits(nbits)
     return uint(value), err
 }
 
 func (r *BitReader) ReadInt(nbits int) (int, error) {
+    if err := checkNbits(nbits); err != nil {
+        return 0, err
+    }
+    if nbits == 0 {
+        return 0, fmt.Errorf("cannot read signed integer of 0 bits")
+    }
     negative, err := r.ReadBool()
     if err != nil {
         return 0, err
@@ -66,6 +79,9 @@ func (r *BitReader) ReadBool() (bool, error) {
 }
 
 func (r *BitReader) ReadBytes(nbytes int) ([]byte, error) {
+    if nbytes < 0 {
+        return nil, fmt.Errorf("invalid number of bytes to read: %v", nbytes)
+    }
     r.pos += nbytes * NBITS_PER_BYTE
     bs := make([]byte, nbytes)
     for i := 0; i < nbytes; i++ {
@@ -79,6 +95,9 @@ func (r *BitReader) ReadBytes(nbytes int) ([]byte, error) {
 }
 
 func (r *BitReader) ReadBinary(nbits int) (*Binary, error) {
+    if nbits < 0 {
+        return nil, fmt.Errorf("invalid number of bits to read: %v", nbits)
+    }
     r.pos += nbits
 
     var (
@@ -96,7 +115,7 @@ func (r *BitReader) ReadBinary(nbits int) (*Binary, error) {
             currentValue, err = r.r.ReadBits(NBITS_PER_BYTE)
         }
         if err != nil {
-            return nil, nil
+            return nil, err
         }
         buffer = append(buffer, byte(currentValue))
         nbits -= NBITS_PER_BYTE
@@ -104,6 +123,25 @@ func (r *BitReader) ReadBinary(nbits int) (*Binary, error) {
     return &Binary{b: buffer, nbits: nbitsSaved}, nil
 }
 
+// checkNbits checks the number of bits fits in a single integer value
+func checkNbits(nbits int) error {
+    if nbits < 0 || nbits > 64 {
+        return fmt.Errorf("invalid number of bits to read: %v", nbits)
+    }
+    return nil
+}
+
+// Skip discards the given number of bytes. It can only be called at complete
+// byte boundary, where the bit reader holds no partially consumed byte.
+func (r *BitReader) Skip(nbytes int) error {
+    if r.pos%NBITS_PER_BYTE != 0 {
+        return fmt.Errorf("can only skip at complete byte boundary: %v", r.pos)
+    }
+    n, err := io.CopyN(ioutil.Discard, r.ur, int64(nbytes))
+    r.pos += int(n) * NBITS_PER_BYTE
+    return err
+}
+
 func (r *BitReader) ReadFloat32() (float64, error) {
     v, err := r.ReadUint(32)
     if err != nil {
@@ -3,8 +3,9 @@ package tdcfio_test
 import (
     "testing"
     assert2 "github.com/seanpont/assert"
-    "gobufrkit/tdcfio"
+    "github.com/ywangd/gobufrkit/tdcfio"
     "os"
+    "bytes"
 )
 
 func TestBitReader(t *testing.T) {
@@ -109,4 +110,33 @@ func TestPeekableBitReader(t *testing.T) {
     assert.Nil(err)
     assert.Equal(u, uint(2))
 
-}
\ No newline at end of file
+}
+// FuzzBitReader reads arbitrary data with a sequence of reads derived from the data
+// itself. Reads must return errors instead of panicking, e.g. for invalid widths.
+func FuzzBitReader(f *testing.F) {
+    f.Add([]byte("BUFR\x00\x00\x5e\x04"))
+    f.Add([]byte{0xff, 0x00, 0x80, 0x7f, 0x41})
+
+    f.Fuzz(func(t *testing.T, data []byte) {
+        r := tdcfio.NewBitReader(bytes.NewReader(data))
+        for _, op := range data {
+            n := int(int8(op)) / 2
+            var err error
+            switch op % 5 {
+            case 0:
+                _, err = r.ReadUint(n)
+            case 1:
+                _, err = r.ReadInt(n)
+            case 2:
+                _, err = r.ReadBytes(n / 8)
+            case 3:
+                _, err = r.ReadBinary(n)
+            case 4:
+                _, err = r.ReadBool()
+            }
+            if err != nil {
+                return
+            }
+        }
+    })
+}
@@ -9,6 +9,8 @@ import (
 
 type bitWriter struct {
     w *bitstream.BitWriter
+    // Number of bits written so far
+    pos int
 }
 
 // BitWriter returns a pointer to bitWriter
@@ -20,9 +22,15 @@ func BitWriter(writer io.Writer) *bitWriter {
 
 // WriteUint writes n bits least significant bits of given uint, most-significant-bit first
 func (w *bitWriter) WriteUint(v uint, n int) error {
+    w.pos += n
     return w.w.WriteBits(uint64(v), n)
 }
 
+// Pos returns the number of bits written so far
+func (w *bitWriter) Pos() int {
+    return w.pos
+}
+
 // WriteInt writes a signed integer using n bits.
 //
 // The first bit is zero if value is positive or one if value is negative.
@@ -35,6 +43,7 @@ func (w *bitWriter) WriteInt(v int, n int) error {
 }
 
 func (w *bitWriter) WriteBool(v bool) error {
+    w.pos++
     return w.w.WriteBit(v == true)
 }
 
@@ -42,6 +51,7 @@ func (w *bitWriter) WriteBool(v bool) error {
 func (w *bitWriter) WriteBytes(v []byte, n int) error {
     var err error
     for i := 0; i < n; i++ {
+        w.pos += NBITS_PER_BYTE
         if i < len(v) {
             err = w.w.WriteByte(v[i])
         } else {
@@ -60,6 +70,7 @@ func (w *bitWriter) WriteBinary(v *Binary, nbits int) error {
         return fmt.Errorf("inconsistent number of bits for writing Bin data: %v != %v",
             nbits, v.nbits)
     }
+    w.pos += nbits
     for i, b := range v.b {
         if i == len(v.b)-1 && v.nbits%NBITS_PER_BYTE != 0 {
             if err := w.w.WriteBits(uint64(b), nbits%NBITS_PER_BYTE); err != nil {
@@ -4,7 +4,7 @@ import (
     "testing"
     assert2 "github.com/seanpont/assert"
     "bytes"
-    "gobufrkit/tdcfio"
+    "github.com/ywangd/gobufrkit/tdcfio"
     "os"
 )
 
@@ -0,0 +1,302 @@
+package tdcfio
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "strconv"
+)
+
+// Letters of descriptors in CREX, indexed by their F values
+const CREX_DESCRIPTOR_LETTERS = "BRCD"
+
+// Number of characters of a CREX descriptor, e.g. B12101
+const CREX_DESCRIPTOR_NCHARS = 6
+
+// CrexReader implements the tdcfio.PeekableReader interface for reading CREX messages,
+// which are made of groups of characters separated by a space, line breaks or the "+"
+// ending a subset.
+//
+// Positions and widths are in bits of the characters, i.e. NBITS_PER_BYTE for each
+// character, so that a width of 16 reads a group of two characters. A numeric group
+// can be preceded by a minus sign, which does not count in the width. Peeking
+// operations skip and return characters ignoring any whitespace, e.g. to find the
+// start signature and the edition number across line breaks.
+type CrexReader struct {
+    r   *bufio.Reader
+    pos int
+    // Whether the separator in front of the next group is already skipped
+    separated bool
+}
+
+// NewCrexReader returns a pointer to CrexReader.
+func NewCrexReader(reader io.Reader) *CrexReader {
+    return &CrexReader{r: bufio.NewReader(reader)}
+}
+
+func (r *CrexReader) Pos() int {
+    return r.pos
+}
+
+func (r *CrexReader) ReadNumber(n int) (float64, error) {
+    x, err := r.ReadInt(n)
+    if err != nil {
+        return 0, err
+    }
+    return float64(x), nil
+}
+
+func (r *CrexReader) ReadUint(n int) (uint, error) {
+    s, err := r.ReadGroup(n)
+    if err != nil {
+        return 0, err
+    }
+    x, err := strconv.ParseUint(s, 10, 64)
+    if err != nil {
+        return 0, r.errorf("not an unsigned integer: %q", s)
+    }
+    return uint(x), nil
+}
+
+func (r *CrexReader) ReadInt(n int) (int, error) {
+    s, err := r.ReadGroup(n)
+    if err != nil {
+        return 0, err
+    }
+    x, err := strconv.ParseInt(s, 10, 64)
+    if err != nil {
+        return 0, r.errorf("not an integer: %q", s)
+    }
+    return int(x), nil
+}
+
+func (r *CrexReader) ReadBool() (bool, error) {
+    s, err := r.ReadGroup(NBITS_PER_BYTE)
+    if err != nil {
+        return false, err
+    }
+    switch s {
+    case "0":
+        return false, nil
+    case "1":
+        return true, nil
+    default:
+        return false, r.errorf("not a bool: %q", s)
+    }
+}
+
+// ReadBytes reads n characters following exactly one separator so that the characters
+// can start with spaces, e.g. for character values.
+func (r *CrexReader) ReadBytes(n int) ([]byte, error) {
+    if err := r.separate(); err != nil {
+        return nil, err
+    }
+    b := make([]byte, n)
+    if _, err := io.ReadFull(r.r, b); err != nil {
+        return nil, unexpectedEOF(err)
+    }
+    r.advance(n)
+    return b, nil
+}
+
+func (r *CrexReader) ReadBinary(n int) (*Binary, error) {
+    return nil, r.errorf("binary values are not supported by CREX")
+}
+
+func (r *CrexReader) ReadFloat32() (float64, error) {
+    return 0, r.errorf("float values are not supported by CREX")
+}
+
+// ReadGroup reads a group of n/NBITS_PER_BYTE characters, optionally preceded by a minus
+// sign, and returns it. Extra spaces in front of the group are skipped.
+func (r *CrexReader) ReadGroup(n int) (string, error) {
+    if err := r.skipSpaces(); err != nil {
+        return "", err
+    }
+    nchars := n / NBITS_PER_BYTE
+    if b, _ := r.r.Peek(1); len(b) > 0 && b[0] == '-' {
+        nchars++
+    }
+    b := make([]byte, nchars)
+    if _, err := io.ReadFull(r.r, b); err != nil {
+        return "", unexpectedEOF(err)
+    }
+    r.advance(nchars)
+    return string(b), nil
+}
+
+// ReadMissing reads the next group only if it is a missing value, i.e. n/NBITS_PER_BYTE
+// slashes, and tells whether it is.
+func (r *CrexReader) ReadMissing(n int) (bool, error) {
+    if err := r.separate(); err != nil {
+        return false, err
+    }
+    nchars := n / NBITS_PER_BYTE
+    b, err := r.r.Peek(nchars)
+    if err != nil && err != io.EOF {
+        return false, err
+    }
+    if len(b) < nchars || nchars == 0 {
+        return false, nil
+    }
+    for _, c := range b {
+        if c != '/' {
+            return false, nil
+        }
+    }
+    r.r.Discard(nchars)
+    r.advance(nchars)
+    return true, nil
+}
+
+// ReadDescriptors reads descriptors, e.g. B12101 R01000 D07022, up to the next group
+// which is not a descriptor and returns their IDs as FXXYYY, e.g. 12101 101000 307022.
+func (r *CrexReader) ReadDescriptors() ([]int, error) {
+    var ids []int
+    for {
+        if err := r.skipSpaces(); err != nil {
+            return nil, err
+        }
+        b, err := r.r.Peek(CREX_DESCRIPTOR_NCHARS)
+        if err != nil && err != io.EOF {
+            return nil, err
+        }
+        id, ok := crexDescriptorId(b)
+        if !ok {
+            return ids, nil
+        }
+        r.r.Discard(CREX_DESCRIPTOR_NCHARS)
+        r.advance(CREX_DESCRIPTOR_NCHARS)
+        ids = append(ids, id)
+    }
+}
+
+func (r *CrexReader) PeekUint(skip int, n int) (uint, error) {
+    b, err := r.peek(skip, n/NBITS_PER_BYTE)
+    if err != nil {
+        return 0, err
+    }
+    x, err := strconv.ParseUint(string(b), 10, 64)
+    if err != nil {
+        return 0, r.errorf("not an unsigned integer: %q", b)
+    }
+    return uint(x), nil
+}
+
+func (r *CrexReader) PeekBytes(skip int, n int) ([]byte, error) {
+    return r.peek(skip, n)
+}
+
+// peek returns n characters after skipping number of characters, ignoring whitespace.
+// It returns io.EOF if there are not enough characters.
+func (r *CrexReader) peek(skip int, n int) ([]byte, error) {
+    for size := skip + n; ; size *= 2 {
+        buf, err := r.r.Peek(size)
+        var b []byte
+        i := 0
+        for _, c := range buf {
+            if isCrexSpace(c) {
+                continue
+            }
+            if i >= skip {
+                b = append(b, c)
+                if len(b) == n {
+                    return b, nil
+                }
+            }
+            i++
+        }
+        if err == io.EOF {
+            return nil, io.EOF
+        }
+        if err != nil {
+            return nil, r.errorf("cannot peek %v characters: %v", skip+n, err)
+        }
+    }
+}
+
+// separate skips the separator in front of the next group, i.e. line breaks together
+// with at most a space and a "+" ending a subset. The "++" ending a section is not
+// a separator.
+func (r *CrexReader) separate() error {
+    if r.separated {
+        return nil
+    }
+    space, plus := false, false
+loop:
+    for {
+        b, err := r.r.Peek(2)
+        if len(b) == 0 {
+            if err == io.EOF {
+                break
+            }
+            return err
+        }
+        switch c := b[0]; {
+        case c == '\r' || c == '\n':
+        case c == ' ' && !space:
+            space = true
+        case c == '+' && !plus && (len(b) < 2 || b[1] != '+'):
+            plus = true
+        default:
+            break loop
+        }
+        r.r.Discard(1)
+        r.advance(1)
+    }
+    r.separated = true
+    return nil
+}
+
+// skipSpaces skips the separator and any extra whitespace in front of the next group
+func (r *CrexReader) skipSpaces() error {
+    if err := r.separate(); err != nil {
+        return err
+    }
+    for {
+        b, err := r.r.Peek(1)
+        if len(b) == 0 {
+            if err == io.EOF {
+                return nil
+            }
+            return err
+        }
+        if !isCrexSpace(b[0]) {
+            return nil
+        }
+        r.r.Discard(1)
+        r.advance(1)
+    }
+}
+
+// advance moves the position by n characters read
+func (r *CrexReader) advance(n int) {
+    r.pos += n * NBITS_PER_BYTE
+    r.separated = false
+}
+
+func (r *CrexReader) errorf(format string, args ...interface{}) error {
+    return fmt.Errorf("character %v: %v", r.pos/NBITS_PER_BYTE, fmt.Sprintf(format, args...))
+}
+
+func isCrexSpace(c byte) bool {
+    return c == ' ' || c == '\r' || c == '\n'
+}
+
+// crexDescriptorId returns the ID of a CREX descriptor, e.g. 307022 for D07022
+func crexDescriptorId(b []byte) (int, bool) {
+    if len(b) != CREX_DESCRIPTOR_NCHARS || !isDigits(string(b[1:])) {
+        return 0, false
+    }
+    f := -1
+    for i := 0; i < len(CREX_DESCRIPTOR_LETTERS); i++ {
+        if b[0] == CREX_DESCRIPTOR_LETTERS[i] {
+            f = i
+        }
+    }
+    if f < 0 {
+        return 0, false
+    }
+    xy, _ := strconv.Atoi(string(b[1:]))
+    return f*100000 + xy, true
+}
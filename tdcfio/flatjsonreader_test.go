@@ -4,7 +4,7 @@ import (
     "testing"
     assert2 "github.com/seanpont/assert"
     "strings"
-    "gobufrkit/tdcfio"
+    "github.com/ywangd/gobufrkit/tdcfio"
 )
 
 var jstr = `
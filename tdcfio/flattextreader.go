@@ -0,0 +1,342 @@
+package tdcfio
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+    "github.com/pkg/errors"
+)
+
+// Prefix of the lines of virtual fields, which have no bits in the message
+const VIRTUAL_FIELD_PREFIX = "(virtual) "
+
+// Width of the descriptor column of cell lines, followed by a space
+const FLAT_TEXT_DESCRIPTOR_WIDTH = 59
+
+// Layout of the text written by serialize.FlatTextVisitor
+const (
+    flatTextSectionBanner = "<<<<<<"
+    flatTextSubsetBanner  = "######"
+    flatTextFieldSep      = " = "
+    flatTextMissing       = "<nil>"
+)
+
+// flatTextValue is a value of a field or a cell as found in the text
+type flatTextValue struct {
+    // Name of the field or empty for cells
+    name string
+    text string
+    line int
+}
+
+// FlatTextReader implements the tdcfio.PeekableReader interface for reading from
+// text dumps as written by serialize.FlatTextVisitor, i.e. section banners,
+// "name = value" lines of header fields and numbered cell lines of subsets. Lines
+// of virtual fields are skipped. Dumps must not convert values to other units.
+//
+// Like FlatJsonReader, values are read one at a time and the width argument of
+// ReadXXX operations only advances the position. The peeking operations skip
+// number of values. A list of descriptors, i.e. the unexpanded template, is read
+// as F, X and Y values of each descriptor. An empty list has no value and so
+// reading zero bytes does not consume any value.
+type FlatTextReader struct {
+    s *bufio.Scanner
+    // Number of lines scanned
+    nlines int
+    // Values scanned but not yet read
+    values []flatTextValue
+    pos    int
+}
+
+// NewFlatTextReader returns a pointer to FlatTextReader.
+func NewFlatTextReader(reader io.Reader) *FlatTextReader {
+    return &FlatTextReader{s: bufio.NewScanner(reader)}
+}
+
+func (r *FlatTextReader) Pos() int {
+    return r.pos
+}
+
+func (r *FlatTextReader) ReadNumber(n int) (float64, error) {
+    v, err := r.next(n)
+    if err != nil {
+        return 0, err
+    }
+    return v.float64()
+}
+
+func (r *FlatTextReader) ReadUint(n int) (uint, error) {
+    v, err := r.next(n)
+    if err != nil {
+        return 0, err
+    }
+    return v.uint()
+}
+
+func (r *FlatTextReader) ReadInt(n int) (int, error) {
+    v, err := r.next(n)
+    if err != nil {
+        return 0, err
+    }
+    return v.int()
+}
+
+func (r *FlatTextReader) ReadBool() (bool, error) {
+    v, err := r.next(1)
+    if err != nil {
+        return false, err
+    }
+    return v.bool()
+}
+
+func (r *FlatTextReader) ReadBytes(n int) ([]byte, error) {
+    if n == 0 {
+        return []byte{}, nil
+    }
+    v, err := r.next(n * NBITS_PER_BYTE)
+    if err != nil {
+        return nil, err
+    }
+    return v.bytes()
+}
+
+func (r *FlatTextReader) ReadBinary(n int) (*Binary, error) {
+    v, err := r.next(n)
+    if err != nil {
+        return nil, err
+    }
+    b, err := NewBinaryFromString(v.text)
+    if err != nil {
+        return nil, v.error(err)
+    }
+    return b, nil
+}
+
+func (r *FlatTextReader) ReadFloat32() (float64, error) {
+    return r.ReadNumber(32)
+}
+
+// ReadMissing reads the next value only if it is a missing value, i.e. <nil>,
+// and tells whether it is.
+func (r *FlatTextReader) ReadMissing(n int) (bool, error) {
+    v, err := r.peek(0)
+    if err != nil {
+        return false, unexpectedEOF(err)
+    }
+    if v.text != flatTextMissing {
+        return false, nil
+    }
+    _, err = r.next(n)
+    return true, err
+}
+
+// PeekFieldName returns the name of the header field of the next value, or an
+// empty string if the next value is a cell of a subset. It returns io.EOF if no
+// more value can be found.
+func (r *FlatTextReader) PeekFieldName() (string, error) {
+    v, err := r.peek(0)
+    if err != nil {
+        return "", err
+    }
+    return v.name, nil
+}
+
+func (r *FlatTextReader) PeekUint(skip int, n int) (uint, error) {
+    v, err := r.peek(skip)
+    if err != nil {
+        return 0, err
+    }
+    return v.uint()
+}
+
+func (r *FlatTextReader) PeekBytes(skip int, n int) ([]byte, error) {
+    v, err := r.peek(skip)
+    if err != nil {
+        return nil, err
+    }
+    return v.bytes()
+}
+
+// next returns the next value and advances the position by n
+func (r *FlatTextReader) next(n int) (flatTextValue, error) {
+    v, err := r.peek(0)
+    if err != nil {
+        return v, unexpectedEOF(err)
+    }
+    r.values = r.values[1:]
+    r.pos += n
+    return v, nil
+}
+
+// peek returns the value after skipping number of values. It returns io.EOF if
+// there are not enough values.
+func (r *FlatTextReader) peek(skip int) (flatTextValue, error) {
+    for len(r.values) <= skip {
+        if !r.s.Scan() {
+            if err := r.s.Err(); err != nil {
+                return flatTextValue{}, errors.Wrap(err, "cannot scan line")
+            }
+            return flatTextValue{}, io.EOF
+        }
+        r.nlines++
+        values, err := parseFlatTextLine(r.s.Text(), r.nlines)
+        if err != nil {
+            return flatTextValue{}, err
+        }
+        r.values = append(r.values, values...)
+    }
+    return r.values[skip], nil
+}
+
+func unexpectedEOF(err error) error {
+    if err == io.EOF {
+        return io.ErrUnexpectedEOF
+    }
+    return err
+}
+
+// parseFlatTextLine returns the values found in a line
+func parseFlatTextLine(line string, lineno int) ([]flatTextValue, error) {
+    line = strings.TrimRight(line, "\r")
+    switch {
+    case strings.TrimSpace(line) == "",
+        strings.HasPrefix(line, flatTextSectionBanner),
+        strings.HasPrefix(line, flatTextSubsetBanner),
+        strings.HasPrefix(line, VIRTUAL_FIELD_PREFIX):
+        return nil, nil
+    }
+
+    // Cell lines start with the right aligned cell number
+    trimmed := strings.TrimLeft(line, " ")
+    if i := strings.IndexByte(trimmed, ' '); i > 0 && isDigits(trimmed[:i]) {
+        start := len(line) - len(trimmed) + i + 1 + FLAT_TEXT_DESCRIPTOR_WIDTH + 1
+        if len(line) <= start {
+            return nil, fmt.Errorf("line %v: no value for cell %v", lineno, trimmed[:i])
+        }
+        text, err := cellText(line[start:])
+        if err != nil {
+            return nil, fmt.Errorf("line %v: %v", lineno, err)
+        }
+        return []flatTextValue{{text: text, line: lineno}}, nil
+    }
+
+    i := strings.Index(line, flatTextFieldSep)
+    if i <= 0 {
+        return nil, fmt.Errorf("line %v: not a field or a cell: %q", lineno, line)
+    }
+    name, text := line[:i], line[i+len(flatTextFieldSep):]
+    ids, ok := descriptorIds(text)
+    if !ok {
+        return []flatTextValue{{name: name, text: text, line: lineno}}, nil
+    }
+    var values []flatTextValue
+    for _, id := range ids {
+        for _, x := range []int{id / 100000, id / 1000 % 100, id % 1000} {
+            values = append(values, flatTextValue{name: name, text: strconv.Itoa(x), line: lineno})
+        }
+    }
+    return values, nil
+}
+
+// cellText returns the value at the start of the value column of a cell line,
+// which can be followed by the unit and packing information
+func cellText(s string) (string, error) {
+    if strings.HasPrefix(s, `"`) {
+        return strconv.QuotedPrefix(s)
+    }
+    if fields := strings.Fields(s); len(fields) > 0 {
+        return fields[0], nil
+    }
+    return "", fmt.Errorf("no value in %q", s)
+}
+
+// descriptorIds returns the ids of a list of descriptors, e.g. [301001 105002],
+// as opposed to a list of bytes. An empty list is taken as no descriptors.
+func descriptorIds(text string) ([]int, bool) {
+    if !strings.HasPrefix(text, "[") || !strings.HasSuffix(text, "]") {
+        return nil, false
+    }
+    fields := strings.Fields(text[1 : len(text)-1])
+    ids := make([]int, len(fields))
+    for i, field := range fields {
+        if len(field) != 6 || !isDigits(field) {
+            return nil, false
+        }
+        ids[i], _ = strconv.Atoi(field)
+    }
+    return ids, true
+}
+
+func (v flatTextValue) error(err error) error {
+    if v.name != "" {
+        return fmt.Errorf("line %v: %v: %v", v.line, v.name, err)
+    }
+    return fmt.Errorf("line %v: %v", v.line, err)
+}
+
+func (v flatTextValue) float64() (float64, error) {
+    x, err := strconv.ParseFloat(v.text, 64)
+    if err != nil {
+        return 0, v.error(fmt.Errorf("value is not a number: %v", v.text))
+    }
+    return x, nil
+}
+
+func (v flatTextValue) uint() (uint, error) {
+    if x, err := strconv.ParseUint(v.text, 10, 64); err == nil {
+        return uint(x), nil
+    }
+    x, err := v.float64()
+    if err != nil {
+        return 0, err
+    }
+    if x < 0 {
+        return 0, v.error(fmt.Errorf("value is negative: %v", v.text))
+    }
+    return uint(x), nil
+}
+
+func (v flatTextValue) int() (int, error) {
+    if x, err := strconv.ParseInt(v.text, 10, 64); err == nil {
+        return int(x), nil
+    }
+    x, err := v.float64()
+    if err != nil {
+        return 0, err
+    }
+    return int(x), nil
+}
+
+func (v flatTextValue) bool() (bool, error) {
+    b, err := strconv.ParseBool(v.text)
+    if err != nil {
+        return false, v.error(fmt.Errorf("value is not bool: %v", v.text))
+    }
+    return b, nil
+}
+
+// bytes returns the value of either a quoted string or a list of bytes, e.g. [66 85 70 82]
+func (v flatTextValue) bytes() ([]byte, error) {
+    if strings.HasPrefix(v.text, `"`) {
+        s, err := strconv.Unquote(v.text)
+        if err != nil {
+            return nil, v.error(err)
+        }
+        return []byte(s), nil
+    }
+    if !strings.HasPrefix(v.text, "[") || !strings.HasSuffix(v.text, "]") {
+        return nil, v.error(fmt.Errorf("value is not a string or bytes: %v", v.text))
+    }
+    fields := strings.Fields(v.text[1 : len(v.text)-1])
+    b := make([]byte, len(fields))
+    for i, field := range fields {
+        x, err := strconv.ParseUint(field, 10, 8)
+        if err != nil {
+            return nil, v.error(fmt.Errorf("value is not a byte: %v", field))
+        }
+        b[i] = byte(x)
+    }
+    return b, nil
+}
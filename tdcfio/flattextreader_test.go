@@ -0,0 +1,83 @@
+package tdcfio_test
+
+import (
+    "testing"
+    assert2 "github.com/seanpont/assert"
+    "strings"
+    "io"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+var tstr = `<<<<<< section 0 >>>>>>
+startSignature = [66 85 70 82]
+bufrEditionNumber = 4
+<<<<<< section 1 >>>>>>
+isSection2Presents = false
+(virtual) centreName = Melbourne
+<<<<<< section 2 >>>>>>
+flagBits = 000000
+unexpandedTemplate = [301001 031001]
+<<<<<< section 3 >>>>>>
+###### subset 1 of 1 ######
+    1 001001 WMO BLOCK NUMBER                                     94                   Numeric
+    2 001015 STATION OR SITE NAME                                 "A \"B\" C"          CCITT IA5
+    3 012101 TEMPERATURE/DRY-BULB TEMPERATURE                     <nil>                K
+`
+
+func TestFlatTextReader(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    r := tdcfio.NewFlatTextReader(strings.NewReader(tstr))
+    b, err := r.ReadBytes(4)
+    assert.Nil(err)
+    assert.Equal(string(b), "BUFR")
+
+    name, err := r.PeekFieldName()
+    assert.Nil(err)
+    assert.Equal(name, "bufrEditionNumber")
+    i, err := r.ReadInt(8)
+    assert.Nil(err)
+    assert.Equal(i, 4)
+
+    q, err := r.ReadBool()
+    assert.Nil(err)
+    assert.False(q)
+
+    s, err := r.ReadBinary(6)
+    assert.Nil(err)
+    assert.Equal(s.String(), "000000")
+
+    // Descriptors are read as F, X and Y
+    for _, want := range []uint{3, 1, 1, 0, 31, 1} {
+        u, err := r.ReadUint(8)
+        assert.Nil(err)
+        assert.Equal(u, want)
+    }
+
+    name, err = r.PeekFieldName()
+    assert.Nil(err)
+    assert.Equal(name, "")
+    b, err = r.PeekBytes(1, 9)
+    assert.Nil(err)
+    assert.Equal(string(b), `A "B" C`)
+    missing, err := r.ReadMissing(7)
+    assert.Nil(err)
+    assert.False(missing)
+    f, err := r.ReadNumber(7)
+    assert.Nil(err)
+    assert.Equal(f, 94.0)
+
+    b, err = r.ReadBytes(9)
+    assert.Nil(err)
+    assert.Equal(string(b), `A "B" C`)
+
+    missing, err = r.ReadMissing(12)
+    assert.Nil(err)
+    assert.True(missing)
+    assert.Equal(r.Pos(), 32+8+1+6+8*6+7+9*8+12)
+
+    _, err = r.PeekFieldName()
+    assert.Equal(err, io.EOF)
+    _, err = r.ReadUint(8)
+    assert.Equal(err, io.ErrUnexpectedEOF)
+}
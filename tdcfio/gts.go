@@ -0,0 +1,187 @@
+package tdcfio
+
+import (
+    "io"
+    "fmt"
+    "bytes"
+    "strings"
+)
+
+// Control characters used by the WMO GTS bulletin framing (WMO-No. 386)
+const (
+    SOH = 0x01
+    ETX = 0x03
+)
+
+// Length of the prefix of FTP transferred bulletins, i.e. 8 ASCII digits of
+// message length followed by 2 ASCII digits of format identifier.
+const ftpPrefixLength = 10
+
+// Maximum number of bytes in front of a start signature kept for parsing the bulletin
+// envelope. It is well above the length of a full envelope including the FTP prefix.
+const MAX_ENVELOPE_LENGTH = 128
+
+// Heading is the abbreviated heading of a WMO GTS bulletin, i.e.
+// TTAAii CCCC YYGGgg [BBB], optionally preceded by a transmission sequence number.
+type Heading struct {
+    SequenceNumber string
+    TTAAii         string
+    CCCC           string
+    YYGGgg         string
+    BBB            string
+}
+
+// String returns the abbreviated heading as it appears in the bulletin.
+func (h *Heading) String() string {
+    s := h.TTAAii + " " + h.CCCC + " " + h.YYGGgg
+    if h.BBB != "" {
+        s += " " + h.BBB
+    }
+    return s
+}
+
+// ParseEnvelope parses the bulletin envelope found in front of a start signature.
+//
+// The envelope can be one of the following WMO 386 framings:
+//   - SOH CR CR LF nnn CR CR LF heading CR CR LF
+//   - FTP format 00, i.e. the above prefixed by 8 digits length and "00"
+//   - FTP format 01, i.e. heading CR CR LF prefixed by 8 digits length and "01"
+// The trailer, i.e. CR CR LF ETX, of the previous bulletin is also accepted.
+// It returns nil if no abbreviated heading can be recognised.
+func ParseEnvelope(b []byte) *Heading {
+    b = bytes.TrimLeft(b, "\r\n\x03\x00")
+    if isFtpPrefix(b) {
+        b = b[ftpPrefixLength:]
+    }
+    b = bytes.TrimLeft(b, "\x01")
+
+    var lines []string
+    for _, line := range strings.Split(string(b), "\n") {
+        if line = strings.TrimSpace(line); line != "" {
+            lines = append(lines, line)
+        }
+    }
+
+    heading := &Heading{}
+    if len(lines) > 0 && isDigits(lines[0]) {
+        heading.SequenceNumber = lines[0]
+        lines = lines[1:]
+    }
+    if len(lines) == 0 || !parseAbbreviatedHeading(lines[0], heading) {
+        return nil
+    }
+    return heading
+}
+
+// ParseHeading parses an abbreviated heading, i.e. TTAAii CCCC YYGGgg [BBB]
+func ParseHeading(s string) (*Heading, error) {
+    heading := &Heading{}
+    if !parseAbbreviatedHeading(s, heading) {
+        return nil, fmt.Errorf("invalid abbreviated heading: %q", s)
+    }
+    return heading, nil
+}
+
+// parseAbbreviatedHeading parses TTAAii CCCC YYGGgg [BBB] into the given heading
+func parseAbbreviatedHeading(line string, heading *Heading) bool {
+    fields := strings.Fields(line)
+    if len(fields) != 3 && len(fields) != 4 {
+        return false
+    }
+    if len(fields[0]) != 6 || len(fields[1]) != 4 || len(fields[2]) != 6 || !isDigits(fields[2]) {
+        return false
+    }
+    heading.TTAAii, heading.CCCC, heading.YYGGgg = fields[0], fields[1], fields[2]
+    if len(fields) == 4 {
+        if len(fields[3]) != 3 {
+            return false
+        }
+        heading.BBB = fields[3]
+    }
+    return true
+}
+
+// isFtpPrefix checks whether the bytes start with the FTP length and format identifier
+func isFtpPrefix(b []byte) bool {
+    if len(b) < ftpPrefixLength || !isDigits(string(b[:8])) {
+        return false
+    }
+    format := string(b[8:ftpPrefixLength])
+    return format == "00" || format == "01"
+}
+
+func isDigits(s string) bool {
+    if s == "" {
+        return false
+    }
+    for _, c := range s {
+        if c < '0' || c > '9' {
+            return false
+        }
+    }
+    return true
+}
+
+// BulletinFormat is the framing used when writing GTS bulletins
+type BulletinFormat int
+
+const (
+    // SOH and ETX framing without any length prefix
+    PlainBulletin BulletinFormat = iota
+    // FTP format 00, i.e. SOH and ETX framing prefixed by length and format identifier
+    FtpFormat00
+    // FTP format 01, i.e. no starting line nor end of message signal, prefixed by length
+    // and format identifier
+    FtpFormat01
+)
+
+// Channel sequence numbers are 3 digits and wrap around after 999
+const csnModulo = 1000
+
+// BulletinWriter frames encoded messages as WMO GTS bulletins.
+type BulletinWriter struct {
+    w      io.Writer
+    format BulletinFormat
+    csn    int
+}
+
+// NewBulletinWriter returns a pointer to a BulletinWriter writing bulletins of the
+// given format. The first bulletin is numbered by the given channel sequence number,
+// which must be between 0 and 999.
+func NewBulletinWriter(w io.Writer, format BulletinFormat, csn int) (*BulletinWriter, error) {
+    if csn < 0 || csn >= csnModulo {
+        return nil, fmt.Errorf("channel sequence number out of range 0-%d: %d", csnModulo-1, csn)
+    }
+    return &BulletinWriter{w: w, format: format, csn: csn}, nil
+}
+
+// WriteBulletin writes a single bulletin of the given heading containing
+// the given encoded messages.
+func (bw *BulletinWriter) WriteBulletin(heading *Heading, messages ...[]byte) error {
+    buf := new(bytes.Buffer)
+    // Format 01 has neither the starting line, i.e. SOH and the sequence number, nor
+    // the end of message signal
+    if bw.format != FtpFormat01 {
+        buf.WriteByte(SOH)
+        fmt.Fprintf(buf, "\r\r\n%03d", bw.csn)
+    }
+    fmt.Fprintf(buf, "\r\r\n%v\r\r\n", heading)
+    for _, message := range messages {
+        buf.Write(message)
+    }
+    if bw.format != FtpFormat01 {
+        buf.WriteString("\r\r\n")
+        buf.WriteByte(ETX)
+    }
+
+    if bw.format == FtpFormat00 || bw.format == FtpFormat01 {
+        if _, err := fmt.Fprintf(bw.w, "%08d%02d", buf.Len(), int(bw.format-FtpFormat00)); err != nil {
+            return err
+        }
+    }
+    if _, err := bw.w.Write(buf.Bytes()); err != nil {
+        return err
+    }
+    bw.csn = (bw.csn + 1) % csnModulo
+    return nil
+}
@@ -0,0 +1,74 @@
+package tdcfio
+
+import (
+    "testing"
+    assert2 "github.com/seanpont/assert"
+    "bytes"
+)
+
+func TestParseEnvelope(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    // SOH framing
+    heading := ParseEnvelope([]byte("\x01\r\r\n052\r\r\nISMD01 OKPR 211200\r\r\n"))
+    assert.NotNil(heading)
+    assert.Equal(heading.SequenceNumber, "052")
+    assert.Equal(heading.TTAAii, "ISMD01")
+    assert.Equal(heading.CCCC, "OKPR")
+    assert.Equal(heading.YYGGgg, "211200")
+    assert.Equal(heading.BBB, "")
+
+    // FTP format 00 following the trailer of a previous bulletin
+    heading = ParseEnvelope([]byte("\r\r\n\x0300002956" + "00\x01\r\r\n00123\r\r\nIUSK73 AMMC 040000 RRA\r\r\n"))
+    assert.NotNil(heading)
+    assert.Equal(heading.SequenceNumber, "00123")
+    assert.Equal(heading.String(), "IUSK73 AMMC 040000 RRA")
+
+    // FTP format 01 has no SOH
+    heading = ParseEnvelope([]byte("0000295601\r\r\nISMD01 OKPR 211200\r\r\n"))
+    assert.NotNil(heading)
+    assert.Equal(heading.TTAAii, "ISMD01")
+
+    // No recognisable heading
+    assert.True(ParseEnvelope([]byte{}) == nil)
+    assert.True(ParseEnvelope([]byte("garbage")) == nil)
+}
+
+func TestBulletinWriter(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    heading, err := ParseHeading("IUSK73 AMMC 040000 RRA")
+    assert.Nil(err)
+
+    buf := new(bytes.Buffer)
+    bw, err := NewBulletinWriter(buf, FtpFormat00, 999)
+    assert.Nil(err)
+    assert.Nil(bw.WriteBulletin(heading, []byte("BUFR...7777")))
+    assert.Nil(bw.WriteBulletin(heading, []byte("BUFR...7777")))
+
+    bulletin := "\x01\r\r\n999\r\r\nIUSK73 AMMC 040000 RRA\r\r\nBUFR...7777\r\r\n\x03"
+    assert.Equal(buf.String()[:10+len(bulletin)], "0000005000"+bulletin)
+    // Sequence number wraps around
+    assert.Equal(buf.String()[10+len(bulletin)+14:10+len(bulletin)+17], "000")
+
+    // Written envelope can be parsed back
+    parsed := ParseEnvelope(buf.Bytes()[:bytes.Index(buf.Bytes(), []byte("BUFR"))])
+    assert.NotNil(parsed)
+    assert.Equal(parsed.String(), heading.String())
+
+    buf.Reset()
+    bw, err = NewBulletinWriter(buf, FtpFormat01, 1)
+    assert.Nil(err)
+    assert.Nil(bw.WriteBulletin(heading, []byte("BUFR...7777")))
+    // Neither the starting line nor the end of message signal
+    assert.Equal(buf.String(), "0000003901\r\r\nIUSK73 AMMC 040000 RRA\r\r\nBUFR...7777")
+    parsed = ParseEnvelope(buf.Bytes()[:bytes.Index(buf.Bytes(), []byte("BUFR"))])
+    assert.NotNil(parsed)
+    assert.Equal(parsed.String(), heading.String())
+
+    // Channel sequence numbers have 3 digits
+    _, err = NewBulletinWriter(buf, FtpFormat00, -5)
+    assert.NotNil(err)
+    _, err = NewBulletinWriter(buf, FtpFormat00, 1000)
+    assert.NotNil(err)
+}
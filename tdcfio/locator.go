@@ -0,0 +1,123 @@
+package tdcfio
+
+import (
+    "io"
+    "fmt"
+    "bytes"
+    "github.com/pkg/errors"
+)
+
+const (
+    START_SIGNATURE = "BUFR"
+    STOP_SIGNATURE  = "7777"
+)
+
+// Number of bytes in section 0, i.e. start signature, total length and edition number
+const section0LengthInBytes = 8
+
+// Size of chunks read when searching for the start signature
+const searchChunkSize = 64 * 1024
+
+// ReadMessageAt reads the complete message starting at the given offset. It validates
+// both the start and stop signatures and returns the bytes of the message.
+func ReadMessageAt(r io.ReaderAt, offset int64) ([]byte, error) {
+    length, err := messageLengthAt(r, offset)
+    if err != nil {
+        return nil, err
+    }
+    b := make([]byte, length)
+    if _, err := r.ReadAt(b, offset); err != nil {
+        return nil, errors.Wrapf(err, "cannot read message of %v bytes at offset %v", length, offset)
+    }
+    if string(b[length-len(STOP_SIGNATURE):]) != STOP_SIGNATURE {
+        return nil, fmt.Errorf("stop signature not found for message at offset %v", offset)
+    }
+    return b, nil
+}
+
+// ReadEnvelopeAt reads the bytes in front of the message at the given offset that
+// may be its GTS bulletin envelope, i.e. at most MAX_ENVELOPE_LENGTH bytes since the
+// last SOH and after the end of any previous message.
+func ReadEnvelopeAt(r io.ReaderAt, offset int64) ([]byte, error) {
+    start := offset - MAX_ENVELOPE_LENGTH
+    if start < 0 {
+        start = 0
+    }
+    b := make([]byte, offset-start)
+    if _, err := r.ReadAt(b, start); err != nil {
+        return nil, errors.Wrapf(err, "cannot read envelope of message at offset %v", offset)
+    }
+    for _, signature := range []string{STOP_SIGNATURE, START_SIGNATURE} {
+        if i := bytes.LastIndex(b, []byte(signature)); i >= 0 {
+            b = b[i+len(signature):]
+        }
+    }
+    if i := bytes.LastIndexByte(b, SOH); i >= 0 {
+        b = b[i:]
+    }
+    return b, nil
+}
+
+// FindStartSignature returns the offset of the first start signature at or after
+// the given offset. It returns io.EOF if none can be found.
+func FindStartSignature(r io.ReaderAt, offset int64) (int64, error) {
+    b := make([]byte, searchChunkSize)
+    for {
+        n, err := r.ReadAt(b, offset)
+        if i := bytes.Index(b[:n], []byte(START_SIGNATURE)); i >= 0 {
+            return offset + int64(i), nil
+        }
+        if err != nil {
+            if err == io.EOF {
+                return 0, io.EOF
+            }
+            return 0, errors.Wrap(err, "cannot search for start signature")
+        }
+        // Keep the overlap in case the signature spans two chunks
+        offset += int64(n - len(START_SIGNATURE) + 1)
+    }
+}
+
+// MessageLocator finds the offsets of messages in random access input without
+// decoding them, i.e. by reading only section 0 of each message.
+type MessageLocator struct {
+    r    io.ReaderAt
+    next int64
+}
+
+// NewMessageLocator returns a pointer to a MessageLocator starting from the beginning of the input.
+func NewMessageLocator(r io.ReaderAt) *MessageLocator {
+    return &MessageLocator{r: r}
+}
+
+// Next returns the offset and length of the next message. It returns io.EOF when
+// there are no more messages.
+func (l *MessageLocator) Next() (int64, int, error) {
+    offset, err := FindStartSignature(l.r, l.next)
+    if err != nil {
+        return 0, 0, err
+    }
+    length, err := messageLengthAt(l.r, offset)
+    if err != nil {
+        return 0, 0, err
+    }
+    l.next = offset + int64(length)
+    return offset, length, nil
+}
+
+// messageLengthAt validates the start signature at the given offset and returns
+// the total length of the message.
+func messageLengthAt(r io.ReaderAt, offset int64) (int, error) {
+    b := make([]byte, section0LengthInBytes)
+    if _, err := r.ReadAt(b, offset); err != nil {
+        return 0, errors.Wrapf(err, "cannot read section 0 at offset %v", offset)
+    }
+    if string(b[:len(START_SIGNATURE)]) != START_SIGNATURE {
+        return 0, fmt.Errorf("start signature not found at offset %v", offset)
+    }
+    length := int(b[4])<<16 | int(b[5])<<8 | int(b[6])
+    if length < section0LengthInBytes+len(STOP_SIGNATURE) {
+        return 0, fmt.Errorf("invalid message length at offset %v: %v", offset, length)
+    }
+    return length, nil
+}
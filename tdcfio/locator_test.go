@@ -0,0 +1,64 @@
+package tdcfio
+
+import (
+    "testing"
+    assert2 "github.com/seanpont/assert"
+    "bytes"
+    "io"
+)
+
+func TestMessageLocator(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    // Two minimal messages separated by junk
+    message := []byte("BUFR\x00\x00\x0c\x047777")
+    data := append([]byte("junk"), message...)
+    data = append(data, []byte("\r\r\n\x03")...)
+    data = append(data, message...)
+    r := bytes.NewReader(data)
+
+    locator := NewMessageLocator(r)
+    offset, length, err := locator.Next()
+    assert.Nil(err)
+    assert.Equal(offset, int64(4))
+    assert.Equal(length, 12)
+
+    offset, _, err = locator.Next()
+    assert.Nil(err)
+    assert.Equal(offset, int64(20))
+
+    _, _, err = locator.Next()
+    assert.Equal(err, io.EOF)
+
+    b, err := ReadMessageAt(r, 20)
+    assert.Nil(err)
+    assert.Equal(b, message)
+
+    _, err = ReadMessageAt(r, 0)
+    assert.NotNil(err)
+
+    // Stop signature must be present
+    _, err = ReadMessageAt(bytes.NewReader([]byte("BUFR\x00\x00\x0c\x047778")), 0)
+    assert.NotNil(err)
+}
+
+func TestReadEnvelopeAt(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    message := []byte("BUFR\x00\x00\x0c\x047777")
+    envelope := []byte("\x01\r\r\n001\r\r\nIUSK73 AMMC 182300\r\r\n")
+    data := append([]byte{}, message...)
+    data = append(data, []byte("\r\r\n\x03")...)
+    data = append(data, envelope...)
+    data = append(data, message...)
+    r := bytes.NewReader(data)
+
+    // Only bytes after the previous message and since the last SOH are kept
+    b, err := ReadEnvelopeAt(r, int64(len(data)-len(message)))
+    assert.Nil(err)
+    assert.Equal(b, envelope)
+
+    b, err = ReadEnvelopeAt(r, 0)
+    assert.Nil(err)
+    assert.Equal(len(b), 0)
+}
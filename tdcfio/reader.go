@@ -9,6 +9,10 @@ const (
     BinaryInput   InputType = iota
     FlatTextInput
     FlatJsonInput
+    // Values given in memory, e.g. by the builder package
+    ValueInput
+    // Characters of CREX messages
+    CrexInput
 )
 
 // Reader is the interface that wraps the basic operations needed for deserialize a value.
@@ -45,3 +49,23 @@ type PeekableReader interface {
     // PeekBytes returns an array of byte by skipping number of skip unit.
     PeekBytes(skip int, n int) ([]byte, error)
 }
+
+// Skipper is implemented by readers that can discard bytes without decoding them.
+type Skipper interface {
+    Skip(nbytes int) error
+}
+
+// FieldNamer is implemented by readers of formats that name the header fields, e.g.
+// text dumps. Optional fields, e.g. padding, are then read only if they are present.
+type FieldNamer interface {
+    // PeekFieldName returns the name of the field of the next value
+    PeekFieldName() (string, error)
+}
+
+// DescriptorReader is implemented by readers of formats that give descriptors as
+// text, e.g. B12101 and D07022 of CREX, instead of their F, X and Y values.
+type DescriptorReader interface {
+    // ReadDescriptors reads all descriptors up to the next value which is not a
+    // descriptor and returns their IDs, e.g. 12101 and 307022.
+    ReadDescriptors() ([]int, error)
+}
@@ -0,0 +1,137 @@
+package tdcfio
+
+import (
+    "fmt"
+    "io"
+)
+
+// ValueReader implements the tdcfio.Reader interface for reading from a list of
+// values in memory. Values are read one at a time regardless of the width argument,
+// which only advances the position.
+//
+// Numbers can be any of int, uint or float64. Strings can be either string or []byte
+// and binary values can be either *Binary or a string of 0s and 1s.
+type ValueReader struct {
+    values []interface{}
+    next   int
+    pos    int
+}
+
+// NewValueReader returns a pointer to ValueReader for the given values.
+func NewValueReader(values []interface{}) *ValueReader {
+    return &ValueReader{values: values}
+}
+
+func (r *ValueReader) Pos() int {
+    return r.pos
+}
+
+// Remaining returns the number of values not yet read
+func (r *ValueReader) Remaining() int {
+    return len(r.values) - r.next
+}
+
+// ReadValue returns the next value as is, which can be nil for missing value
+func (r *ValueReader) ReadValue(n int) (interface{}, error) {
+    if r.next >= len(r.values) {
+        return nil, io.ErrUnexpectedEOF
+    }
+    v := r.values[r.next]
+    r.next++
+    r.pos += n
+    return v, nil
+}
+
+func (r *ValueReader) ReadNumber(n int) (float64, error) {
+    v, err := r.ReadValue(n)
+    if err != nil {
+        return 0, err
+    }
+    return ValueToFloat64(v)
+}
+
+func (r *ValueReader) ReadUint(n int) (uint, error) {
+    x, err := r.ReadNumber(n)
+    if err != nil {
+        return 0, err
+    }
+    if x < 0 {
+        return 0, fmt.Errorf("value is negative: %v", x)
+    }
+    return uint(x), nil
+}
+
+func (r *ValueReader) ReadInt(n int) (int, error) {
+    x, err := r.ReadNumber(n)
+    if err != nil {
+        return 0, err
+    }
+    return int(x), nil
+}
+
+func (r *ValueReader) ReadBool() (bool, error) {
+    v, err := r.ReadValue(1)
+    if err != nil {
+        return false, err
+    }
+    b, ok := v.(bool)
+    if !ok {
+        return false, fmt.Errorf("value is not bool type: %v, %T", v, v)
+    }
+    return b, nil
+}
+
+func (r *ValueReader) ReadBytes(n int) ([]byte, error) {
+    v, err := r.ReadValue(n * NBITS_PER_BYTE)
+    if err != nil {
+        return nil, err
+    }
+    return ValueToBytes(v)
+}
+
+func (r *ValueReader) ReadBinary(n int) (*Binary, error) {
+    v, err := r.ReadValue(n)
+    if err != nil {
+        return nil, err
+    }
+    return ValueToBinary(v)
+}
+
+func (r *ValueReader) ReadFloat32() (float64, error) {
+    return r.ReadNumber(32)
+}
+
+// ValueToFloat64 converts a numeric value of int, uint or float64 to float64
+func ValueToFloat64(v interface{}) (float64, error) {
+    switch v := v.(type) {
+    case int:
+        return float64(v), nil
+    case uint:
+        return float64(v), nil
+    case float64:
+        return v, nil
+    }
+    return 0, fmt.Errorf("value is not a number: %v, %T", v, v)
+}
+
+// ValueToBytes converts a string value of string or []byte to []byte
+func ValueToBytes(v interface{}) ([]byte, error) {
+    switch v := v.(type) {
+    case string:
+        return []byte(v), nil
+    case []byte:
+        return v, nil
+    }
+    return nil, fmt.Errorf("value is not a string: %v, %T", v, v)
+}
+
+// ValueToBinary converts a binary value of *Binary or a string of 0s and 1s to *Binary
+func ValueToBinary(v interface{}) (*Binary, error) {
+    switch v := v.(type) {
+    case *Binary:
+        return v, nil
+    case string:
+        return NewBinaryFromString(v)
+    }
+    return nil, fmt.Errorf("value is not binary: %v, %T", v, v)
+}
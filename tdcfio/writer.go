@@ -14,4 +14,7 @@ type Writer interface {
 
     // Write a float64 in format of IEE754 float32
     WriteFloat32(v float64) error
+
+    // Pos returns the number of bits written so far
+    Pos() int
 }
\ No newline at end of file
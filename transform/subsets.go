@@ -0,0 +1,225 @@
+// Package transform provides operations that derive new messages from decoded
+// messages, e.g. splitting and merging subsets or changing compression. The
+// derived messages have their header fields updated accordingly and can be encoded
+// with serialize.BinarySerializer, which also updates the section lengths.
+package transform
+
+import (
+    "fmt"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/table"
+)
+
+// Header fields that must agree for messages to be merged
+var mergeableFields = []string{
+    "bufrEditionNumber",
+    "masterTableNumber",
+    "masterTableVersion",
+    "localTableVersion",
+}
+
+// Header fields that must also agree when local tables are used, since local
+// descriptors are defined by the originating centre
+var localTableFields = []string{
+    "originatingCentre",
+    "originatingSubCentre",
+}
+
+// Split returns one uncompressed single-subset message for each subset of the given message.
+func Split(message *bufr.Message) ([]*bufr.Message, error) {
+    payload, err := messagePayload(message)
+    if err != nil {
+        return nil, err
+    }
+
+    var messages []*bufr.Message
+    for _, subset := range payload.Subsets() {
+        p := bufr.NewPayload(false)
+        p.AddSubset(bufr.NewSubset(subset.Cells(), subset.Root()))
+        m := message.Copy()
+        if err := setPayload(m, p); err != nil {
+            return nil, err
+        }
+        messages = append(messages, m)
+    }
+    return messages, nil
+}
+
+// Merge returns a message which has all subsets of the given messages in order.
+// The messages must share an identical unexpanded template and table versions.
+// Header fields other than number of subsets and compression are taken from the
+// first message. Subsets can only be compressed if they have the same structure,
+// e.g. same replication factors.
+func Merge(messages []*bufr.Message, compressed bool) (*bufr.Message, error) {
+    if len(messages) == 0 {
+        return nil, fmt.Errorf("no message to merge")
+    }
+
+    first := messages[0]
+    p := bufr.NewPayload(compressed)
+    for i, message := range messages {
+        if err := checkMergeable(first, message); err != nil {
+            return nil, fmt.Errorf("cannot merge message %v: %v", i+1, err)
+        }
+        payload, err := messagePayload(message)
+        if err != nil {
+            return nil, err
+        }
+        for _, subset := range payload.Subsets() {
+            p.AddSubset(bufr.NewSubset(subset.Cells(), subset.Root()))
+        }
+    }
+
+    if compressed {
+        if err := checkCompressible(p); err != nil {
+            return nil, err
+        }
+    }
+
+    m := first.Copy()
+    if err := setPayload(m, p); err != nil {
+        return nil, err
+    }
+    return m, nil
+}
+
+// Recompress returns a copy of the message with its subsets packed in the compressed
+// or uncompressed form. Subsets must have the same delayed replication factors to be
+// compressed.
+func Recompress(message *bufr.Message, compressed bool) (*bufr.Message, error) {
+    payload, err := messagePayload(message)
+    if err != nil {
+        return nil, err
+    }
+
+    p := bufr.NewPayload(compressed)
+    for _, subset := range payload.Subsets() {
+        p.AddSubset(bufr.NewSubset(subset.Cells(), subset.Root()))
+    }
+    if compressed {
+        if err := checkCompressible(p); err != nil {
+            return nil, err
+        }
+    }
+
+    m := message.Copy()
+    if err := setPayload(m, p); err != nil {
+        return nil, err
+    }
+    return m, nil
+}
+
+// setPayload replaces the payload of a message and updates the number of subsets
+// and compression flag in section 3.
+func setPayload(message *bufr.Message, payload *bufr.Payload) error {
+    field, err := message.ProxyField("payload")
+    if err != nil {
+        return err
+    }
+    field.Value = payload
+
+    if field, err = message.ProxyField("nSubsets"); err != nil {
+        return err
+    }
+    field.Value = uint(len(payload.Subsets()))
+
+    if field, err = message.ProxyField("isCompressed"); err != nil {
+        return err
+    }
+    field.Value = payload.Compressed
+    return nil
+}
+
+func messagePayload(message *bufr.Message) (*bufr.Payload, error) {
+    field, err := message.ProxyField("payload")
+    if err != nil {
+        return nil, err
+    }
+    payload, ok := field.Value.(*bufr.Payload)
+    if !ok {
+        return nil, fmt.Errorf("invalid payload: %T", field.Value)
+    }
+    return payload, nil
+}
+
+func checkMergeable(m1, m2 *bufr.Message) error {
+    names := mergeableFields
+    if field, err := m1.ProxyField("localTableVersion"); err == nil && field.Value != uint(0) {
+        names = append(names[:len(names):len(names)], localTableFields...)
+    }
+    for _, name := range names {
+        f1, err := m1.ProxyField(name)
+        if err != nil {
+            return err
+        }
+        f2, err := m2.ProxyField(name)
+        if err != nil {
+            return err
+        }
+        if f1.Value != f2.Value {
+            return fmt.Errorf("different %v: %v != %v", name, f1.Value, f2.Value)
+        }
+    }
+
+    t1, err := unexpandedTemplate(m1)
+    if err != nil {
+        return err
+    }
+    t2, err := unexpandedTemplate(m2)
+    if err != nil {
+        return err
+    }
+    ids1, ids2 := t1.Ids(), t2.Ids()
+    if len(ids1) != len(ids2) {
+        return fmt.Errorf("different unexpanded templates")
+    }
+    for i := range ids1 {
+        if ids1[i] != ids2[i] {
+            return fmt.Errorf("different unexpanded templates at descriptor %v: %v != %v",
+                i+1, ids1[i], ids2[i])
+        }
+    }
+    return nil
+}
+
+func unexpandedTemplate(message *bufr.Message) (*table.UnexpandedTemplate, error) {
+    field, err := message.ProxyField("unexpandedTemplate")
+    if err != nil {
+        return nil, err
+    }
+    ut, ok := field.Value.(*table.UnexpandedTemplate)
+    if !ok {
+        return nil, fmt.Errorf("invalid unexpanded template: %T", field.Value)
+    }
+    return ut, nil
+}
+
+// checkCompressible checks that all subsets have the same sequence of descriptors
+// with the same packing so that they can be packed together.
+func checkCompressible(payload *bufr.Payload) error {
+    subsets := payload.Subsets()
+    if len(subsets) == 0 {
+        return nil
+    }
+    cells0 := subsets[0].Cells()
+    for _, subset := range subsets[1:] {
+        cells := subset.Cells()
+        for i := 0; i < len(cells) && i < len(cells0); i++ {
+            n0, n := cells0[i].Node(), cells[i].Node()
+            if n.Descriptor.Id() != n0.Descriptor.Id() || *n.PackingInfo != *n0.PackingInfo {
+                return fmt.Errorf("cannot compress subsets of different structures: value %v of subset %v",
+                    i+1, subset.Index()+1)
+            }
+            if n.Descriptor.Id().IsDelayedFactor() && cells[i].Value() != cells0[i].Value() {
+                return fmt.Errorf(
+                    "delayed replication factor not equal across all subsets: value %v of subset %v",
+                    i+1, subset.Index()+1)
+            }
+        }
+        if len(cells) != len(cells0) {
+            return fmt.Errorf("cannot compress subsets of different number of values: %v != %v",
+                len(cells), len(cells0))
+        }
+    }
+    return nil
+}
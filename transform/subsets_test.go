@@ -0,0 +1,182 @@
+package transform_test
+
+import (
+    "testing"
+    "context"
+    "bytes"
+    assert2 "github.com/seanpont/assert"
+    "github.com/ywangd/gobufrkit/api"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/builder"
+    "github.com/ywangd/gobufrkit/serialize"
+    "github.com/ywangd/gobufrkit/table"
+    "github.com/ywangd/gobufrkit/tdcfio"
+    "github.com/ywangd/gobufrkit/transform"
+)
+
+// newMessage builds a message of the given temperatures, one subset each. A nil
+// temperature is missing.
+func newMessage(t *testing.T, temperatures ...interface{}) *bufr.Message {
+    b := builder.NewBuilder("../_definitions/tables")
+    b.Set("originatingCentre", 1)
+    b.Set("masterTableVersion", 25)
+    b.Set("year", 2018)
+    // year, month, day, delayed replication of temperature
+    b.SetTemplate(301011, 101000, 31001, 12101)
+    for i, temperature := range temperatures {
+        b.AddSubset(2018, 1, i+1, []interface{}{temperature})
+    }
+    message, err := b.Build()
+    if err != nil {
+        t.Fatal(err)
+    }
+    return message
+}
+
+// encodeDecode encodes the message and decodes it again
+func encodeDecode(t *testing.T, message *bufr.Message) *bufr.Message {
+    buf := new(bytes.Buffer)
+    if err := serialize.NewBinarySerializer(buf).Serialize(message); err != nil {
+        t.Fatal(err)
+    }
+    decoded, err := api.DecodeMessageAt(context.Background(), &api.Config{
+        DefinitionsPath: "../_definitions",
+        TablesPath:      "../_definitions/tables",
+        InputType:       tdcfio.BinaryInput,
+    }, bytes.NewReader(buf.Bytes()), 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return decoded
+}
+
+func proxyValue(t *testing.T, message *bufr.Message, name string) interface{} {
+    field, err := message.ProxyField(name)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return field.Value
+}
+
+// temperatures returns the last value, i.e. the temperature, of each subset
+func temperatures(t *testing.T, message *bufr.Message) []interface{} {
+    var values []interface{}
+    for _, subset := range proxyValue(t, message, "payload").(*bufr.Payload).Subsets() {
+        cells := subset.Cells()
+        values = append(values, cells[len(cells)-1].Value())
+    }
+    return values
+}
+
+func TestSplit(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    messages, err := transform.Split(newMessage(t, 273.15, 280.0, 281.5))
+    assert.Nil(err)
+    assert.Equal(len(messages), 3)
+    for i, want := range []float64{273.15, 280.0, 281.5} {
+        decoded := encodeDecode(t, messages[i])
+        assert.Equal(proxyValue(t, decoded, "nSubsets"), uint(1))
+        assert.Equal(proxyValue(t, decoded, "isCompressed"), false)
+        assert.Equal(temperatures(t, decoded), []interface{}{want})
+    }
+}
+
+func TestMerge(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    messages := []*bufr.Message{newMessage(t, 273.15), newMessage(t, 280.0, 281.5)}
+    for _, compressed := range []bool{false, true} {
+        merged, err := transform.Merge(messages, compressed)
+        assert.Nil(err)
+        decoded := encodeDecode(t, merged)
+        assert.Equal(proxyValue(t, decoded, "nSubsets"), uint(3))
+        assert.Equal(proxyValue(t, decoded, "isCompressed"), compressed)
+        assert.Equal(temperatures(t, decoded), []interface{}{273.15, 280.0, 281.5})
+    }
+
+    _, err := transform.Merge(nil, false)
+    assert.NotNil(err)
+
+    // Header fields must agree
+    other := newMessage(t, 290.0)
+    field, err := other.ProxyField("masterTableVersion")
+    assert.Nil(err)
+    field.Value = uint(24)
+    _, err = transform.Merge(append(messages, other), false)
+    assert.NotNil(err)
+
+    // Originating centres only need to agree when local tables are used
+    other = newMessage(t, 290.0)
+    field, err = other.ProxyField("originatingCentre")
+    assert.Nil(err)
+    field.Value = uint(98)
+    _, err = transform.Merge(append(messages, other), false)
+    assert.Nil(err)
+    for _, m := range append(messages, other) {
+        field, err = m.ProxyField("localTableVersion")
+        assert.Nil(err)
+        field.Value = uint(1)
+    }
+    _, err = transform.Merge(append(messages, other), false)
+    assert.NotNil(err)
+}
+
+func TestRecompress(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    compressed, err := transform.Recompress(newMessage(t, 273.15, nil, 281.5), true)
+    assert.Nil(err)
+    compressed = encodeDecode(t, compressed)
+    assert.Equal(proxyValue(t, compressed, "isCompressed"), true)
+    want := []interface{}{273.15, nil, 281.5}
+    assert.Equal(temperatures(t, compressed), want)
+
+    // Compressed to uncompressed and back
+    uncompressed, err := transform.Recompress(compressed, false)
+    assert.Nil(err)
+    uncompressed = encodeDecode(t, uncompressed)
+    assert.Equal(proxyValue(t, uncompressed, "isCompressed"), false)
+    assert.Equal(temperatures(t, uncompressed), want)
+
+    recompressed, err := transform.Recompress(uncompressed, true)
+    assert.Nil(err)
+    recompressed = encodeDecode(t, recompressed)
+    assert.Equal(proxyValue(t, recompressed, "isCompressed"), true)
+    assert.Equal(proxyValue(t, recompressed, "nSubsets"), uint(3))
+    assert.Equal(temperatures(t, recompressed), want)
+}
+
+func TestRecompressDifferentStructures(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    newBuilder := func(ids ...table.ID) *builder.Builder {
+        b := builder.NewBuilder("../_definitions/tables")
+        b.Set("masterTableVersion", 25)
+        b.SetTemplate(ids...)
+        return b
+    }
+
+    // Different delayed replication factors
+    b := newBuilder(301011, 101000, 31001, 12101)
+    b.AddSubset(2018, 1, 1, []interface{}{273.15})
+    b.AddSubset(2018, 1, 2, []interface{}{280.0, 281.5})
+    message, err := b.Build()
+    assert.Nil(err)
+    _, err = transform.Recompress(message, true)
+    assert.NotNil(err)
+
+    // Different descriptors
+    b = newBuilder(301011, 12101)
+    b.AddSubset(2018, 1, 3, 273.15)
+    other, err := b.Build()
+    assert.Nil(err)
+    payload := bufr.NewPayload(false)
+    payload.AddSubset(proxyValue(t, message, "payload").(*bufr.Payload).Subset(0))
+    payload.AddSubset(proxyValue(t, other, "payload").(*bufr.Payload).Subset(0))
+    field, err := message.ProxyField("payload")
+    assert.Nil(err)
+    field.Value = payload
+    _, err = transform.Recompress(message, true)
+    assert.NotNil(err)
+}
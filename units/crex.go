@@ -0,0 +1,112 @@
+package units
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "github.com/ywangd/gobufrkit/bufr"
+    "github.com/ywangd/gobufrkit/table"
+    "github.com/ywangd/gobufrkit/tdcfio"
+)
+
+// Number of characters of delayed replication factors in CREX, which have no
+// CREX representation in Table B
+const CREX_NCHARS_REPLICATION_FACTOR = 4
+
+// Conversions of values from the units of CREX to the ones of BUFR for Table B entries
+// whose CREX unit differs from the BUFR unit, e.g. C and K for temperatures.
+var crexConversions = []*Conversion{
+    {From: "C", To: "K", Factor: 1, Offset: 273.15},
+    {From: "kPa", To: "Pa", Factor: 1000, Offset: 0},
+    {From: "nbar", To: "Pa", Factor: 0.0001, Offset: 0},
+    {From: "mm/h", To: "kg m-2 s-1", Factor: 1.0 / 3600, Offset: 0},
+    {From: "ft", To: "m", Factor: 0.3048, Offset: 0},
+    {From: "mm", To: "m", Factor: 0.001, Offset: 0},
+}
+
+// CrexConversion returns the conversion of values from the given CREX unit to the
+// given BUFR unit. It returns nil if the units are the same.
+func CrexConversion(crexUnit, bufrUnit string) (*Conversion, error) {
+    if crexUnit == bufrUnit {
+        return nil, nil
+    }
+    for _, c := range crexConversions {
+        if c.From == crexUnit && c.To == bufrUnit {
+            return c, nil
+        }
+    }
+    return nil, fmt.Errorf("no conversion from CREX unit %v to %v", crexUnit, bufrUnit)
+}
+
+// Inverse returns the conversion in the opposite direction
+func (c *Conversion) Inverse() *Conversion {
+    return &Conversion{From: c.To, To: c.From, Factor: 1 / c.Factor, Offset: -c.Offset / c.Factor}
+}
+
+// CrexInfo describes how a value is represented in CREX, i.e. as a group of
+// characters of the scaled value in the CREX unit.
+type CrexInfo struct {
+    Unit       table.Unit
+    UnitString string
+    Scale      int
+    Nchars     int
+}
+
+// NewCrexInfo returns how the value of the given descriptor and packing info is
+// represented in CREX. Changes of width and scale made by operators to the packing
+// info apply to the CREX representation as well.
+func NewCrexInfo(descriptor table.Descriptor, info *bufr.PackingInfo) (*CrexInfo, error) {
+    if _, decorated := descriptor.(*table.DecorateDescriptor); decorated {
+        return nil, fmt.Errorf("no CREX representation for associated field of %v", descriptor.Id())
+    }
+    // Characters inserted by operator 205YYY
+    if descriptor.F() == table.F_OPERATOR && descriptor.X() == 5 {
+        return &CrexInfo{Unit: table.STRING, Nchars: info.Nbits / tdcfio.NBITS_PER_BYTE}, nil
+    }
+    entry, ok := descriptor.Entry().(*table.Bentry)
+    if !ok {
+        return nil, fmt.Errorf("no CREX representation for %v", descriptor.Id())
+    }
+
+    if entry.CrexUnitString == "NA" || entry.CrexNchars == 0 {
+        if descriptor.X() != 31 || entry.Unit != table.NUMERIC {
+            return nil, fmt.Errorf("no CREX representation for %v", descriptor.Id())
+        }
+        return &CrexInfo{Unit: table.NUMERIC, UnitString: entry.UnitString,
+            Nchars: CREX_NCHARS_REPLICATION_FACTOR}, nil
+    }
+
+    ci := &CrexInfo{
+        Unit:       entry.CrexUnit,
+        UnitString: entry.CrexUnitString,
+        Scale:      entry.CrexScale,
+        Nchars:     entry.CrexNchars,
+    }
+    switch info.Unit {
+    case table.STRING:
+        ci.Nchars = info.Nbits / tdcfio.NBITS_PER_BYTE
+    case table.NUMERIC:
+        if d := info.Scale - entry.Scale; d != 0 {
+            ci.Scale += d
+            if d > 0 {
+                ci.Nchars += d
+            }
+        }
+        // Values widened by operators, e.g. 201YYY, need as many characters as the
+        // largest of them
+        if info.Nbits > entry.Nbits {
+            if n := crexNdigits(info, ci.Scale); n > ci.Nchars {
+                ci.Nchars = n
+            }
+        }
+    }
+    return ci, nil
+}
+
+// crexNdigits returns the number of digits of the largest value that can be packed
+// with the given packing info when written with the given CREX scale
+func crexNdigits(info *bufr.PackingInfo, scale int) int {
+    largest := math.Max(math.Abs(info.Refval), math.Abs(info.Refval+math.Exp2(float64(info.Nbits))-1))
+    largest *= math.Pow10(scale - info.Scale)
+    return len(strconv.FormatFloat(math.Ceil(largest), 'f', 0, 64))
+}
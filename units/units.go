@@ -0,0 +1,148 @@
+// Package units converts decoded values between units of measure.
+//
+// Conversions are grouped into named unit systems, e.g. si, aviation and operational,
+// which are loaded from a CSV file. Each conversion is keyed on the unit string
+// as it appears in Table B, e.g. K, m/s, Pa.
+package units
+
+import (
+    "encoding/csv"
+    "fmt"
+    "math"
+    "os"
+    "strconv"
+    "github.com/ywangd/gobufrkit/bufr"
+)
+
+// Conversion converts a value from one unit to another as: x * Factor + Offset
+type Conversion struct {
+    From   string
+    To     string
+    Factor float64
+    Offset float64
+}
+
+func (c *Conversion) Convert(x float64) float64 {
+    return x*c.Factor + c.Offset
+}
+
+// System is a named set of conversions indexed by the unit to convert from.
+type System struct {
+    name        string
+    conversions map[string]*Conversion
+}
+
+func NewSystem(name string) *System {
+    return &System{name: name, conversions: make(map[string]*Conversion)}
+}
+
+func (s *System) Name() string {
+    return s.name
+}
+
+// Add adds a conversion to the system. It replaces any existing conversion
+// of the same source unit.
+func (s *System) Add(conversion *Conversion) {
+    s.conversions[conversion.From] = conversion
+}
+
+// Lookup returns the conversion for the given source unit.
+func (s *System) Lookup(unit string) (*Conversion, bool) {
+    c, ok := s.conversions[unit]
+    return c, ok
+}
+
+// Convert converts the given value of the given unit. Only numeric values are
+// converted. Missing values stay missing but are reported in the converted unit.
+// Other values and values of units unknown to the system are returned unchanged
+// together with the unit.
+func (s *System) Convert(value interface{}, unit string) (interface{}, string) {
+    c, ok := s.Lookup(unit)
+    if !ok {
+        return value, unit
+    }
+    switch x := value.(type) {
+    case float64:
+        return c.Convert(x), c.To
+    case nil:
+        return nil, c.To
+    default:
+        return value, unit
+    }
+}
+
+// ConvertCell converts the value of the given cell and returns the converted
+// value and its unit. The result is rounded to the precision implied by the
+// scale of the cell and the conversion factor.
+func (s *System) ConvertCell(cell *bufr.Cell) (interface{}, string) {
+    value, unit := s.Convert(cell.Value(), cell.Unit())
+    x, ok := value.(float64)
+    c, converted := s.Lookup(cell.Unit())
+    if !ok || !converted || cell.Node().PackingInfo == nil {
+        return value, unit
+    }
+    ndigits := cell.Node().PackingInfo.Scale
+    if f := math.Abs(c.Factor); f > 0 && f < 1 {
+        ndigits += int(math.Ceil(-math.Log10(f)))
+    }
+    return round(x, ndigits), unit
+}
+
+// round rounds x to the given number of decimal digits. The number of digits
+// can be negative to round to tens, hundreds etc.
+func round(x float64, ndigits int) float64 {
+    p := math.Pow10(ndigits)
+    return math.Round(x*p) / p
+}
+
+// LoadSystems reads all unit systems defined in the given CSV file.
+func LoadSystems(path string) (map[string]*System, error) {
+    ins, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer ins.Close()
+
+    r := csv.NewReader(ins)
+    r.Comment = '#'
+
+    records, err := r.ReadAll()
+    if err != nil {
+        return nil, err
+    }
+
+    systems := make(map[string]*System)
+    for _, record := range records {
+        if len(record) != 5 {
+            return nil, fmt.Errorf("invalid unit conversion record: %v", record)
+        }
+        factor, err := strconv.ParseFloat(record[3], 64)
+        if err != nil {
+            return nil, err
+        }
+        offset, err := strconv.ParseFloat(record[4], 64)
+        if err != nil {
+            return nil, err
+        }
+        system, ok := systems[record[0]]
+        if !ok {
+            system = NewSystem(record[0])
+            systems[record[0]] = system
+        }
+        system.Add(&Conversion{From: record[1], To: record[2], Factor: factor, Offset: offset})
+    }
+    return systems, nil
+}
+
+// LoadSystem reads the unit system of the given name from the given CSV file.
+func LoadSystem(path string, name string) (*System, error) {
+    systems, err := LoadSystems(path)
+    if err != nil {
+        return nil, err
+    }
+    system, ok := systems[name]
+    if !ok {
+        return nil, fmt.Errorf("unit system not found: %s", name)
+    }
+    return system, nil
+}
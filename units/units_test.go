@@ -0,0 +1,37 @@
+package units
+
+import (
+    "testing"
+    assert2 "github.com/seanpont/assert"
+)
+
+func TestLoadSystem(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    system, err := LoadSystem("../_definitions/units.csv", "operational")
+    assert.Nil(err)
+    assert.Equal(system.Name(), "operational")
+
+    value, unit := system.Convert(273.15, "K")
+    assert.Equal(unit, "C")
+    assert.Equal(round(value.(float64), 2), 0.0)
+
+    value, unit = system.Convert(101320.0, "Pa")
+    assert.Equal(unit, "hPa")
+    assert.Equal(round(value.(float64), 1), 1013.2)
+
+    // Non-numeric values and unknown units are left unchanged
+    value, unit = system.Convert(uint(3), "CODE TABLE")
+    assert.Equal(value, uint(3))
+    assert.Equal(unit, "CODE TABLE")
+
+    _, err = LoadSystem("../_definitions/units.csv", "imperial")
+    assert.NotNil(err)
+}
+
+func TestRound(t *testing.T) {
+    assert := assert2.Assert(t)
+
+    assert.Equal(round(15.000000000000028, 2), 15.0)
+    assert.Equal(round(1234.5, -1), 1230.0)
+}